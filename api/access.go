@@ -0,0 +1,151 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func initAccess() {
+	a := apiGroupe.Group("/guilds/:guildID/access", guildAccess("guildID"))
+	a.GET("/", getGuildAccesses, requires(models.GuildsAdmin)).Name = "Fetch users with access to a guild."
+	a.PUT("/:username", setGuildAccess, requires(models.GuildsAdmin)).Name = "Grant a user access to a guild."
+	a.DELETE("/:username", deleteGuildAccess, requires(models.GuildsAdmin)).Name = "Revoke the access of a user to a guild."
+}
+
+// guildAccess adds the permissions granted to the logged in user on the guild of the param
+// to the permissions of the user.
+func guildAccess(param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			guildID := c.Param(param)
+			if guildID == "" || c.Get("restricted") == true {
+				return next(c)
+			}
+
+			access, err := stores.Access.Get(guildID, loggedUsername(c))
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					return next(c)
+				}
+				log.Warn("GuildAccess/ Error getting access: ", err)
+				return echo.ErrInternalServerError
+			}
+
+			c.Set("permissions", permissions(c).Union(guildPermissions(c, access)))
+			return next(c)
+		}
+	}
+}
+
+// guildPermissions returns the permissions granted by the access, restricted to the api key if any.
+func guildPermissions(c echo.Context, access models.GuildAccess) models.PermissionSet {
+	if c.Get("restricted") == true {
+		return models.PermissionSet{}
+	}
+	perms := access.Permissions()
+	if key, ok := c.Get("apiKey").(*models.ApiKey); ok {
+		perms = perms.Intersect(key.Permissions())
+	}
+	return perms
+}
+
+// @Summary      Get guild accesses
+// @Tags         Guilds
+// @Description  Fetch the users granted access to the guild.
+// @Param        guildID  path      string              true  "guild id"
+// @Success      200      {array}   models.GuildAccess  "OK"
+// @Failure      403      "Forbidden"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/access/ [GET]
+func getGuildAccesses(c echo.Context) error {
+	accesses, err := stores.Access.ListGuild(c.Param("guildID"))
+	if err != nil {
+		log.Warn("GetGuildAccesses/ Error retrieving accesses: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, accesses)
+}
+
+// @Summary      Set guild access
+// @Tags         Guilds
+// @Description  Grant a user read, moderate or admin access to the guild, replacing its previous access.
+// @Accept       json
+// @Produce      json
+// @Param        guildID   path      string              true  "guild id"
+// @Param        username  path      string              true  "username"
+// @Param        access    body      string              true  "read, moderate or admin"
+// @Success      200       {object}  models.GuildAccess  "OK"
+// @Failure      400       "Invalid access"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Guild or user not found"
+// @Failure      500       "Server error"
+// @Router       /guilds/{guildID}/access/{username} [PUT]
+func setGuildAccess(c echo.Context) error {
+	access := models.GuildAccess{
+		GuildID:   c.Param("guildID"),
+		Username:  c.Param("username"),
+		GrantedAt: time.Now().UTC(),
+	}
+	var body struct {
+		Access string `json:"access" form:"access"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	access.Access = body.Access
+	if err := access.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := stores.Guilds.Get(access.GuildID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "guild " + access.GuildID + " not found."})
+		}
+		log.Warn("SetGuildAccess/ Error getting guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if _, err := stores.Users.Get(access.Username); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + access.Username + " not found."})
+		}
+		log.Warn("SetGuildAccess/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	if err := stores.Access.Set(access); err != nil {
+		log.Warn("SetGuildAccess/ Error saving access: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, access)
+}
+
+// @Summary      Delete guild access
+// @Tags         Guilds
+// @Description  Revoke the access of a user to the guild.
+// @Param        guildID   path  string  true  "guild id"
+// @Param        username  path  string  true  "username"
+// @Success      204       "No Content"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /guilds/{guildID}/access/{username} [DELETE]
+func deleteGuildAccess(c echo.Context) error {
+	err := stores.Access.Delete(c.Param("guildID"), c.Param("username"))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
+		log.Warn("DeleteGuildAccess/ Error deleting access: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
@@ -1,11 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strings"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/golang-jwt/jwt"
 	_ "github.com/gyroskan/cardinal/docs"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
@@ -15,10 +21,14 @@ import (
 const (
 	version   = "v1.0.3"
 	base_path = "/api/v1"
+
+	// Delay given to the running requests once the api is asked to stop
+	shutdownTimeout = 10 * time.Second
 )
 
 var (
 	apiGroupe *echo.Group
+	stores    *store.Store
 )
 
 // @title Cardinal API
@@ -37,7 +47,11 @@ var (
 // @securityDefinitions.apikey  BearerAuth
 // @in                          header
 // @name                        Authorization
-func InitRouter() *echo.Echo {
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        Authorization
+func InitRouter(s *store.Store) *echo.Echo {
+	stores = s
 	e := echo.New()
 
 	e.Use(middleware.Logger())
@@ -51,53 +65,75 @@ func InitRouter() *echo.Echo {
 	})
 
 	initAuth()
+	initKeys()
+	e.GET("/.well-known/jwks.json", getJwks)
 
 	config := middleware.JWTConfig{
-		Claims:     &JwtCustomClaims{},
-		SigningKey: []byte(secret),
-		Skipper: func(c echo.Context) bool {
-			if c.Request().URL.Path == base_path+"/users/register" ||
-				c.Request().URL.Path == base_path+"/users/login" {
-				return true
-			}
-			if c.Get("user") == nil {
-				return false
-			}
-			user, success := c.Get("user").(*jwt.Token)
-			if !success {
-				return false
-			}
-			claims, success := user.Claims.(*JwtCustomClaims)
-			if !success {
-				return false
-			}
-			accessLevel := claims.Access_level
-
-			if c.Request().Method == "GET" {
-				return accessLevel <= 2
-			}
-
-			return strings.HasPrefix(c.Request().URL.Path, "/users") || accessLevel <= 1
-		},
+		ParseTokenFunc: parseToken,
+		Skipper:        isPublicOrApiKey,
 	}
-	apiGroupe.Use(middleware.JWTWithConfig(config))
+	apiGroupe.Use(apiKeyAuth, middleware.JWTWithConfig(config))
 
 	initUsers()
+	initSessions()
+	initDiscord()
+	initApiKeys()
+	initTotp()
 	initGuilds()
+	initAccess()
 	initMembers()
+	initLevels()
+	initLeaderboard()
+	initRewards()
 	initChannels()
 	initRoles()
 	initBans()
 	initWarn()
+	initCases()
 
 	return e
 }
 
-func Run() {
-	e := InitRouter()
+// isPublic reports whether the route can be reached without token.
+func isPublic(c echo.Context) bool {
+	switch c.Request().URL.Path {
+	case base_path + "/users/register", base_path + "/users/login",
+		base_path + "/users/login/2fa", base_path + "/users/refresh", base_path + "/users/logout",
+		base_path + "/users/discord/login", base_path + "/users/discord/callback",
+		base_path + "/users/verify-email", base_path + "/users/password-reset",
+		base_path + "/users/password-reset/confirm":
+		return true
+	}
+	return false
+}
+
+// isPublicOrApiKey reports whether the jwt middleware must be skipped,
+// either because the route is public or because the request was authenticated with an api key.
+func isPublicOrApiKey(c echo.Context) bool {
+	_, apiKey := c.Get("apiKey").(*models.ApiKey)
+	return apiKey || isPublic(c)
+}
 
-	log.Info("Started cardinal API " + version + ", made by gyroskan!")
-	if err := e.Start(":5005"); err != nil {
-		log.Fatal("unable to start api. ", err)
+func Run(s *store.Store) {
+	e := InitRouter(s)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	jobs := StartJobs(ctx)
+
+	go func() {
+		log.Info("Started cardinal API " + version + ", made by gyroskan!")
+		if err := e.Start(":5005"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("unable to start api. ", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Stopping cardinal API...")
+	shutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdown); err != nil {
+		log.Warn("Error stopping api: ", err)
 	}
+	jobs.Wait()
 }
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const apiKeyScheme = "ApiKey "
+
+func initApiKeys() {
+	keys := apiGroupe.Group("/users/:username/keys", isAdminOrLoggedIn, notApiKey)
+	keys.GET("", getApiKeys)
+	keys.POST("", createApiKey)
+	keys.DELETE("/:keyID", deleteApiKey)
+}
+
+// apiKeyAuth authenticates the requests carrying an `Authorization: ApiKey <key>` header.
+// The owner of the key is set as the logged in user, so that the jwt middleware is skipped,
+// with the permissions granted by both the key and its owner.
+func apiKeyAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(auth, apiKeyScheme) {
+			return next(c)
+		}
+
+		key, err := stores.ApiKeys.Get(hashToken(strings.TrimPrefix(auth, apiKeyScheme)))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid api key")
+			}
+			log.Warn("ApiKeyAuth/ Error getting key: ", err)
+			return echo.ErrInternalServerError
+		}
+		now := time.Now()
+		if key.ExpiresAt.Valid() && now.After(key.ExpiresAt.TimeValue()) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Api key expired")
+		}
+
+		user, err := stores.Users.Get(key.Username)
+		if err != nil {
+			log.Warn("ApiKeyAuth/ Error getting user: ", err)
+			return echo.ErrInternalServerError
+		}
+		if user.Banned {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid api key")
+		}
+
+		if err := stores.ApiKeys.Touch(key.KeyID, now.UTC()); err != nil {
+			log.Warn("ApiKeyAuth/ Error updating last use: ", err)
+		}
+
+		c.Set("user", &jwt.Token{
+			Claims: &JwtCustomClaims{
+				Username:     user.Username,
+				Access_level: user.AccessLvl,
+				TokenVersion: user.TokenVersion,
+			},
+			Valid: true,
+		})
+		c.Set("apiKey", &key)
+		setPermissions(c, user, key.Permissions().Intersect(user.Permissions()))
+
+		return next(c)
+	}
+}
+
+// notApiKey forbids the route to requests authenticated with an api key.
+func notApiKey(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := c.Get("apiKey").(*models.ApiKey); ok {
+			return echo.ErrForbidden
+		}
+		return next(c)
+	}
+}
+
+// @Summary      Get Api Keys
+// @Tags         Users
+// @Description  Get the api keys of the user
+// @Param        username  path      string         true  "username"
+// @Success      200       {array}   models.ApiKey  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/keys [GET]
+func getApiKeys(c echo.Context) error {
+	keys, err := stores.ApiKeys.List(c.Param("username"))
+	if err != nil {
+		log.Warn("GetApiKeys/ Error getting keys: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// @Summary      Create Api Key
+// @Tags         Users
+// @Description  Create an api key for the user. The key is only returned in this response.
+// @Accept       json
+// @Produce      json
+// @Param        username  path      string                 true  "username"
+// @Param        key       body      models.ApiKeyCreation  true  "Key values"
+// @Success      201       {object}  models.ApiKey          "Created key"
+// @Failure      400       "Invalid values"
+// @Failure      403       "Forbidden"
+// @Failure      404       "User not found"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/keys [POST]
+func createApiKey(c echo.Context) error {
+	username := c.Param("username")
+
+	var keyCreate models.ApiKeyCreation
+	if err := c.Bind(&keyCreate); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err := keyCreate.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if _, err := stores.Users.Get(username); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
+		}
+		log.Warn("CreateApiKey/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	random, err := generateToken()
+	if err != nil {
+		log.Warn("CreateApiKey/ Error generating key: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	raw := models.ApiKeyPrefix + random
+
+	key := models.ApiKey{
+		Username:  username,
+		Name:      keyCreate.Name,
+		Prefix:    raw[:len(models.ApiKeyPrefix)+8],
+		KeyHash:   hashToken(raw),
+		Roles:     keyCreate.Roles,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: keyCreate.ExpiresAt,
+	}
+	if err := stores.ApiKeys.Create(&key); err != nil {
+		log.Warn("CreateApiKey/ Error inserting key: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	key.Key = raw
+
+	return c.JSON(http.StatusCreated, key)
+}
+
+// @Summary      Revoke Api Key
+// @Tags         Users
+// @Description  Revoke an api key of the user
+// @Param        username  path  string  true  "username"
+// @Param        keyID     path  int     true  "key id"
+// @Success      204       "No Content"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not found"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/keys/{keyID} [DELETE]
+func deleteApiKey(c echo.Context) error {
+	keyID, err := strconv.Atoi(c.Param("keyID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "key " + c.Param("keyID") + " not found."})
+	}
+
+	if err := stores.ApiKeys.Delete(c.Param("username"), keyID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "key " + c.Param("keyID") + " not found."})
+		}
+		log.Warn("DeleteApiKey/ Error deleting key: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
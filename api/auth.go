@@ -1,42 +1,76 @@
 package api
 
 import (
-	"database/sql"
-	"encoding/hex"
+	"errors"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"github.com/golang-jwt/jwt"
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/password"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
+// Minimum length of the SECRET
+const minSecretLength = 16
+
 var (
 	secret = os.Getenv("SECRET")
+	hasher password.Hasher
+
+	// Access level of the registered users, with no global role by default
+	registerAccessLvl = 3
 )
 
 type (
 	JwtCustomClaims struct {
 		Username     string `json:"username"`
 		Access_level int    `json:"access_lvl"`
+		TokenVersion int    `json:"ver"`
+		SessionID    int    `json:"sid,omitempty"`
 		jwt.StandardClaims
 	}
 )
 
 func initAuth() {
+	// The secret seals the signing keys, and signs the Discord states and the 2FA challenges.
+	if len(secret) < minSecretLength {
+		log.Fatalf("SECRET must be set, with at least %d characters", minSecretLength)
+	}
+
+	var err error
+	if hasher, err = password.New(os.Getenv("PASSWORD_HASHER")); err != nil {
+		log.Fatal(err)
+	}
+
+	if v := os.Getenv("REGISTER_ACCESS_LEVEL"); v != "" {
+		// Levels 0 and 1 would make every registered user an admin or an editor.
+		if registerAccessLvl, err = strconv.Atoi(v); err != nil || registerAccessLvl < 2 || registerAccessLvl > 3 {
+			log.Fatal("Invalid REGISTER_ACCESS_LEVEL, must be 2 or 3: ", v)
+		}
+	}
+
+	requireAdmin2FA, _ = strconv.ParseBool(os.Getenv("REQUIRE_ADMIN_2FA"))
+
+	initTokens()
+	initEmail()
+
 	users := apiGroupe.Group("/users")
 	users.POST("/register", registerUser)
 	users.POST("/login", loginUser)
+	users.POST("/login/2fa", loginSecondFactor)
+	users.POST("/refresh", refreshTokens)
+	users.POST("/logout", logoutUser)
 }
 
 // Register godoc
 // @Summary      Register user
 // @Tags         Users
-// @Description  Create a new user
+// @Description  Create a new user. The user is restricted until it confirms its email.
 // @Accept       json
 // @Produce      json
 // @Param        user  body      models.UserCreation  true  "User values"
@@ -56,9 +90,10 @@ func registerUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
-	salt, err := generateSalt()
+	hash, err := hasher.Hash(userCreate.Password)
 
 	if err != nil {
+		log.Warn("register/ Error hashing password: ", err)
 		return c.JSON(http.StatusInternalServerError, "Error hashing the password.")
 	}
 
@@ -66,21 +101,22 @@ func registerUser(c echo.Context) error {
 		Username:     userCreate.Username,
 		Email:        userCreate.Email,
 		DiscordID:    userCreate.DiscordID,
-		PasswordHash: hashPassword(userCreate.Password, salt),
-		Salt:         hex.EncodeToString(salt),
-		AccessLvl:    2,
+		PasswordHash: hash,
+		AccessLvl:    registerAccessLvl,
 		CreatedAt:    time.Now(),
 		Banned:       false,
 	}
 
-	if _, err := db.DB.NamedExec(models.InsertUserQuery, user); err != nil {
-		switch err.(*mysql.MySQLError).Number {
-		case 1062:
+	if err := stores.Users.Create(user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
 			return echo.NewHTTPError(http.StatusBadRequest, "Username already taken")
-		default:
-			log.Warn("register/ error inserting user in db: ", err)
-			return c.JSON(http.StatusInternalServerError, err)
 		}
+		log.Warn("register/ error inserting user in db: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	if err := sendUserToken(user, models.VerifyEmailPurpose); err != nil {
+		log.Warn("register/ Error sending verification email: ", err)
 	}
 
 	return c.JSON(http.StatusCreated, user)
@@ -95,7 +131,7 @@ func registerUser(c echo.Context) error {
 // @Produce      json
 // @Param        username  body      string  true  "username"
 // @Param        password  body      string  true  "password"
-// @Success      200       {string}  string  "Token"
+// @Success      200       {object}  object  "Access token, refresh token and access token lifetime in seconds"
 // @Failure      400       "Invalid logins"
 // @Failure      500       "Server Error"
 // @Router       /users/login [POST]
@@ -109,45 +145,154 @@ func loginUser(c echo.Context) error {
 		log.Warn("Login/ binding error: ", err)
 		return echo.NewHTTPError(http.StatusBadRequest, "Username or password invalid")
 	}
-	var user models.User
+	if locked, err := loginLocked(c, logged.Username, models.PasswordLogin); locked {
+		return err
+	}
 
-	if err := db.DB.Get(&user, "SELECT * FROM user WHERE username=?", logged.Username); err != nil {
-		if err == sql.ErrNoRows {
+	user, err := stores.Users.Get(logged.Username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			loginFailed(c, logged.Username, models.PasswordLogin, models.InvalidCredentials)
 			return echo.NewHTTPError(http.StatusBadRequest, "Username or password invalid")
 		}
 		log.Warn("GetUser/ Error getting user: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
-	salt, err := hex.DecodeString(user.Salt)
+	valid, err := checkPassword(&user, logged.Password)
 	if err != nil {
+		log.Warn("Login/ Error verifying password: ", err)
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
-
-	if user.PasswordHash != hashPassword(logged.Password, salt) {
+	if !valid {
+		loginFailed(c, logged.Username, models.PasswordLogin, models.InvalidCredentials)
 		return echo.NewHTTPError(http.StatusBadRequest, "Username or password invalid")
 	}
 
-	// Set custom claims
-	claims := &JwtCustomClaims{
-		Username:     user.Username,
-		Access_level: user.AccessLvl,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: 0,
-		},
+	return completeLogin(c, user, models.PasswordLogin)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken" form:"refreshToken"`
+}
+
+// Refresh godoc
+// @Summary      Refresh tokens
+// @Tags         Users
+// @Description  Exchange a refresh token for a new access token and a new refresh token.
+// @Description  The refresh token can only be used once.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        refreshToken  body      string  true  "refresh token"
+// @Success      200           {object}  object  "Access token, refresh token and access token lifetime in seconds"
+// @Failure      400           "Invalid request"
+// @Failure      401           "Invalid refresh token"
+// @Failure      500           "Server Error"
+// @Router       /users/refresh [POST]
+func refreshTokens(c echo.Context) error {
+	var req refreshRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "refreshToken missing")
 	}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hash := hashToken(req.RefreshToken)
+	token, err := stores.Tokens.Get(hash)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+		}
+		log.Warn("Refresh/ Error getting token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
 
-	// Generate encoded token and send it as response.
-	t, err := token.SignedString([]byte(secret))
+	if token.RevokedAt.Valid() {
+		// A rotated token is presented again, it may have been stolen.
+		log.Warn("Refresh/ Reuse of revoked refresh token of ", token.Username)
+		if err := revokeTokens(token.Username); err != nil {
+			log.Warn("Refresh/ Error revoking tokens: ", err)
+		}
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+	}
+	sessionID := int(token.SessionID.Int64Value())
+	if token.SessionID.Valid() {
+		session, err := stores.Sessions.Get(sessionID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Warn("Refresh/ Error getting session: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+		if err != nil || session.RevokedAt.Valid() {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+		}
+	}
+
+	if err := stores.Tokens.Revoke(hash); err != nil {
+		if errors.Is(err, store.ErrNotFound) { // used concurrently
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+		}
+		log.Warn("Refresh/ Error revoking token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	user, err := stores.Users.Get(token.Username)
+	if err != nil {
+		log.Warn("Refresh/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if user.Banned {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid refresh token")
+	}
+
+	tokens, err := issueTokens(c, user, sessionID)
 	if err != nil {
 		log.Warn("Error generating token: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error generating token."})
 	}
 
-	return c.JSON(http.StatusOK, echo.Map{
-		"token": t,
-	})
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary      Logout user
+// @Tags         Users
+// @Description  Revoke the refresh token and its session.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Param        refreshToken  body  string  true  "refresh token"
+// @Success      204           "No Content"
+// @Failure      400           "Invalid request"
+// @Failure      500           "Server Error"
+// @Router       /users/logout [POST]
+func logoutUser(c echo.Context) error {
+	var req refreshRequest
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "refreshToken missing")
+	}
+
+	hash := hashToken(req.RefreshToken)
+	token, err := stores.Tokens.Get(hash)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNoContent, nil)
+		}
+		log.Warn("Logout/ Error getting token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	if err := stores.Tokens.Revoke(hash); err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Warn("Logout/ Error revoking token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if token.SessionID.Valid() {
+		err := stores.Sessions.Revoke(token.Username, int(token.SessionID.Int64Value()))
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			log.Warn("Logout/ Error revoking session: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
 }
@@ -1,21 +1,50 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	// Delay between two checks of the expired bans
+	banLiftInterval = time.Minute
+
+	defaultPendingUnbansLimit = 100
+	maxPendingUnbansLimit     = 1000
 )
 
 func initBans() {
-	b := apiGroupe.Group("/guilds/:guildID/members/:memberID/bans")
-	b.GET("/", getBans).Name = "Fetch all bans of a member."
-	b.GET("/:banID", getBan).Name = "Fetch a ban of a member."
-	b.POST("/", createBan).Name = "Create a ban for a member."
-	b.DELETE("/:banID", deleteBan).Name = "Delete a ban of a member."
+	b := apiGroupe.Group("/guilds/:guildID/members/:memberID/bans", guildAccess("guildID"))
+	b.GET("/", getBans, requires(models.ModerationRead)).Name = "Fetch all bans of a member."
+	b.GET("/:banID", getBan, requires(models.ModerationRead)).Name = "Fetch a ban of a member."
+	b.POST("/", createBan, requires(models.ModerationWrite)).Name = "Create a ban for a member."
+	b.POST("/:banID/lift", liftBan, requires(models.ModerationWrite)).Name = "Lift a ban of a member."
+	b.DELETE("/:banID", deleteBan, requires(models.ModerationWrite)).Name = "Delete a ban of a member."
+
+	u := apiGroupe.Group("/guilds/:guildID/bans/pending-unbans", guildAccess("guildID"))
+	u.GET("", getPendingUnbans, requires(models.ModerationRead)).Name = "Fetch lifted bans to unban."
+	u.DELETE("/:banID", deletePendingUnban, requires(models.ModerationWrite)).Name = "Acknowledge an unban."
+}
+
+// liftExpiredBans lifts the bans whose expiry passed, so that the bot unbans their members.
+// It is run by the LiftExpiredBans job.
+func liftExpiredBans() {
+	bans, err := stores.Bans.LiftExpired(time.Now().UTC())
+	if err != nil {
+		log.Warn("LiftExpiredBans/ Error lifting bans: ", err)
+		return
+	}
+	if len(bans) > 0 {
+		log.Info("Lifted ", len(bans), " expired bans")
+	}
 }
 
 // @Summary      Get Member Bans
@@ -29,10 +58,8 @@ func initBans() {
 // @Router       /guilds/{guildID}/members/{memberID}/bans [GET]
 func getBans(c echo.Context) error {
 	guildID := c.Param("guildID")
-	memberID := c.Param("guildID")
-	var bans []models.Ban
-
-	err := db.DB.Select(&bans, "SELECT * FROM ban WHERE guild_id=? AND member_id=?", guildID, memberID)
+	memberID := c.Param("memberID")
+	bans, err := stores.Bans.List(guildID, memberID)
 
 	if err != nil {
 		log.Warn("GetBans/ Error retrieving bans: ", err)
@@ -55,14 +82,16 @@ func getBans(c echo.Context) error {
 // @Router       /guilds/{guildID}/members/{memberID}/bans/{banID} [GET]
 func getBan(c echo.Context) error {
 	guildID := c.Param("guildID")
-	memberID := c.Param("guildID")
-	banID := c.Param("banID")
-	var ban models.Ban
+	memberID := c.Param("memberID")
+	banID, err := strconv.Atoi(c.Param("banID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, nil)
+	}
 
-	err := db.DB.Get(&ban, "SELECT * FROM ban WHERE guild_id=? AND member_id=? AND ban_id=?", guildID, memberID, banID)
+	ban, err := stores.Bans.Get(guildID, memberID, banID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Warn("GetBan/ Error retrieving Ban: ", err)
@@ -74,7 +103,8 @@ func getBan(c echo.Context) error {
 
 // @Summary      Create ban
 // @Tags         Bans
-// @Description  Create a new ban for a member.
+// @Description  Create a new ban for a member, added to the moderation cases of the guild.
+// @Description  bannedAt defaults to the current time.
 // @Accept       json
 // @Produce      json
 // @Param        guildID   path      string      true  "guild id"
@@ -83,40 +113,37 @@ func getBan(c echo.Context) error {
 // @Success      201       {object}  models.Ban  "Created role"
 // @Failure      400       "Wrong values"
 // @Failure      403       "Forbidden"
+// @Failure      404       "Guild not found"
 // @Failure      500       "Server Error"
 // @Router       /guilds/{guildID}/members/{memberID}/bans [POST]
 func createBan(c echo.Context) error {
 	guildID := c.Param("guildID")
-	memberID := c.Param("guildID")
+	memberID := c.Param("memberID")
 	var ban models.Ban
 
 	if err := c.Bind(&ban); err != nil || ban.GuildID != guildID || ban.MemberID != memberID {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "ban": ban})
 	}
-
-	query, err := db.DB.PrepareNamed(models.CreateBanQuery)
-
-	if err != nil {
-		log.Error("CreateBan/ error while preparing query:", err)
-		return c.JSON(http.StatusInternalServerError, nil)
+	if ban.BannedAt.IsZero() {
+		ban.BannedAt = time.Now()
 	}
-
-	res, err := query.Exec(ban)
-
-	if err != nil {
-		log.Error("CreateBan/ error while executing query:", err)
-		// TODO switch case of sql errors.
-		return c.JSON(http.StatusInternalServerError, nil)
+	// Compared to the dates of the warns, even as text with sqlite
+	ban.BannedAt = ban.BannedAt.UTC()
+	if ban.ExpiresAt.Valid() {
+		// Compared to the current time by the scheduler, even as text with sqlite
+		ban.ExpiresAt = nulltype.NullTimeOf(ban.ExpiresAt.TimeValue().UTC())
 	}
+	// Bans are lifted with the lift route only
+	ban.LiftedAt, ban.LiftedBy, ban.UnbannedAt = nulltype.NullTime{}, nulltype.NullString{}, nulltype.NullTime{}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		log.Error("CreateBan/ Error while getting last index: ", err)
-		// TODO switch case of sql errors.
+	if err := stores.Bans.Create(&ban); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Error("CreateBan/ error while executing query:", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
-	ban.BanID = int(id)
 	return c.JSON(http.StatusCreated, ban)
 }
 
@@ -135,19 +162,134 @@ func createBan(c echo.Context) error {
 // @Router       /guilds/{guildID}/members/{memberID}/bans/{banID} [DELETE]
 func deleteBan(c echo.Context) error {
 	guildID := c.Param("guildID")
-	memberID := c.Param("guildID")
-	banID := c.Param("banID")
+	memberID := c.Param("memberID")
+	banID, err := strconv.Atoi(c.Param("banID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, nil)
+	}
 
-	res, err := db.DB.Exec("DELETE FROM ban WHERE guild_id = ? AND member_id= ? AND ban_id=?", guildID, memberID, banID)
+	err = stores.Bans.Delete(guildID, memberID, banID)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Error("DeleteBan/ Error while deleting ban from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the ban."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Lift member's ban
+// @Tags         Bans
+// @Description  Lift a ban of the member before its expiry, so that the bot unbans it.
+// @Description  An unban case with the reason is added to the moderation cases of the guild.
+// @Accept       json
+// @Produce      json
+// @Param        guildID   path      string      true   "Guild id"
+// @Param        memberID  path      string      true   "member id"
+// @Param        banID     path      string      true   "ban id"
+// @Param        liftedBy  body      string      false  "id of the user lifting the ban"
+// @Param        reason    body      string      false  "reason of the unban"
+// @Success      200       {object}  models.Ban  "Lifted ban"
+// @Failure      400       "Wrong values"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      409       "Already lifted"
+// @Failure      500       "Server Error"
+// @Router       /guilds/{guildID}/members/{memberID}/bans/{banID}/lift [POST]
+func liftBan(c echo.Context) error {
+	guildID := c.Param("guildID")
+	memberID := c.Param("memberID")
+	banID, err := strconv.Atoi(c.Param("banID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, nil)
+	}
+
+	var req struct {
+		LiftedBy nulltype.NullString `json:"liftedBy" form:"liftedBy"`
+		Reason   nulltype.NullString `json:"reason" form:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	ban, err := stores.Bans.Get(guildID, memberID, banID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
+		log.Error("LiftBan/ Error retrieving ban: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	now := time.Now().UTC()
+	err = stores.Bans.Lift(guildID, memberID, banID, req.LiftedBy, req.Reason, now)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "The ban was already lifted."})
+		}
+		log.Error("LiftBan/ Error lifting ban: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	ban.LiftedAt = nulltype.NullTimeOf(now)
+	ban.LiftedBy = req.LiftedBy
+
+	return c.JSON(http.StatusOK, ban)
+}
+
+// @Summary      Get pending unbans
+// @Tags         Bans
+// @Description  Fetch the lifted bans of the guild, expired or lifted by a moderator, whose member was not unbanned yet, oldest first.
+// @Description  Once the member is unbanned, the bot deletes the ban from the pending unbans.
+// @Param        guildID  path     string      true   "guild id"
+// @Param        limit    query    int         false  "limit to fetch, at most 1000"  default(100)
+// @Success      200      {array}  models.Ban  "OK"
+// @Failure      403      "Forbidden"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/bans/pending-unbans [GET]
+func getPendingUnbans(c echo.Context) error {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPendingUnbansLimit
+	} else if limit > maxPendingUnbansLimit {
+		limit = maxPendingUnbansLimit
+	}
+
+	bans, err := stores.Bans.PendingUnbans(c.Param("guildID"), limit)
+	if err != nil {
+		log.Warn("GetPendingUnbans/ Error retrieving bans: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, bans)
+}
+
+// @Summary      Acknowledge unban
+// @Tags         Bans
+// @Description  Record that the member of a lifted ban was unbanned, removing the ban from the pending unbans.
+// @Param        guildID  path  string  true  "guild id"
+// @Param        banID    path  string  true  "ban id"
+// @Success      204      "No Content"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Not pending"
+// @Failure      500      "Server Error"
+// @Router       /guilds/{guildID}/bans/pending-unbans/{banID} [DELETE]
+func deletePendingUnban(c echo.Context) error {
+	banID, err := strconv.Atoi(c.Param("banID"))
+	if err != nil {
 		return c.JSON(http.StatusNotFound, nil)
 	}
 
+	err = stores.Bans.SetUnbanned(c.Param("guildID"), banID, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
+		log.Error("DeletePendingUnban/ Error updating ban: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
 	return c.JSON(http.StatusNoContent, nil)
 }
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	defaultCasesLimit = 50
+	maxCasesLimit     = 100
+)
+
+func initCases() {
+	g := apiGroupe.Group("/guilds/:guildID/cases", guildAccess("guildID"))
+	g.GET("", getCases, requires(models.ModerationRead)).Name = "Fetch moderation cases of guild."
+	g.GET("/:caseNumber", getCase, requires(models.ModerationRead)).Name = "Fetch a moderation case."
+	g.POST("", createCase, requires(models.ModerationWrite)).Name = "Create a kick, mute or note case."
+	g.PATCH("/:caseNumber", updateCaseReason, requires(models.ModerationWrite)).Name = "Edit the reason of a case."
+}
+
+// caseNumberParam returns the case number of the path, or 0 if invalid.
+func caseNumberParam(c echo.Context) int {
+	n, err := strconv.Atoi(c.Param("caseNumber"))
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// @Summary      Get moderation cases
+// @Tags         Cases
+// @Description  Get the moderation log of the guild, most recent case first.
+// @Description  Dates are RFC 3339, from is inclusive and to exclusive.
+// @Param        guildID      path     string       true   "guild id"
+// @Param        memberID     query    string       false  "member id"
+// @Param        moderatorID  query    string       false  "moderator id"
+// @Param        type         query    string       false  "warn, ban, unban, kick, mute or note"
+// @Param        from         query    string       false  "first date"
+// @Param        to           query    string       false  "last date"
+// @Param        before       query    int          false  "lower last case number fetched"
+// @Param        limit        query    int          false  "limit to fetch, at most 100"  default(50)
+// @Success      200          {array}  models.Case  "OK"
+// @Failure      400          "Wrong values"
+// @Failure      403          "Forbidden"
+// @Failure      500          "Server error"
+// @Router       /guilds/{guildID}/cases [GET]
+func getCases(c echo.Context) error {
+	filter := store.CaseFilter{
+		MemberID:    c.QueryParam("memberID"),
+		ModeratorID: c.QueryParam("moderatorID"),
+		Type:        c.QueryParam("type"),
+	}
+	if filter.Type != "" && !models.ValidCaseType(filter.Type) {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "Unknown case type " + filter.Type})
+	}
+	for param, date := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.QueryParam(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": param + " must be an RFC 3339 date"})
+		}
+		*date = t.UTC()
+	}
+	filter.Before, _ = strconv.Atoi(c.QueryParam("before"))
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultCasesLimit
+	} else if limit > maxCasesLimit {
+		limit = maxCasesLimit
+	}
+	filter.Limit = limit
+
+	cases, err := stores.Cases.List(c.Param("guildID"), filter)
+	if err != nil {
+		log.Warn("GetCases/ Error retrieving cases: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, cases)
+}
+
+// @Summary      Get one moderation case
+// @Tags         Cases
+// @Description  Get a case of the moderation log of the guild by its number.
+// @Param        guildID     path      string       true  "guild id"
+// @Param        caseNumber  path      int          true  "case number"
+// @Success      200         {object}  models.Case  "OK"
+// @Failure      403         "Forbidden"
+// @Failure      404         "Not Found"
+// @Failure      500         "Server error"
+// @Router       /guilds/{guildID}/cases/{caseNumber} [GET]
+func getCase(c echo.Context) error {
+	caseNumber := caseNumberParam(c)
+	if caseNumber == 0 {
+		return c.JSON(http.StatusNotFound, nil)
+	}
+
+	modCase, err := stores.Cases.Get(c.Param("guildID"), caseNumber)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
+		log.Warn("GetCase/ Error retrieving case: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, modCase)
+}
+
+// @Summary      Create moderation case
+// @Tags         Cases
+// @Description  Add a kick, mute or note to the moderation log of the guild, with the next case number.
+// @Description  Warns, bans and unbans are logged by their own routes.
+// @Description  createdAt defaults to the current date, and expiresAt is kept for mutes only.
+// @Accept       json
+// @Produce      json
+// @Param        guildID  path      string       true  "guild id"
+// @Param        case     body      models.Case  true  "case"
+// @Success      201      {object}  models.Case  "Created case"
+// @Failure      400      "Wrong values"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Guild not found"
+// @Failure      500      "Server Error"
+// @Router       /guilds/{guildID}/cases [POST]
+func createCase(c echo.Context) error {
+	guildID := c.Param("guildID")
+	var modCase models.Case
+
+	if err := c.Bind(&modCase); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if modCase.GuildID != "" && modCase.GuildID != guildID {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "guildID does not match the path"})
+	}
+	switch modCase.Type {
+	case models.KickCase, models.MuteCase, models.NoteCase:
+	default:
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "type must be kick, mute or note"})
+	}
+	if modCase.MemberID == "" {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "memberID is required"})
+	}
+
+	modCase.GuildID = guildID
+	if modCase.CreatedAt.IsZero() {
+		modCase.CreatedAt = time.Now()
+	}
+	modCase.CreatedAt = modCase.CreatedAt.UTC()
+	if modCase.Type == models.MuteCase && modCase.ExpiresAt.Valid() {
+		modCase.ExpiresAt = nulltype.NullTimeOf(modCase.ExpiresAt.TimeValue().UTC())
+	} else {
+		modCase.ExpiresAt = nulltype.NullTime{}
+	}
+	modCase.UpdatedAt, modCase.WarnID, modCase.BanID = nulltype.NullTime{}, nulltype.NullInt64{}, nulltype.NullInt64{}
+
+	if err := stores.Cases.Create(&modCase); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Error("CreateCase/ Error inserting case: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusCreated, modCase)
+}
+
+// @Summary      Edit case reason
+// @Tags         Cases
+// @Description  Edit the reason of a case, and of its warn or ban for warn and ban cases.
+// @Accept       json
+// @Produce      json
+// @Param        guildID     path      string       true  "guild id"
+// @Param        caseNumber  path      int          true  "case number"
+// @Param        reason      body      string       true  "new reason, null to remove it"
+// @Success      200         {object}  models.Case  "Updated case"
+// @Failure      400         "Wrong values"
+// @Failure      403         "Forbidden"
+// @Failure      404         "Not Found"
+// @Failure      500         "Server Error"
+// @Router       /guilds/{guildID}/cases/{caseNumber} [PATCH]
+func updateCaseReason(c echo.Context) error {
+	guildID := c.Param("guildID")
+	caseNumber := caseNumberParam(c)
+	if caseNumber == 0 {
+		return c.JSON(http.StatusNotFound, nil)
+	}
+
+	var req struct {
+		Reason nulltype.NullString `json:"reason" form:"reason"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	err := stores.Cases.SetReason(guildID, caseNumber, req.Reason, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
+		log.Error("UpdateCaseReason/ Error updating case: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	modCase, err := stores.Cases.Get(guildID, caseNumber)
+	if err != nil {
+		log.Warn("UpdateCaseReason/ Error retrieving case: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, modCase)
+}
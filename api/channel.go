@@ -1,24 +1,24 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
 func initChannels() {
-	chans := apiGroupe.Group("/guilds/:guildID/channels")
-	chans.GET("/", getChannels).Name = "Fetch channels of a guild."
-	chans.GET("/:id", getChannel).Name = "Fetch channel of a guild."
-	chans.POST("/", createChannel).Name = "Create channel."
-	chans.PATCH("/:id", updateChannel).Name = "Update channel values."
-	chans.DELETE("/:id", deleteChannel).Name = "Delete channel."
+	chans := apiGroupe.Group("/guilds/:guildID/channels", guildAccess("guildID"))
+	chans.GET("/", getChannels, requires(models.GuildsRead)).Name = "Fetch channels of a guild."
+	chans.GET("/:id", getChannel, requires(models.GuildsRead)).Name = "Fetch channel of a guild."
+	chans.POST("/", createChannel, requires(models.GuildsWrite)).Name = "Create channel."
+	chans.PATCH("/:id", updateChannel, requires(models.GuildsWrite)).Name = "Update channel values."
+	chans.DELETE("/:id", deleteChannel, requires(models.GuildsWrite)).Name = "Delete channel."
 }
 
 // @Summary      Get Guild channels
@@ -41,17 +41,11 @@ func getChannels(c echo.Context) error {
 	if c.QueryParam("xpBlacklisted") != "" {
 		xpBlacklisted, _ = strconv.ParseBool(c.QueryParam("xpBlacklist"))
 	}
-	var channels []models.Channel
 
-	query := `SELECT * FROM channel WHERE guild_id=?`
-	if ignored {
-		query += " AND ignored=true"
-	}
-	if xpBlacklisted {
-		query += " AND xp_blacklisted=true"
-	}
-
-	err := db.DB.Select(&channels, query, guildID)
+	channels, err := stores.Channels.List(guildID, store.ChannelFilter{
+		Ignored:       ignored,
+		XpBlacklisted: xpBlacklisted,
+	})
 
 	if err != nil {
 		log.Warn("GetChannels/ Error retrieving channels from guildID: ", err)
@@ -75,16 +69,14 @@ func getChannel(c echo.Context) error {
 	guildID := c.Param("guildID")
 	chanID := c.Param("id")
 
-	var chann models.Channel
-
-	err := db.DB.Get(&chann, "SELECT * FROM channel WHERE guild_id=? AND channel_id=?", guildID, chanID)
+	chann, err := stores.Channels.Get(guildID, chanID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Error("getChannel/ Error retrieving channel: ", err)
-		c.JSON(http.StatusInternalServerError, nil)
+		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
 	return c.JSON(http.StatusOK, chann)
@@ -100,20 +92,27 @@ func getChannel(c echo.Context) error {
 // @Success      201      {object}  models.Channel  "Created channel"
 // @Failure      400      "Wrong values"
 // @Failure      403      "Forbidden"
+// @Failure      409      "Conflict"
 // @Failure      500      "Server Error"
 // @Router       /guilds/{guildID}/channels [POST]
 func createChannel(c echo.Context) error {
-	var channel models.Channel
+	channel := models.Channel{XpMultiplier: 1}
 	guildID := c.Param("guildID")
 
 	if err := c.Bind(&channel); err != nil || channel.GuildID != guildID {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "channel": channel})
 	}
+	if !validXpMultiplier(channel.XpMultiplier) {
+		return echo.NewHTTPError(http.StatusBadRequest, xpMultiplierError)
+	}
 
-	_, err := db.DB.NamedExec(models.CreateChannelQuery, channel)
+	err := stores.Channels.Create(channel)
 
 	if err != nil {
-		// TODO switch case of sql errors.
+		if errors.Is(err, store.ErrConflict) {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "The channel with id " + channel.ChannelID + " already exists."})
+		}
+		log.Warn("CreateChannel/ Error inserting channel: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
@@ -137,26 +136,27 @@ func updateChannel(c echo.Context) error {
 	guildID := c.Param("guildID")
 	chanID := c.Param("id")
 
-	var channel models.Channel
-
-	err := db.DB.Get(&channel, "SELECT * FROM channel WHERE guild_id=? AND channel_id=?", guildID, chanID)
+	channel, err := stores.Channels.Get(guildID, chanID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Error("getChannel/ Error retrieving channel: ", err)
-		c.JSON(http.StatusInternalServerError, nil)
+		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
 	if err := json.NewDecoder(c.Request().Body).Decode(&channel); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
+	if !validXpMultiplier(channel.XpMultiplier) {
+		return echo.NewHTTPError(http.StatusBadRequest, xpMultiplierError)
+	}
 
 	channel.ChannelID = chanID
 	channel.GuildID = guildID
 
-	_, err = db.DB.NamedExec(models.UpdateChannelQuery, channel)
+	err = stores.Channels.Update(channel)
 	if err != nil {
 		log.Warn("UpdateMember/ Error updating member: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
@@ -181,16 +181,15 @@ func deleteChannel(c echo.Context) error {
 	guildID := c.Param("guildID")
 	chanID := c.Param("id")
 
-	res, err := db.DB.Exec("DELETE FROM channel WHERE guild_id = ? AND channel_id = ?", guildID, chanID)
+	err := stores.Channels.Delete(guildID, chanID)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Warn("HardDeleteMember/ Error while deleting member from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the channel."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
-	}
-
 	return c.JSON(http.StatusNoContent, nil)
 }
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/discord"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	discordStateCookie = "discord_state"
+	discordStateTTL    = 10 * time.Minute
+)
+
+var (
+	discordClient *discord.Client
+
+	usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.]`)
+)
+
+type (
+	// discordState is the signed state sent to Discord, bound to the browser by a cookie holding its nonce.
+	discordState struct {
+		Nonce string `json:"nonce"`
+		Link  string `json:"link,omitempty"` // Username of the user linking its account, empty for a login
+		jwt.StandardClaims
+	}
+)
+
+// initDiscord enables the Discord login when DISCORD_CLIENT_ID is set.
+func initDiscord() {
+	clientID := os.Getenv("DISCORD_CLIENT_ID")
+	if clientID == "" {
+		return
+	}
+	discordClient = discord.New(
+		os.Getenv("DISCORD_API_URL"),
+		clientID,
+		os.Getenv("DISCORD_CLIENT_SECRET"),
+		os.Getenv("DISCORD_REDIRECT_URI"),
+	)
+
+	d := apiGroupe.Group("/users/discord")
+	d.GET("/login", discordLogin)
+	d.GET("/callback", discordCallback)
+	d.GET("/link", discordLink)
+}
+
+// @Summary      Login with Discord
+// @Tags         Users
+// @Description  Redirect to Discord to login. A user is created on the first login.
+// @Success      302  "Redirect to Discord"
+// @Failure      500  "Server Error"
+// @Router       /users/discord/login [GET]
+func discordLogin(c echo.Context) error {
+	url, err := discordAuthorizeURL(c, "")
+	if err != nil {
+		log.Warn("DiscordLogin/ Error creating state: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+// @Summary      Link Discord account
+// @Tags         Users
+// @Description  Get the Discord url to redirect the logged in user to, in order to link its Discord account.
+// @Produce      json
+// @Success      200  {object}  object  "Url to redirect to"
+// @Failure      403  "Forbidden"
+// @Failure      500  "Server Error"
+// @Router       /users/discord/link [GET]
+func discordLink(c echo.Context) error {
+	if _, ok := c.Get("apiKey").(*models.ApiKey); ok {
+		return echo.ErrForbidden
+	}
+
+	url, err := discordAuthorizeURL(c, loggedUsername(c))
+	if err != nil {
+		log.Warn("DiscordLink/ Error creating state: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"url": url})
+}
+
+// @Summary      Discord callback
+// @Tags         Users
+// @Description  Finish the Discord login or account linking.
+// @Description  A login returns the tokens of the user, or a 2FA challenge, a link returns the updated user.
+// @Produce      json
+// @Param        code   query     string  true  "authorization code"
+// @Param        state  query     string  true  "state"
+// @Success      200    {object}  object  "Access token, refresh token and access token lifetime in seconds"
+// @Failure      400    "Invalid request"
+// @Failure      409    "Discord account already linked to another user"
+// @Failure      502    "Discord error"
+// @Failure      500    "Server Error"
+// @Router       /users/discord/callback [GET]
+func discordCallback(c echo.Context) error {
+	if e := c.QueryParam("error"); e != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Discord authorization failed: "+e)
+	}
+
+	state, err := parseDiscordState(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid state")
+	}
+	c.SetCookie(&http.Cookie{Name: discordStateCookie, Path: base_path + "/users/discord", MaxAge: -1})
+
+	accessToken, err := discordClient.Exchange(c.Request().Context(), c.QueryParam("code"))
+	if err != nil {
+		log.Warn("DiscordCallback/ ", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Discord authorization failed")
+	}
+	account, err := discordClient.CurrentUser(c.Request().Context(), accessToken)
+	if err != nil {
+		log.Warn("DiscordCallback/ ", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "Discord authorization failed")
+	}
+
+	owner, err := stores.Users.GetByDiscordID(account.ID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Warn("DiscordCallback/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	found := err == nil
+
+	if state.Link != "" {
+		if found && owner.Username != state.Link {
+			return c.JSON(http.StatusConflict, echo.Map{"error": "Discord account already linked to another user."})
+		}
+		if err := stores.Users.LinkDiscord(state.Link, account.ID); err != nil {
+			log.Warn("DiscordCallback/ Error linking account: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+		user, err := stores.Users.Get(state.Link)
+		if err != nil {
+			log.Warn("DiscordCallback/ Error getting user: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+		return c.JSON(http.StatusOK, user)
+	}
+
+	if !found {
+		if owner, err = createDiscordUser(account); err != nil {
+			if errors.Is(err, store.ErrConflict) {
+				return c.JSON(http.StatusConflict, echo.Map{"error": "No username available for the Discord account."})
+			}
+			log.Warn("DiscordCallback/ Error creating user: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+	}
+
+	return completeLogin(c, owner, models.DiscordLogin)
+}
+
+// discordAuthorizeURL returns the Discord url to redirect to, and binds its state to the browser with a cookie.
+func discordAuthorizeURL(c echo.Context, link string) (string, error) {
+	nonce, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	state := jwt.NewWithClaims(jwt.SigningMethodHS256, &discordState{
+		Nonce: nonce,
+		Link:  link,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(discordStateTTL).Unix(),
+		},
+	})
+	signed, err := state.SignedString([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     discordStateCookie,
+		Value:    nonce,
+		Path:     base_path + "/users/discord",
+		MaxAge:   int(discordStateTTL.Seconds()),
+		Secure:   c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return discordClient.AuthorizeURL(signed), nil
+}
+
+// parseDiscordState verifies the state returned by Discord against the cookie of the browser.
+func parseDiscordState(c echo.Context) (*discordState, error) {
+	state := &discordState{}
+	_, err := jwt.ParseWithClaims(c.QueryParam("state"), state, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cookie, err := c.Cookie(discordStateCookie)
+	if err != nil {
+		return nil, err
+	}
+	if state.Nonce == "" || cookie.Value != state.Nonce {
+		return nil, errors.New("state does not match")
+	}
+
+	return state, nil
+}
+
+// createDiscordUser creates a user without password for the Discord account,
+// named after the Discord username when it is available.
+// The Discord email is only kept when Discord verified it.
+func createDiscordUser(account discord.User) (models.User, error) {
+	base := usernameInvalidChars.ReplaceAllString(strings.ToLower(account.Username), "")
+	candidates := []string{
+		truncate(base, 20),
+		truncate(base, 15) + "_" + last(account.ID, 4),
+		truncate(base, 11) + "_" + last(account.ID, 8),
+	}
+
+	user := models.User{
+		DiscordID:       nulltype.NullStringOf(account.ID),
+		DiscordVerified: true,
+		AccessLvl:       registerAccessLvl,
+		CreatedAt:       time.Now(),
+	}
+	if account.Verified && account.Email != "" {
+		user.Email = account.Email
+		user.EmailVerified = true
+	}
+
+	var err error
+	for _, username := range candidates {
+		if len(username) < 3 {
+			continue
+		}
+		user.Username = username
+		if err = stores.Users.Create(user); !errors.Is(err, store.ErrConflict) {
+			break
+		}
+	}
+	if err == nil && user.Username == "" {
+		err = store.ErrConflict
+	}
+	if err == nil {
+		// Other users can no longer claim the discord id without verification.
+		err = stores.Users.LinkDiscord(user.Username, account.ID)
+	}
+
+	return user, err
+}
+
+// truncate returns the first n bytes of s.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// last returns the last n bytes of s.
+func last(s string, n int) string {
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return s
+}
@@ -0,0 +1,264 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gyroskan/cardinal/mail"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+var (
+	mailer mail.Mailer
+	// Url of the front end, used to build the links sent by email
+	publicURL = os.Getenv("PUBLIC_URL")
+)
+
+// initEmail selects the mailer from the MAILER setting and registers the public email routes.
+func initEmail() {
+	var err error
+	if mailer, err = mail.New(os.Getenv("MAILER")); err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := mailer.(mail.Log); ok {
+		log.Warn("Emails are written to the logs with their tokens, MAILER=log must not be used in production.")
+	}
+
+	users := apiGroupe.Group("/users")
+	users.POST("/verify-email", verifyEmail)
+	users.POST("/password-reset", requestPasswordReset)
+	users.POST("/password-reset/confirm", confirmPasswordReset)
+}
+
+// sendUserToken issues a token for the purpose and sends it to the email of the user.
+// The email is sent in the background so that the response time does not depend on the mail server.
+func sendUserToken(user models.User, purpose string) error {
+	if user.Email == "" {
+		return errors.New("user " + user.Username + " has no email")
+	}
+
+	ttl, subject, path, text := verifyEmailTTL, "Confirm your email",
+		"/verify-email", "Confirm the email of your cardinal account %s"
+	if purpose == models.ResetPasswordPurpose {
+		ttl, subject, path, text = resetPasswordTTL, "Reset your password",
+			"/password-reset", "A password reset was requested for your cardinal account %s.\nIgnore this email if you did not request it"
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = stores.UserTokens.Create(models.UserToken{
+		TokenHash: hashToken(token),
+		Username:  user.Username,
+		Purpose:   purpose,
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(ttl).UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(text, user.Username) + " with the following token, valid for " + ttl.String() + ":\n\n" + token
+	if publicURL != "" {
+		body += "\n\nor by following this link: " + publicURL + path + "?token=" + url.QueryEscape(token)
+	}
+
+	go func() {
+		if err := mailer.Send(user.Email, subject, body); err != nil {
+			log.Warn("SendUserToken/ Error sending email: ", err)
+		}
+	}()
+
+	return nil
+}
+
+// useUserToken consumes the token if it was issued for the purpose and did not expire.
+func useUserToken(token string, purpose string) (models.UserToken, error) {
+	hash := hashToken(token)
+	t, err := stores.UserTokens.Get(hash)
+	if err != nil {
+		return t, err
+	}
+	if t.Purpose != purpose || t.UsedAt.Valid() || time.Now().After(t.ExpiresAt) {
+		return t, store.ErrNotFound
+	}
+	return t, stores.UserTokens.Use(hash)
+}
+
+type tokenRequest struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+// @Summary      Verify email
+// @Tags         Users
+// @Description  Confirm the email of the user with the token sent by email.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Param        token  body  string  true  "token"
+// @Success      204    "No Content"
+// @Failure      400    "Invalid token"
+// @Failure      500    "Server Error"
+// @Router       /users/verify-email [POST]
+func verifyEmail(c echo.Context) error {
+	var req tokenRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "token missing")
+	}
+
+	token, err := useUserToken(req.Token, models.VerifyEmailPurpose)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid token")
+		}
+		log.Warn("VerifyEmail/ Error using token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	if err := stores.Users.SetEmailVerified(token.Username, true); err != nil {
+		log.Warn("VerifyEmail/ Error updating user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Send verification email
+// @Tags         Users
+// @Description  Send a new verification email to the user.
+// @Param        username  path  string  true  "username"
+// @Success      204       "No Content"
+// @Failure      400       "Email already verified"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server Error"
+// @Router       /users/{username}/verify-email [POST]
+func resendVerification(c echo.Context) error {
+	username := c.Param("username")
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
+		}
+		log.Warn("ResendVerification/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+	if user.EmailVerified {
+		return echo.NewHTTPError(http.StatusBadRequest, "Email already verified")
+	}
+
+	if err := sendUserToken(user, models.VerifyEmailPurpose); err != nil {
+		log.Warn("ResendVerification/ Error sending token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Request password reset
+// @Tags         Users
+// @Description  Send a password reset token to every user with the email.
+// @Description  The response does not tell whether a user has this email.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Param        email  body  string  true  "email"
+// @Success      204    "No Content"
+// @Failure      400    "Invalid request"
+// @Failure      500    "Server Error"
+// @Router       /users/password-reset [POST]
+func requestPasswordReset(c echo.Context) error {
+	var req struct {
+		Email string `json:"email" form:"email"`
+	}
+	if err := c.Bind(&req); err != nil || req.Email == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "email missing")
+	}
+
+	users, err := stores.Users.ListByEmail(req.Email)
+	if err != nil {
+		log.Warn("RequestPasswordReset/ Error getting users: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	for _, user := range users {
+		if err := sendUserToken(user, models.ResetPasswordPurpose); err != nil {
+			log.Warn("RequestPasswordReset/ Error sending token: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Confirm password reset
+// @Tags         Users
+// @Description  Set a new password with the token sent by email. Every token of the user is revoked.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Param        token     body  string  true  "token"
+// @Param        password  body  string  true  "new password"
+// @Success      204       "No Content"
+// @Failure      400       "Invalid token or password"
+// @Failure      500       "Server Error"
+// @Router       /users/password-reset/confirm [POST]
+func confirmPasswordReset(c echo.Context) error {
+	var req tokenRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "token missing")
+	}
+	if err := models.ValidPassword(req.Password); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	token, err := useUserToken(req.Token, models.ResetPasswordPurpose)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid token")
+		}
+		log.Warn("ConfirmPasswordReset/ Error using token: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	user, err := stores.Users.Get(token.Username)
+	if err != nil {
+		log.Warn("ConfirmPasswordReset/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	if user.PasswordHash, err = hasher.Hash(req.Password); err != nil {
+		log.Warn("ConfirmPasswordReset/ Error hashing password: ", err)
+		return c.JSON(http.StatusInternalServerError, "Error hashing new password.")
+	}
+	user.Salt = ""
+	// The token was received by email, which proves its ownership.
+	user.EmailVerified = true
+
+	if err := stores.Users.Update(user); err != nil {
+		log.Warn("ConfirmPasswordReset/ Error updating user: ", err)
+		return c.JSON(http.StatusInternalServerError, "Error saving data.")
+	}
+
+	if err := stores.UserTokens.UseAll(user.Username, models.ResetPasswordPurpose); err != nil {
+		log.Warn("ConfirmPasswordReset/ Error revoking reset tokens: ", err)
+	}
+	if err := revokeTokens(user.Username); err != nil {
+		log.Warn("ConfirmPasswordReset/ Error revoking tokens: ", err)
+		return c.JSON(http.StatusInternalServerError, "Error revoking tokens.")
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
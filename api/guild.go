@@ -1,37 +1,40 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
 
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
 func initGuilds() {
-	g := apiGroupe.Group("/guilds")
+	g := apiGroupe.Group("/guilds", guildAccess("id"))
 	g.GET("/", getGuilds).Name = "Fetch All Guilds."
-	g.GET("/:id", getGuild).Name = "Fetch Guild by id."
-	g.POST("/", createGuild).Name = "Create new guild."
-	g.PATCH("/:id", updateGuild).Name = "Update guild."
-	g.POST("/:id/reset", resetGuild).Name = "Reset guild."
-	g.DELETE("/:id", hardDeleteGuild).Name = "Hard Delete guild."
+	g.GET("/:id", getGuild, requires(models.GuildsRead)).Name = "Fetch Guild by id."
+	g.POST("/", createGuild, requires(models.GuildsWrite)).Name = "Create new guild."
+	g.PATCH("/:id", updateGuild, requires(models.GuildsWrite)).Name = "Update guild."
+	g.POST("/:id/reset", resetGuild, requires(models.GuildsWrite)).Name = "Reset guild."
+	g.DELETE("/:id", hardDeleteGuild, requires(models.GuildsWrite)).Name = "Hard Delete guild."
 }
 
 // @Summary      Get all Guilds
 // @Tags         Guilds
-// @Description  Fetch all guilds.
+// @Description  Fetch all guilds, or only the guilds the user was granted access to.
 // @Success      200  {array}  models.Guild  "OK"
 // @Failure      403  "Forbidden"
 // @Failure      500  "Server error"
 // @Router       /guilds/ [GET]
 func getGuilds(c echo.Context) error {
-	var guilds []models.Guild
+	if !permissions(c).Has(models.GuildsRead) {
+		return getAccessibleGuilds(c)
+	}
 
-	err := db.DB.Select(&guilds, "SELECT * FROM `guild`")
+	guilds, err := stores.Guilds.All()
 
 	if err != nil {
 		log.Warn("GetGuilds/ Error retrieving guilds: ", err)
@@ -41,6 +44,30 @@ func getGuilds(c echo.Context) error {
 	return c.JSON(http.StatusOK, guilds)
 }
 
+// getAccessibleGuilds returns the guilds the logged in user can read through its guild accesses.
+func getAccessibleGuilds(c echo.Context) error {
+	accesses, err := stores.Access.List(loggedUsername(c))
+	if err != nil {
+		log.Warn("GetGuilds/ Error retrieving accesses: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	guilds := []models.Guild{}
+	for _, access := range accesses {
+		if !guildPermissions(c, access).Has(models.GuildsRead) {
+			continue
+		}
+		guild, err := stores.Guilds.Get(access.GuildID)
+		if err != nil {
+			log.Warn("GetGuilds/ Error retrieving guild: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+		guilds = append(guilds, guild)
+	}
+
+	return c.JSON(http.StatusOK, guilds)
+}
+
 // @Summary      Get one guild
 // @Tags         Guilds
 // @Description  Fetch a specific guild
@@ -58,11 +85,10 @@ func getGuild(c echo.Context) error {
 	// 	members = false
 	// }
 
-	var guild models.Guild
-	err := db.DB.Get(&guild, "SELECT * FROM guild WHERE `guild_id`=?", id)
+	guild, err := stores.Guilds.Get(id)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 
@@ -71,7 +97,7 @@ func getGuild(c echo.Context) error {
 	}
 
 	// if members {
-	// 	err = db.DB.Select(&guild.Members, "SELECT * FROM member WHERE `guild_id`=?", id)
+	// 	guild.Members, err = stores.Members.List(id, "0", math.MaxInt32)
 
 	// 	if err != nil {
 	// 		log.Warn("GetGuild/ Error retrieving members of guild: ", err)
@@ -93,15 +119,18 @@ func getGuild(c echo.Context) error {
 // @Failure      500    "Server error"
 // @Router       /guilds/ [POST]
 func createGuild(c echo.Context) error {
-	var guild models.Guild
+	guild := models.Guild{XpStacking: models.MaxStacking}
 	if err := c.Bind(&guild); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "guild": guild})
 	}
+	if guild.XpStacking != models.MaxStacking && guild.XpStacking != models.MultiplyStacking {
+		return echo.NewHTTPError(http.StatusBadRequest, xpStackingError)
+	}
 
-	_, err := db.DB.NamedExec(models.CreateGuildQuery, guild)
+	err := stores.Guilds.Create(guild)
 
 	if err != nil {
-		if err == nil { // TODO error code for conflict
+		if errors.Is(err, store.ErrConflict) {
 			return c.JSON(http.StatusConflict, echo.Map{"message": "The guild with id " + guild.GuildID + " already exists."})
 		}
 		log.Warn("CreateGuild/ Error inserting values: ", err)
@@ -113,7 +142,8 @@ func createGuild(c echo.Context) error {
 
 // @Summary      Update guild values
 // @Tags         Guilds
-// @Description  Update fields of a guild
+// @Description  Update fields of a guild.
+// @Description  Changing the lvlCurve starts a job recomputing the levels of the members.
 // @Accept       json
 // @Produce      json
 // @Param        guildID  path      string        true  "Guild id"
@@ -125,28 +155,35 @@ func createGuild(c echo.Context) error {
 // @Router       /guilds/{guildID} [PATCH]
 func updateGuild(c echo.Context) error {
 	id := c.Param("id")
-	var guild models.Guild
 
-	if err := db.DB.Get(&guild, "SELECT * FROM guild WHERE `guild_id`=?", id); err != nil {
-		if err == sql.ErrNoRows {
+	guild, err := stores.Guilds.Get(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "Guild with id`" + id + "` not found."})
 		}
 		log.Warn("UpdateGuild/ Error while retrieving guild: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
+	previousCurve := guild.LvlCurve
 	// If some fields were not provided, the previous value are kept.
 	if err := json.NewDecoder(c.Request().Body).Decode(&guild); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if guild.XpStacking != models.MaxStacking && guild.XpStacking != models.MultiplyStacking {
+		return echo.NewHTTPError(http.StatusBadRequest, xpStackingError)
 	}
 	guild.GuildID = id
 
-	_, err := db.DB.NamedExec(models.UpdateGuildQuery, guild)
+	err = stores.Guilds.Update(guild)
 
 	if err != nil {
 		log.Warn("UpdateGuild/ Error while Updating DB: ", err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
+	if !reflect.DeepEqual(previousCurve, guild.LvlCurve) {
+		startRecompute(id)
+	}
 
 	return c.JSON(http.StatusOK, guild)
 }
@@ -163,25 +200,33 @@ func updateGuild(c echo.Context) error {
 // @Failure      500      "Server Error"
 // @Router       /guilds/{guildID}/reset [POST]
 func resetGuild(c echo.Context) error {
-	guildID := c.Param("guildID")
+	guildID := c.Param("id")
 
-	_, err := db.DB.Exec(models.ResetGuildQuery, guildID)
+	previous, err := stores.Guilds.Get(guildID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Error("ResetGuild/ error retrieving guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	err = stores.Guilds.Reset(guildID)
 
 	if err != nil {
-		if err == sql.ErrNoRows { //should not happened
-			return c.JSON(http.StatusNotFound, "Guild with id "+guildID+" not found.")
-		}
 		log.Error("ResetGuild/ Error updating guild: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
-	var guild models.Guild
-	err = db.DB.Get(&guild, "SELECT * FROM guild WHERE `guild_id`=?", guildID)
+	guild, err := stores.Guilds.Get(guildID)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, "Guild with id "+guildID+" not found.")
+		}
 		log.Error("GetGuild/ error retrieving guild: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
+	if previous.LvlCurve.Valid {
+		startRecompute(guildID)
+	}
 
 	return c.JSON(http.StatusOK, guild)
 }
@@ -200,16 +245,15 @@ func resetGuild(c echo.Context) error {
 func hardDeleteGuild(c echo.Context) error {
 	id := c.Param("id")
 
-	res, err := db.DB.Exec("DELETE FROM guild WHERE guild_id = ?", id)
+	err := stores.Guilds.Delete(id)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Error("HardDeleteGuild/ Error while deleting guild from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the guild."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
-	}
-
 	return c.JSON(http.StatusNoContent, nil)
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// jobs are the background tasks of the api, started by StartJobs.
+var jobs = []struct {
+	name     string
+	interval time.Duration
+	run      func()
+}{
+	{"RotateKeys", keyRefreshInterval, func() {
+		if err := rotateKeys(); err != nil {
+			log.Warn("RotateKeys/ ", err)
+		}
+	}},
+	{"LiftExpiredBans", banLiftInterval, liftExpiredBans},
+	{"EvictRecomputeJobs", recomputeEvictInterval, evictRecomputeJobs},
+	{"EvictLoginFailures", loginEvictInterval, evictLoginFailures},
+}
+
+// background holds the context and wait group of the jobs started by StartJobs,
+// which the tasks started by runInBackground share.
+var background = struct {
+	sync.Mutex
+	ctx context.Context
+	wg  *sync.WaitGroup
+}{ctx: context.Background(), wg: &sync.WaitGroup{}}
+
+// StartJobs runs each background job at once, then on its interval until ctx is canceled.
+// The returned wait group is done once every job, and every task started by runInBackground, returned.
+func StartJobs(ctx context.Context) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	background.Lock()
+	background.ctx, background.wg = ctx, wg
+	background.Unlock()
+
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(name string, interval time.Duration, run func()) {
+			defer wg.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			run()
+			for {
+				select {
+				case <-ctx.Done():
+					log.Info("Stopped job ", name)
+					return
+				case <-ticker.C:
+					run()
+				}
+			}
+		}(job.name, job.interval, job.run)
+	}
+	return wg
+}
+
+// runInBackground runs the task in a goroutine of its own, given the context of the jobs
+// so that it returns once the api stops, which waits for it.
+// It reports false without running the task if the api is already stopping.
+func runInBackground(task func(ctx context.Context)) bool {
+	background.Lock()
+	defer background.Unlock()
+
+	ctx, wg := background.ctx, background.wg
+	if ctx.Err() != nil {
+		return false
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		task(ctx)
+	}()
+	return true
+}
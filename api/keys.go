@@ -0,0 +1,321 @@
+package api
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// Delay between two checks of the keys, which also loads the keys created by other instances
+	keyRefreshInterval = time.Minute
+	// Minimum delay between two reloads of the keys caused by an unknown kid
+	keyReloadInterval = 10 * time.Second
+	// Purpose of the cipher sealing the signing keys
+	signingKeyPurpose = "cardinal signing key"
+)
+
+var (
+	// Algorithm of the new keys
+	keyAlgorithm = jwt.SigningMethodRS256.Alg()
+	// Delay after which a new key signs the tokens
+	keyRotation = 30 * 24 * time.Hour
+	// Delay during which a replaced key still verifies tokens
+	keyOverlap = 24 * time.Hour
+
+	keys keyRing
+)
+
+type (
+	// keyRing holds the keys verifying the access tokens, the newest one signing them.
+	keyRing struct {
+		rotating sync.Mutex // serializes the rotations, so that an instance creates a single key
+		mu       sync.RWMutex
+		keys     map[string]*signingKey
+		signing  *signingKey
+		loadedAt time.Time
+	}
+
+	signingKey struct {
+		id      string
+		method  jwt.SigningMethod
+		private crypto.Signer
+	}
+
+	jwk struct {
+		Kty string `json:"kty"`
+		Crv string `json:"crv,omitempty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+)
+
+// initKeys reads the JWT_ALGORITHM, JWT_KEY_ROTATION and JWT_KEY_OVERLAP settings,
+// and loads the signing keys, which the RotateKeys job then rotates.
+func initKeys() {
+	if v := os.Getenv("JWT_ALGORITHM"); v != "" {
+		if v != jwt.SigningMethodRS256.Alg() && v != jwt.SigningMethodEdDSA.Alg() {
+			log.Fatal("Invalid JWT_ALGORITHM, must be RS256 or EdDSA: ", v)
+		}
+		keyAlgorithm = v
+	}
+	for env, d := range map[string]*time.Duration{
+		"JWT_KEY_ROTATION": &keyRotation,
+		"JWT_KEY_OVERLAP":  &keyOverlap,
+	} {
+		if v := os.Getenv(env); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				log.Fatalf("Invalid %s duration: %s", env, v)
+			}
+			*d = parsed
+		}
+	}
+	if keyOverlap < accessTokenTTL {
+		log.Fatal("JWT_KEY_OVERLAP must be longer than ACCESS_TOKEN_TTL, so that replaced keys verify every valid token")
+	}
+
+	if err := rotateKeys(); err != nil {
+		log.Fatal("Could not load signing keys: ", err)
+	}
+}
+
+// rotateKeys creates a new key when the newest one is due for rotation,
+// deletes the keys replaced for longer than the overlap, and loads the remaining ones.
+func rotateKeys() error {
+	keys.rotating.Lock()
+	defer keys.rotating.Unlock()
+
+	stored, err := stores.SigningKeys.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	loaded := map[string]*signingKey{}
+	var newest *signingKey
+	var newestAt time.Time
+	for i, k := range stored {
+		if i+1 < len(stored) && now.Sub(stored[i+1].CreatedAt) > keyOverlap {
+			if err := stores.SigningKeys.Delete(k.KeyID); err != nil && !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+			continue
+		}
+		key, err := openSigningKey(k)
+		if err != nil {
+			// The SECRET changed, the key is replaced.
+			log.Warn("RotateKeys/ Ignoring key ", k.KeyID, ": ", err)
+			continue
+		}
+		loaded[key.id] = key
+		newest, newestAt = key, k.CreatedAt
+	}
+
+	if newest == nil || now.Sub(newestAt) >= keyRotation || newest.method.Alg() != keyAlgorithm {
+		k, key, err := newSigningKey(keyAlgorithm, now)
+		if err != nil {
+			return err
+		}
+		if err := stores.SigningKeys.Create(k); err != nil {
+			return err
+		}
+		log.Info("Created signing key ", k.KeyID)
+		loaded[key.id] = key
+		newest = key
+	}
+
+	keys.mu.Lock()
+	keys.keys, keys.signing, keys.loadedAt = loaded, newest, now
+	keys.mu.Unlock()
+
+	return nil
+}
+
+// newSigningKey generates a key pair for the algorithm.
+func newSigningKey(alg string, now time.Time) (models.SigningKey, *signingKey, error) {
+	var private crypto.Signer
+	var err error
+	if alg == jwt.SigningMethodEdDSA.Alg() {
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return models.SigningKey{}, nil, err
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return models.SigningKey{}, nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return models.SigningKey{}, nil, err
+	}
+	sealed, err := sealKey(signingKeyPurpose, der)
+	if err != nil {
+		return models.SigningKey{}, nil, err
+	}
+
+	k := models.SigningKey{
+		KeyID:      hex.EncodeToString(id),
+		Algorithm:  alg,
+		PrivateKey: sealed,
+		CreatedAt:  now.UTC(),
+	}
+	return k, &signingKey{id: k.KeyID, method: jwt.GetSigningMethod(alg), private: private}, nil
+}
+
+// openSigningKey decrypts the stored key.
+func openSigningKey(k models.SigningKey) (*signingKey, error) {
+	method := jwt.GetSigningMethod(k.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unknown algorithm %s", k.Algorithm)
+	}
+	der, err := openKey(signingKeyPurpose, k.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported key type")
+	}
+	return &signingKey{id: k.KeyID, method: method, private: signer}, nil
+}
+
+// sealKey encrypts the private key with the SECRET, so that a leak of the database
+// does not allow to sign tokens. The purpose derives a cipher of its own for each kind of key.
+func sealKey(purpose string, der []byte) (string, error) {
+	gcm, err := keyCipher(purpose)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, der, nil)), nil
+}
+
+func openKey(purpose string, sealed string) ([]byte, error) {
+	gcm, err := keyCipher(purpose)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < gcm.NonceSize() {
+		return nil, errors.New("sealed key too short")
+	}
+	return gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], nil)
+}
+
+func keyCipher(purpose string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(purpose + ":" + secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// signAccessToken signs the claims with the newest key.
+func signAccessToken(claims jwt.Claims) (string, error) {
+	keys.mu.RLock()
+	key := keys.signing
+	keys.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+// verificationKey returns the public key of the kid of the token.
+// Unknown kids reload the keys, in case another instance created them.
+func verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	keys.mu.RLock()
+	key, ok := keys.keys[kid]
+	reload := !ok && time.Since(keys.loadedAt) > keyReloadInterval
+	keys.mu.RUnlock()
+
+	if reload {
+		if err := rotateKeys(); err != nil {
+			log.Warn("VerificationKey/ Error loading keys: ", err)
+		}
+		keys.mu.RLock()
+		key, ok = keys.keys[kid]
+		keys.mu.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+	}
+	return key.private.Public(), nil
+}
+
+// @Summary      Get JWKS
+// @Tags         Users
+// @Description  Get the public keys verifying the access tokens, as a JSON Web Key Set.
+// @Description  Tokens carry the id of their key in the kid header. Fetch the keys again on an unknown kid.
+// @Produce      json
+// @Success      200  {object}  object  "OK"
+// @Router       /.well-known/jwks.json [GET]
+func getJwks(c echo.Context) error {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+
+	set := []jwk{}
+	for _, key := range keys.keys {
+		k := jwk{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			k.Kty = "RSA"
+			k.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			k.Kty, k.Crv = "OKP", "Ed25519"
+			k.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set = append(set, k)
+	}
+
+	c.Response().Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(keyRefreshInterval.Seconds())))
+	return c.JSON(http.StatusOK, echo.Map{"keys": set})
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 100
+)
+
+type leaderboard struct {
+	Total   int                   `json:"total"`            // Number of members of the guild
+	Offset  int                   `json:"offset,omitempty"` // Members skipped before the page, with offset or rank
+	Members []models.RankedMember `json:"members"`          // Members ranked by xp
+	Next    string                `json:"next,omitempty"`   // Cursor of the next page, when there may be one
+}
+
+func initLeaderboard() {
+	// Not grouped, a group on /guilds/:guildID would catch the routes of the guilds.
+	apiGroupe.GET("/guilds/:guildID/leaderboard", getLeaderboard, guildAccess("guildID"), requires(models.GuildsRead)).Name = "Fetch leaderboard of guild."
+	apiGroupe.GET("/guilds/:guildID/members/:id/rank", getMemberRank, guildAccess("guildID"), requires(models.GuildsRead)).Name = "Fetch rank of GuildMember."
+}
+
+// @Summary      Get leaderboard
+// @Tags         Members
+// @Description  Get the members of the guild ranked by xp, members with the same xp sharing the same rank.
+// @Description  Pages start after offset members, at the position given by rank,
+// @Description  or after the next cursor returned by the previous page, which costs the same whatever the page.
+// @Param        guildID  path      string       true   "guild id"
+// @Param        offset   query     int          false  "members to skip"  default(0)
+// @Param        rank     query     int          false  "first position, instead of offset"
+// @Param        after    query     string       false  "next cursor of the previous page, instead of offset or rank"
+// @Param        limit    query     int          false  "limit to fetch, at most 100"  default(50)
+// @Success      200      {object}  leaderboard  "OK"
+// @Failure      400      "Invalid cursor"
+// @Failure      403      "Forbidden"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/leaderboard [GET]
+func getLeaderboard(c echo.Context) error {
+	guildID := c.Param("guildID")
+
+	var after models.LeaderboardCursor
+	if v := c.QueryParam("after"); v != "" {
+		var err error
+		if after, err = parseLeaderboardCursor(v); err != nil {
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "Invalid cursor"})
+		}
+	}
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 || after.MemberID != "" {
+		offset = 0
+	}
+	if rank, err := strconv.Atoi(c.QueryParam("rank")); err == nil && rank > 0 && after.MemberID == "" {
+		offset = rank - 1
+	}
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultLeaderboardLimit
+	} else if limit > maxLeaderboardLimit {
+		limit = maxLeaderboardLimit
+	}
+
+	page := leaderboard{Offset: offset}
+	page.Total, err = stores.Members.Count(guildID)
+	if err != nil {
+		log.Warn("GetLeaderboard/ Error counting members: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	page.Members, err = stores.Members.Leaderboard(guildID, after, offset, limit)
+	if err != nil {
+		log.Warn("GetLeaderboard/ Error retrieving members: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if n := len(page.Members); n == limit {
+		page.Next = formatLeaderboardCursor(page.Members[n-1].Cursor())
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// formatLeaderboardCursor encodes the cursor as an opaque string for the clients.
+func formatLeaderboardCursor(cursor models.LeaderboardCursor) string {
+	v := fmt.Sprintf("%d:%s", cursor.Xp, cursor.MemberID)
+	return base64.RawURLEncoding.EncodeToString([]byte(v))
+}
+
+// parseLeaderboardCursor decodes a cursor returned by formatLeaderboardCursor.
+func parseLeaderboardCursor(v string) (models.LeaderboardCursor, error) {
+	var cursor models.LeaderboardCursor
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return cursor, err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return cursor, errors.New("malformed cursor")
+	}
+	if cursor.Xp, err = strconv.Atoi(parts[0]); err != nil {
+		return cursor, err
+	}
+	cursor.MemberID = parts[1]
+	return cursor, nil
+}
+
+// @Summary      Get member rank
+// @Tags         Members
+// @Description  Get the member with its rank in the leaderboard of the guild.
+// @Param        guildID   path      string               true  "guild id"
+// @Param        memberID  path      string               true  "member id"
+// @Success      200       {object}  models.RankedMember  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /guilds/{guildID}/members/{memberID}/rank [GET]
+func getMemberRank(c echo.Context) error {
+	guildID := c.Param("guildID")
+	id := c.Param("id")
+
+	member, err := stores.Members.Rank(guildID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Member with id " + id + " not found in guild " + guildID})
+		}
+		log.Warn("GetMemberRank/ Error retrieving rank: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, member)
+}
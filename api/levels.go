@@ -0,0 +1,324 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gyroskan/cardinal/leveling"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// Levels returned by a preview
+	maxPreviewLevels = 100
+	// Members whose level is recomputed at once
+	recomputeBatch = 500
+	// Time a finished recompute job is kept for
+	recomputeJobTTL = 24 * time.Hour
+	// Interval between the evictions of the finished recompute jobs
+	recomputeEvictInterval = time.Hour
+)
+
+// Recompute jobs of each guild, the last one being kept for recomputeJobTTL once finished
+var recomputeJobs = struct {
+	sync.Mutex
+	jobs map[string]*recomputeJob
+}{jobs: map[string]*recomputeJob{}}
+
+type (
+	levelPreview struct {
+		Curve    leveling.Curve   `json:"curve"`    // Previewed curve
+		MaxLevel int              `json:"maxLevel"` // Highest level of the curve
+		Levels   []levelThreshold `json:"levels"`   // Thresholds of the requested levels
+	}
+
+	levelThreshold struct {
+		Level int `json:"level"` // Level
+		Xp    int `json:"xp"`    // Total xp required to reach the level
+		Cost  int `json:"cost"`  // Xp required from the previous level
+	}
+
+	// recomputeJob sets the level of every member of a guild from its xp, following the curve of the guild.
+	recomputeJob struct {
+		GuildID    string     `json:"guildID"`    // Guild ID
+		Running    bool       `json:"running"`    // Whether the job is still running
+		Checked    int        `json:"checked"`    // Members checked
+		Updated    int        `json:"updated"`    // Members whose level changed
+		StartedAt  time.Time  `json:"startedAt"`  // Start of the job
+		FinishedAt *time.Time `json:"finishedAt"` // End of the job
+		Error      string     `json:"error,omitempty"`
+
+		rerun bool // the curve changed while running
+	}
+)
+
+func initLevels() {
+	g := apiGroupe.Group("/guilds/:guildID/levels", guildAccess("guildID"))
+	g.GET("", getLevels, requires(models.GuildsRead)).Name = "Fetch level thresholds of guild."
+	g.POST("/preview", previewLevels, requires(models.GuildsRead)).Name = "Preview level thresholds of a curve."
+	g.GET("/multiplier", getXpMultiplier, requires(models.GuildsRead)).Name = "Fetch effective xp multiplier."
+	g.GET("/recompute", getRecomputeJob, requires(models.GuildsRead)).Name = "Fetch level recompute job of guild."
+	g.POST("/recompute", recomputeGuildLevels, requires(models.MembersXp)).Name = "Recompute levels of guild members."
+}
+
+// @Summary      Get level thresholds
+// @Tags         Levels
+// @Description  Get the xp required to reach each level, following the curve of the guild.
+// @Param        guildID  path      string        true   "guild id"
+// @Param        from     query     int           false  "first level"  default(1)
+// @Param        to       query     int           false  "last level, at most 100 levels are returned"
+// @Success      200      {object}  levelPreview  "OK"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Not Found"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/levels [GET]
+func getLevels(c echo.Context) error {
+	guildID := c.Param("guildID")
+
+	guild, err := stores.Guilds.Get(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Warn("GetLevels/ Error getting guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, preview(c, guild.Curve()))
+}
+
+// @Summary      Preview level thresholds
+// @Tags         Levels
+// @Description  Get the xp required to reach each level following a curve, before setting it as the lvlCurve of the guild.
+// @Accept       json
+// @Produce      json
+// @Param        guildID  path      string          true   "guild id"
+// @Param        curve    body      leveling.Curve  true   "curve"
+// @Param        from     query     int             false  "first level"  default(1)
+// @Param        to       query     int             false  "last level, at most 100 levels are returned"
+// @Success      200      {object}  levelPreview    "OK"
+// @Failure      400      "Invalid curve"
+// @Failure      403      "Forbidden"
+// @Router       /guilds/{guildID}/levels/preview [POST]
+func previewLevels(c echo.Context) error {
+	var curve leveling.Curve
+	if err := c.Bind(&curve); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if err := curve.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, preview(c, curve))
+}
+
+// preview returns the thresholds of the levels between the from and to query parameters.
+func preview(c echo.Context, curve leveling.Curve) levelPreview {
+	from, err := strconv.Atoi(c.QueryParam("from"))
+	if err != nil || from < 1 {
+		from = 1
+	}
+	to, err := strconv.Atoi(c.QueryParam("to"))
+	if err != nil || to < from {
+		to = from + 19
+	}
+	if to >= from+maxPreviewLevels {
+		to = from + maxPreviewLevels - 1
+	}
+	if to > curve.MaxLevel() {
+		to = curve.MaxLevel()
+	}
+
+	p := levelPreview{Curve: curve, MaxLevel: curve.MaxLevel(), Levels: []levelThreshold{}}
+	for level := from; level <= to; level++ {
+		xp := curve.Threshold(level)
+		p.Levels = append(p.Levels, levelThreshold{Level: level, Xp: xp, Cost: xp - curve.Threshold(level-1)})
+	}
+	return p
+}
+
+// @Summary      Get xp multiplier
+// @Tags         Levels
+// @Description  Get the multiplier of the xp gained by a member with the roles in the channel,
+// @Description  following the xpStacking rule of the guild. It is 0 when no xp is granted, with the reason.
+// @Param        guildID    path      string        true   "guild id"
+// @Param        channelID  query     string        false  "channel id"
+// @Param        roleIDs    query     []string      false  "role ids of the member, repeated or separated by commas"
+// @Success      200        {object}  xpMultiplier  "OK"
+// @Failure      403        "Forbidden"
+// @Failure      404        "Not Found"
+// @Failure      500        "Server error"
+// @Router       /guilds/{guildID}/levels/multiplier [GET]
+func getXpMultiplier(c echo.Context) error {
+	guildID := c.Param("guildID")
+
+	guild, err := stores.Guilds.Get(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Warn("GetXpMultiplier/ Error getting guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	multiplier, err := effectiveMultiplier(guild, c.QueryParam("channelID"), roleIDsParam(c))
+	if err != nil {
+		log.Warn("GetXpMultiplier/ Error computing multiplier: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, multiplier)
+}
+
+// @Summary      Get level recompute job
+// @Tags         Levels
+// @Description  Get the progress of the running or last job recomputing the levels of the guild members.
+// @Description  Finished jobs are kept for a day.
+// @Param        guildID  path      string        true  "guild id"
+// @Success      200      {object}  recomputeJob  "OK"
+// @Failure      403      "Forbidden"
+// @Failure      404      "No job"
+// @Router       /guilds/{guildID}/levels/recompute [GET]
+func getRecomputeJob(c echo.Context) error {
+	recomputeJobs.Lock()
+	job, ok := recomputeJobs.jobs[c.Param("guildID")]
+	var status recomputeJob
+	if ok {
+		status = *job
+	}
+	recomputeJobs.Unlock()
+
+	if !ok {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "No recompute job for this guild"})
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// @Summary      Recompute levels
+// @Tags         Levels
+// @Description  Start a job setting the level of every member of the guild from its xp, following the curve of the guild.
+// @Description  A job is started automatically when the lvlCurve of the guild changes.
+// @Param        guildID  path      string        true  "guild id"
+// @Success      202      {object}  recomputeJob  "Started"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Not Found"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/levels/recompute [POST]
+func recomputeGuildLevels(c echo.Context) error {
+	guildID := c.Param("guildID")
+
+	if _, err := stores.Guilds.Get(guildID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Warn("RecomputeGuildLevels/ Error getting guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusAccepted, startRecompute(guildID))
+}
+
+// startRecompute starts a recompute job for the guild, or asks the running one to start again
+// once finished, so that it follows the latest curve.
+func startRecompute(guildID string) recomputeJob {
+	recomputeJobs.Lock()
+	defer recomputeJobs.Unlock()
+
+	if job, ok := recomputeJobs.jobs[guildID]; ok && job.Running {
+		job.rerun = true
+		return *job
+	}
+	job := &recomputeJob{GuildID: guildID, Running: true, StartedAt: time.Now().UTC()}
+	if !runInBackground(job.run) {
+		job.Running, job.FinishedAt, job.Error = false, &job.StartedAt, "The api is stopping"
+		return *job
+	}
+	recomputeJobs.jobs[guildID] = job
+	return *job
+}
+
+// run recomputes the levels until no rerun was asked, or ctx is canceled.
+func (job *recomputeJob) run(ctx context.Context) {
+	for {
+		err := job.recompute(ctx)
+
+		recomputeJobs.Lock()
+		if err != nil {
+			log.Warn("RecomputeLevels/ Error recomputing levels of guild ", job.GuildID, ": ", err)
+			job.Error = err.Error()
+		} else if job.rerun {
+			job.rerun = false
+			job.Checked, job.Updated, job.StartedAt = 0, 0, time.Now().UTC()
+			recomputeJobs.Unlock()
+			continue
+		}
+		now := time.Now().UTC()
+		job.Running, job.FinishedAt = false, &now
+		recomputeJobs.Unlock()
+		return
+	}
+}
+
+// recompute walks through the members of the guild by batches, fixing the levels which do not match their xp.
+func (job *recomputeJob) recompute(ctx context.Context) error {
+	guild, err := stores.Guilds.Get(job.GuildID)
+	if err != nil {
+		return err
+	}
+	curve := guild.Curve()
+
+	after := "0"
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		members, err := stores.Members.List(job.GuildID, after, recomputeBatch)
+		if err != nil {
+			return err
+		}
+
+		updated := 0
+		for _, m := range members {
+			level := curve.Level(m.Xp)
+			if level == m.Level {
+				continue
+			}
+			// Members awarded xp meanwhile already have a level computed from the new curve.
+			err := stores.Members.SetLevel(m.GuildID, m.MemberID, m.Xp, level)
+			if err == nil {
+				updated++
+			} else if !errors.Is(err, store.ErrNotFound) {
+				return err
+			}
+		}
+
+		recomputeJobs.Lock()
+		job.Checked += len(members)
+		job.Updated += updated
+		recomputeJobs.Unlock()
+
+		if len(members) < recomputeBatch {
+			return nil
+		}
+		after = members[len(members)-1].MemberID
+	}
+}
+
+// evictRecomputeJobs forgets the recompute jobs finished for longer than recomputeJobTTL.
+func evictRecomputeJobs() {
+	recomputeJobs.Lock()
+	defer recomputeJobs.Unlock()
+
+	for guildID, job := range recomputeJobs.jobs {
+		if !job.Running && time.Since(*job.FinishedAt) > recomputeJobTTL {
+			delete(recomputeJobs.jobs, guildID)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvictRecomputeJobs(t *testing.T) {
+	old := time.Now().UTC().Add(-recomputeJobTTL - time.Minute)
+	recent := time.Now().UTC()
+	recomputeJobs.jobs = map[string]*recomputeJob{
+		"old":     {GuildID: "old", FinishedAt: &old},
+		"recent":  {GuildID: "recent", FinishedAt: &recent},
+		"running": {GuildID: "running", Running: true, StartedAt: old},
+	}
+	defer func() { recomputeJobs.jobs = map[string]*recomputeJob{} }()
+
+	evictRecomputeJobs()
+	for guildID, want := range map[string]bool{"old": false, "recent": true, "running": true} {
+		if _, kept := recomputeJobs.jobs[guildID]; kept != want {
+			t.Errorf("job of %s kept=%v, want %v", guildID, kept, want)
+		}
+	}
+}
+
+func TestRunInBackground(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	background.ctx, background.wg = ctx, wg
+	defer func() { background.ctx, background.wg = context.Background(), &sync.WaitGroup{} }()
+
+	started := make(chan struct{})
+	stopped := false
+	if !runInBackground(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		stopped = true
+	}) {
+		t.Fatal("task refused before the api stopped")
+	}
+	<-started
+	cancel()
+	wg.Wait()
+	if !stopped {
+		t.Error("jobs returned before the task")
+	}
+	if runInBackground(func(context.Context) {}) {
+		t.Error("task started once the api stopped")
+	}
+}
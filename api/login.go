@@ -0,0 +1,184 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	// Delay before a failed attempt is forgotten
+	loginFailureWindow = time.Hour
+	// Lockout of the first failure exceeding the free attempts, doubled on each new failure
+	loginMinLockout = time.Second
+	loginMaxLockout = 15 * time.Minute
+	// Interval between the evictions of the forgotten failures
+	loginEvictInterval = time.Minute
+)
+
+var (
+	// Failed logins of each username, and of each ip which may try many usernames,
+	// kept in memory so that they are lost on restart and not shared between replicas
+	usernameThrottle = newLoginThrottle(5)
+	ipThrottle       = newLoginThrottle(20)
+)
+
+type (
+	// loginThrottle locks a key out for an exponential delay once it failed more than the free attempts.
+	loginThrottle struct {
+		mu       sync.Mutex
+		free     int
+		failures map[string]loginFailures
+	}
+
+	loginFailures struct {
+		count       int
+		last        time.Time
+		lockedUntil time.Time
+	}
+)
+
+func newLoginThrottle(free int) *loginThrottle {
+	return &loginThrottle{free: free, failures: map[string]loginFailures{}}
+}
+
+// locked returns how long the key is still locked out.
+func (t *loginThrottle) locked(key string, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if wait := t.failures[key].lockedUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// fail records a failed attempt of the key.
+func (t *loginThrottle) fail(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.failures[key]
+	if now.Sub(f.last) > loginFailureWindow {
+		f = loginFailures{}
+	}
+	f.count++
+	f.last = now
+	if exceeded := f.count - t.free; exceeded > 0 {
+		lockout := loginMaxLockout
+		if exceeded <= 20 && loginMinLockout<<(exceeded-1) < loginMaxLockout {
+			lockout = loginMinLockout << (exceeded - 1)
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+	t.failures[key] = f
+}
+
+// reset forgets the failed attempts of the key.
+func (t *loginThrottle) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.failures, key)
+}
+
+// evict forgets the failures older than loginFailureWindow,
+// so that the keys which never succeed do not grow the map.
+func (t *loginThrottle) evict(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for k, f := range t.failures {
+		if now.Sub(f.last) > loginFailureWindow {
+			delete(t.failures, k)
+		}
+	}
+}
+
+// evictLoginFailures evicts the forgotten failures of the usernames and ips.
+func evictLoginFailures() {
+	now := time.Now()
+	usernameThrottle.evict(now)
+	ipThrottle.evict(now)
+}
+
+// loginLocked returns a 429 error if the username or the ip of the request is locked out.
+func loginLocked(c echo.Context, username string, method string) (bool, error) {
+	now := time.Now()
+	wait := usernameThrottle.locked(username, now)
+	if w := ipThrottle.locked(c.RealIP(), now); w > wait {
+		wait = w
+	}
+	if wait == 0 {
+		return false, nil
+	}
+
+	recordLogin(c, username, method, models.LoginLocked)
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return true, echo.NewHTTPError(http.StatusTooManyRequests, "Too many failed attempts, retry later")
+}
+
+// loginFailed records the failed attempt and counts it against the username and the ip of the request.
+func loginFailed(c echo.Context, username string, method string, reason string) {
+	now := time.Now()
+	usernameThrottle.fail(username, now)
+	ipThrottle.fail(c.RealIP(), now)
+	recordLogin(c, username, method, reason)
+}
+
+// recordLogin adds the attempt to the login history, a success when reason is empty.
+func recordLogin(c echo.Context, username string, method string, reason string) {
+	err := stores.Logins.Create(&models.LoginAttempt{
+		Username:  truncate(username, 20),
+		IP:        truncate(c.RealIP(), 45),
+		UserAgent: truncate(c.Request().UserAgent(), 255),
+		Method:    method,
+		Success:   reason == "",
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		log.Warn("RecordLogin/ Error saving attempt: ", err)
+	}
+}
+
+// @Summary      Get login history
+// @Tags         Users
+// @Description  Get the login attempts, most recent first.
+// @Param        username  query    string               false  "username sent"
+// @Param        ip        query    string               false  "ip of the client"
+// @Param        failed    query    bool                 false  "failed attempts only"
+// @Param        before    query    int                  false  "lower last id fetched"
+// @Param        limit     query    int                  false  "limit to fetch"  default(50)
+// @Success      200       {array}  models.LoginAttempt  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      500       "Server error"
+// @Router       /users/logins [GET]
+func getLoginHistory(c echo.Context) error {
+	filter := store.LoginFilter{
+		Username: c.QueryParam("username"),
+		IP:       c.QueryParam("ip"),
+	}
+	filter.Failed, _ = strconv.ParseBool(c.QueryParam("failed"))
+	filter.Before, _ = strconv.Atoi(c.QueryParam("before"))
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	filter.Limit = limit
+
+	attempts, err := stores.Logins.List(filter)
+	if err != nil {
+		log.Warn("GetLoginHistory/ Error getting attempts: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, attempts)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		throttle *loginThrottle
+		failures int           // failures recorded a second apart
+		reset    bool          // whether a success follows them
+		after    time.Duration // delay after the last failure
+		want     time.Duration // lockout left after the delay
+	}{
+		{"username free attempts", usernameThrottle, 5, false, 0, 0},
+		{"username first lockout", usernameThrottle, 6, false, 0, loginMinLockout},
+		{"username doubled lockout", usernameThrottle, 8, false, 0, 4 * loginMinLockout},
+		{"username max lockout", usernameThrottle, 40, false, 0, loginMaxLockout},
+		{"username lockout elapsed", usernameThrottle, 6, false, loginMinLockout, 0},
+		{"username lockout left", usernameThrottle, 7, false, loginMinLockout, loginMinLockout},
+		{"username reset on success", usernameThrottle, 8, true, 0, 0},
+		{"ip free attempts", ipThrottle, 20, false, 0, 0},
+		{"ip first lockout", ipThrottle, 21, false, 0, loginMinLockout},
+		{"ip doubled lockout", ipThrottle, 22, false, 0, 2 * loginMinLockout},
+	}
+	for _, tt := range tests {
+		throttle := newLoginThrottle(tt.throttle.free)
+		at := now
+		for i := 0; i < tt.failures; i++ {
+			at = now.Add(time.Duration(i) * time.Second)
+			throttle.fail("key", at)
+		}
+		if tt.reset {
+			throttle.reset("key")
+		}
+		if got := throttle.locked("key", at.Add(tt.after)); got != tt.want {
+			t.Errorf("%s: locked for %s, want %s", tt.name, got, tt.want)
+		}
+		if got := throttle.locked("other", at); got != 0 {
+			t.Errorf("%s: other key locked for %s", tt.name, got)
+		}
+	}
+}
+
+func TestLoginThrottleWindow(t *testing.T) {
+	throttle := newLoginThrottle(1)
+	now := time.Now()
+	throttle.fail("old", now)
+	throttle.fail("recent", now.Add(loginFailureWindow))
+
+	// A failure after the window starts counting again.
+	throttle.fail("old", now.Add(loginFailureWindow+time.Minute))
+	if got := throttle.locked("old", now.Add(loginFailureWindow+time.Minute)); got != 0 {
+		t.Errorf("failure after the window locked for %s", got)
+	}
+
+	throttle.evict(now.Add(2*loginFailureWindow + 30*time.Second))
+	if _, ok := throttle.failures["recent"]; ok {
+		t.Error("failures older than the window were not evicted")
+	}
+	if f, ok := throttle.failures["old"]; !ok || f.count != 1 {
+		t.Errorf("failures within the window evicted or miscounted: %+v", f)
+	}
+}
@@ -1,26 +1,74 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"os"
 	"strconv"
+	"time"
 
-	"github.com/gyroskan/cardinal/db"
+	"github.com/gyroskan/cardinal/leveling"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
+const (
+	maxXpAward      = 1000000
+	maxXpMultiplier = 100
+)
+
+const (
+	xpMultiplierError = "xpMultiplier must be between 0 and 100"
+	xpStackingError   = "xpStacking must be max or multiply"
+)
+
+// Reasons of the xp awards which were not granted
+const (
+	xpChannelIgnored     = "channelIgnored"
+	xpChannelBlacklisted = "channelBlacklisted"
+	xpRoleBlacklisted    = "roleBlacklisted"
+	xpCooldown           = "cooldown"
+)
+
+type (
+	// xpAward is the xp gained by a member. Awards for a message carry its channel,
+	// so that the channel rules and the cooldown of the guild are applied.
+	xpAward struct {
+		Xp        int      `json:"xp" form:"xp"`               // Xp to add, before the multipliers
+		ChannelID string   `json:"channelID" form:"channelID"` // Channel of the message
+		RoleIDs   []string `json:"roleIDs" form:"roleIDs"`     // Roles of the member
+	}
+
+	// xpMultiplier is the multiplier of the xp gained by a member in a channel.
+	xpMultiplier struct {
+		Multiplier float64 `json:"multiplier"`       // Effective multiplier, 0 when no xp is granted
+		Stacking   string  `json:"stacking"`         // Stacking rule of the guild
+		Reason     string  `json:"reason,omitempty"` // Why no xp is granted
+	}
+)
+
 func initMembers() {
-	g := apiGroupe.Group("/guilds/:guildID/members")
-	g.GET("/", GetGuildMembers).Name = "Fetch GuildMembers."
-	g.GET("/:id", GetMember).Name = "Fetch Member."
-	g.POST("/", createMember).Name = "Create GuildMember."
-	g.POST("/reset", resetGuildMembers).Name = "Reset Data of GuildMembers."
-	g.POST("/:id/reset", resetMember).Name = "Reset Data of GuildMember."
-	g.PATCH("/:id", updateMember).Name = "Update GuildMember."
-	g.DELETE("/:id", hardDeleteMember).Name = "Delete GuildMember."
+	// Curve of the guilds which did not choose one
+	if v := os.Getenv("LEVEL_CURVE"); v != "" {
+		var err error
+		if leveling.Default, err = leveling.Parse(v); err != nil {
+			log.Fatal("Invalid LEVEL_CURVE: ", err)
+		}
+	}
+
+	g := apiGroupe.Group("/guilds/:guildID/members", guildAccess("guildID"))
+	g.GET("/", GetGuildMembers, requires(models.GuildsRead)).Name = "Fetch GuildMembers."
+	g.GET("/:id", GetMember, requires(models.GuildsRead)).Name = "Fetch Member."
+	g.POST("/", createMember, requires(models.GuildsWrite)).Name = "Create GuildMember."
+	g.POST("/reset", resetGuildMembers, requires(models.MembersXp)).Name = "Reset Data of GuildMembers."
+	g.POST("/:id/reset", resetMember, requires(models.MembersXp)).Name = "Reset Data of GuildMember."
+	g.PATCH("/:id", updateMember, requires(models.MembersXp)).Name = "Update GuildMember."
+	g.POST("/:id/xp", awardXp, requires(models.MembersXp)).Name = "Award Xp to GuildMember."
+	g.DELETE("/:id", hardDeleteMember, requires(models.GuildsWrite)).Name = "Delete GuildMember."
 }
 
 // @Summary      Get Guild Members
@@ -45,9 +93,7 @@ func GetGuildMembers(c echo.Context) error {
 		limit = 1
 	}
 
-	members := []models.Member{}
-
-	err = db.DB.Select(&members, models.SelectGuildMembersQuery, guildID, lastID, limit)
+	members, err := stores.Members.List(guildID, lastID, limit)
 
 	if err != nil {
 		log.Warn("GetGuildMembers/ Error retrieving members from guildID: ", err)
@@ -71,12 +117,10 @@ func GetMember(c echo.Context) error {
 	guildID := c.Param("guildID")
 	id := c.Param("id")
 
-	var member models.Member
-
-	err := db.DB.Get(&member, "SELECT * FROM `member` WHERE member_id=? AND guild_id=?", id, guildID)
+	member, err := stores.Members.Get(guildID, id)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"message": "Member with id " + id + " not found in guild " + guildID})
 		}
 		log.Warn("GetMember/ Error retrieving members from guildID: ", err)
@@ -96,6 +140,7 @@ func GetMember(c echo.Context) error {
 // @Success      201      {object}  models.Member  "Created member"
 // @Failure      400      "Wrong values"
 // @Failure      403      "Forbidden"
+// @Failure      409      "Conflict"
 // @Failure      500      "Server Error"
 // @Router       /guilds/{guildID}/members [POST]
 func createMember(c echo.Context) error {
@@ -106,9 +151,11 @@ func createMember(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "member": member})
 	}
 
-	_, err := db.DB.NamedExec(models.CreateMemberQuery, member)
+	err := stores.Members.Create(member)
 	if err != nil {
-		// TODO switch case of sql errors.
+		if errors.Is(err, store.ErrConflict) {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "The member with id " + member.MemberID + " already exists."})
+		}
 		log.Warn("createMember/ Error creating member: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
@@ -129,10 +176,10 @@ func createMember(c echo.Context) error {
 func resetGuildMembers(c echo.Context) error {
 	guildID := c.Param("guildID")
 
-	_, err := db.DB.Exec(models.ResetGuildMembersQuery, guildID)
+	err := stores.Members.ResetGuild(guildID)
 	if err != nil {
 		log.Warn("ResetGuildMembers/ Error updating members: ", err)
-		c.JSON(http.StatusInternalServerError, nil)
+		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
 	return c.JSON(http.StatusOK, nil)
@@ -154,19 +201,18 @@ func resetMember(c echo.Context) error {
 	guildID := c.Param("guildID")
 	id := c.Param("id")
 
-	_, err := db.DB.Exec(models.ResetGuildMembersQuery, guildID)
+	err := stores.Members.Reset(guildID, id)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.JSON(http.StatusNotFound, "Member with id "+id+" not found in guild "+guildID)
-		}
 		log.Warn("ResetMember/ Error updating member: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
-	var memb models.Member
-	err = db.DB.Get(&memb, "SELECT * FROM `member` WHERE member_id=? AND guild_id=?", id, guildID)
+	memb, err := stores.Members.Get(guildID, id)
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, "Member with id "+id+" not found in guild "+guildID)
+		}
 		log.Warn("ResetMember/ Error getting member: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
@@ -188,12 +234,12 @@ func resetMember(c echo.Context) error {
 // @Failure      500       "Server Error"
 // @Router       /guilds/{guildID}/members/{memberID} [PATCH]
 func updateMember(c echo.Context) error {
-	var member models.Member
 	guildID := c.Param("guildID")
 	id := c.Param("id")
 
-	if err := db.DB.Get(&member, "SELECT * FROM `member` WHERE member_id=? AND guild_id=?", id, guildID); err != nil {
-		if err == sql.ErrNoRows {
+	member, err := stores.Members.Get(guildID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"message": "Member with id " + id + " not found in guild " + guildID})
 		}
 		log.Warn("UpdateMember/ Error getting member: ", err)
@@ -206,7 +252,7 @@ func updateMember(c echo.Context) error {
 	member.GuildID = guildID
 	member.MemberID = id
 
-	_, err := db.DB.NamedExec(models.UpdateMemberQuery, member)
+	err = stores.Members.Update(member)
 	if err != nil {
 		log.Warn("UpdateMember/ Error updating member: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
@@ -231,16 +277,151 @@ func hardDeleteMember(c echo.Context) error {
 	guildID := c.Param("guildID")
 	id := c.Param("id")
 
-	res, err := db.DB.Exec("DELETE FROM member WHERE guild_id = ? AND member_id = ?", guildID, id)
+	err := stores.Members.Delete(guildID, id)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Warn("HardDeleteMember/ Error while deleting member from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the member."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Award xp
+// @Tags         Members
+// @Description  Atomically add xp to the member, created if missing, and compute its level.
+// @Description  announce tells whether a level multiple of the guild lvlResponse was reached.
+// @Description  No xp is granted in ignored or xp blacklisted channels, to members with an xp blacklisted role,
+// @Description  nor for a message sent during the xpCooldown of the guild. granted is then false, with the reason.
+// @Description  Otherwise the xp is multiplied by the xp multipliers of the channel and of the roles.
+// @Accept       json
+// @Produce      json
+// @Param        guildID   path      string   true  "guild id"
+// @Param        memberID  path      string   true  "member id"
+// @Param        award     body      xpAward  true  "xp to add"
+// @Success      200       {object}  object   "granted, reason, xp added, multiplier, updated member, previous level, levelUp and announce"
+// @Failure      400       "Invalid xp"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Guild not found"
+// @Failure      500       "Server error"
+// @Router       /guilds/{guildID}/members/{memberID}/xp [POST]
+func awardXp(c echo.Context) error {
+	guildID := c.Param("guildID")
+	id := c.Param("id")
+
+	var req xpAward
+	if err := c.Bind(&req); err != nil || req.Xp <= 0 || req.Xp > maxXpAward {
+		return echo.NewHTTPError(http.StatusBadRequest, "xp must be between 1 and "+strconv.Itoa(maxXpAward))
 	}
 
-	return c.JSON(http.StatusNoContent, nil)
+	guild, err := stores.Guilds.Get(guildID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Warn("AwardXp/ Error getting guild: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	multiplier, err := effectiveMultiplier(guild, req.ChannelID, req.RoleIDs)
+	if err != nil {
+		log.Warn("AwardXp/ Error computing multiplier: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if multiplier.Reason != "" {
+		return c.JSON(http.StatusOK, echo.Map{"granted": false, "reason": multiplier.Reason})
+	}
+	xp := int(math.Round(float64(req.Xp) * multiplier.Multiplier))
+	if xp < 1 {
+		xp = 1
+	} else if xp > maxXpAward {
+		xp = maxXpAward
+	}
+
+	var cooldown time.Duration
+	if req.ChannelID != "" {
+		cooldown = time.Duration(guild.XpCooldown) * time.Second
+	}
+	member, previous, err := stores.Members.AddXp(guildID, id, xp, time.Now().UTC(), cooldown, guild.Curve().Level)
+	if err != nil {
+		if errors.Is(err, store.ErrCooldown) {
+			return c.JSON(http.StatusOK, echo.Map{"granted": false, "reason": xpCooldown})
+		}
+		log.Warn("AwardXp/ Error adding xp: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	levelUp := member.Level > previous
+	return c.JSON(http.StatusOK, echo.Map{
+		"granted":       true,
+		"xp":            xp,
+		"multiplier":    multiplier.Multiplier,
+		"member":        member,
+		"previousLevel": previous,
+		"levelUp":       levelUp,
+		"announce":      levelUp && guild.LvlResponse > 0 && member.Level/guild.LvlResponse > previous/guild.LvlResponse,
+	})
+}
+
+func validXpMultiplier(m float64) bool {
+	return m >= 0 && m <= maxXpMultiplier
+}
+
+// effectiveMultiplier combines the xp multipliers of the channel and of the roles of a member,
+// following the stacking rule of the guild. Channels and roles unknown to the guild do not change the xp,
+// while ignored and blacklisted ones prevent any xp.
+func effectiveMultiplier(guild models.Guild, channelID string, roleIDs []string) (xpMultiplier, error) {
+	m := xpMultiplier{Stacking: guild.XpStacking}
+	multipliers := []float64{}
+
+	if channelID != "" {
+		channel, err := stores.Channels.Get(guild.GuildID, channelID)
+		if err == nil {
+			if channel.Ignored {
+				m.Reason = xpChannelIgnored
+				return m, nil
+			}
+			if channel.XpBlacklisted || channel.XpMultiplier == 0 {
+				m.Reason = xpChannelBlacklisted
+				return m, nil
+			}
+			multipliers = append(multipliers, channel.XpMultiplier)
+		} else if !errors.Is(err, store.ErrNotFound) {
+			return m, err
+		}
+	}
+
+	if len(roleIDs) > 0 {
+		roles, err := stores.Roles.List(guild.GuildID, store.RoleFilter{})
+		if err != nil {
+			return m, err
+		}
+		member := map[string]bool{}
+		for _, id := range roleIDs {
+			member[id] = true
+		}
+		for _, role := range roles {
+			if !member[role.RoleID] {
+				continue
+			}
+			if role.XpBlacklisted || role.XpMultiplier == 0 {
+				m.Reason = xpRoleBlacklisted
+				return m, nil
+			}
+			multipliers = append(multipliers, role.XpMultiplier)
+		}
+	}
+
+	m.Multiplier = 1
+	for i, x := range multipliers {
+		if guild.XpStacking == models.MultiplyStacking {
+			m.Multiplier *= x
+		} else if i == 0 || x > m.Multiplier {
+			m.Multiplier = x
+		}
+	}
+	return m, nil
 }
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	defaultRewardsLimit = 100
+	maxRewardsLimit     = 1000
+)
+
+// rewardRoles are the reward roles a member should have at its level.
+type rewardRoles struct {
+	MemberID string   `json:"memberID,omitempty"` // Member ID
+	Level    int      `json:"level"`              // Level of the member
+	Roles    []string `json:"roles"`              // Reward roles the member should have
+	Add      []string `json:"add"`                // Reward roles to give, the member not having them yet
+	Remove   []string `json:"remove"`             // Reward roles the member should not have
+}
+
+func initRewards() {
+	g := apiGroupe.Group("/guilds/:guildID/rewards", guildAccess("guildID"))
+	g.GET("", getLevelRewards, requires(models.GuildsRead)).Name = "Fetch reward roles of a level."
+	g.GET("/members", getMembersRewards, requires(models.GuildsRead)).Name = "Fetch reward roles of GuildMembers."
+	apiGroupe.GET("/guilds/:guildID/members/:id/rewards", getMemberRewards, guildAccess("guildID"), requires(models.GuildsRead)).Name = "Fetch reward roles of GuildMember."
+}
+
+// @Summary      Get reward roles of a level
+// @Tags         Roles
+// @Description  Get the reward roles a member should have at the level, and the ones to remove,
+// @Description  the highest rewards replacing the previous ones when the guild lvlReplace is set.
+// @Description  Given the current roles of the member, add and remove only list the changes.
+// @Param        guildID  path      string       true   "guild id"
+// @Param        level    query     int          true   "level"
+// @Param        roleIDs  query     []string     false  "current role ids of the member, repeated or separated by commas"
+// @Success      200      {object}  rewardRoles  "OK"
+// @Failure      400      "Invalid level"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Not Found"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/rewards [GET]
+func getLevelRewards(c echo.Context) error {
+	level, err := strconv.Atoi(c.QueryParam("level"))
+	if err != nil || level < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "level must be a positive integer")
+	}
+
+	guild, rewards, err := guildRewards(c.Param("guildID"))
+	if err != nil {
+		return rewardsError(c, "GetLevelRewards", err)
+	}
+
+	return c.JSON(http.StatusOK, resolveRewards(rewards, guild.LvlReplace, level, roleIDsParam(c)))
+}
+
+// @Summary      Get reward roles of a member
+// @Tags         Roles
+// @Description  Get the reward roles the member should have at its level, and the ones to remove,
+// @Description  the highest rewards replacing the previous ones when the guild lvlReplace is set.
+// @Description  Given the current roles of the member, add and remove only list the changes.
+// @Param        guildID   path      string       true   "guild id"
+// @Param        memberID  path      string       true   "member id"
+// @Param        roleIDs   query     []string     false  "current role ids of the member, repeated or separated by commas"
+// @Success      200       {object}  rewardRoles  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /guilds/{guildID}/members/{memberID}/rewards [GET]
+func getMemberRewards(c echo.Context) error {
+	guildID := c.Param("guildID")
+	id := c.Param("id")
+
+	member, err := stores.Members.Get(guildID, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Member with id " + id + " not found in guild " + guildID})
+		}
+		log.Warn("GetMemberRewards/ Error getting member: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	guild, rewards, err := guildRewards(guildID)
+	if err != nil {
+		return rewardsError(c, "GetMemberRewards", err)
+	}
+
+	resolved := resolveRewards(rewards, guild.LvlReplace, member.Level, roleIDsParam(c))
+	resolved.MemberID = member.MemberID
+	return c.JSON(http.StatusOK, resolved)
+}
+
+// @Summary      Get reward roles of the members
+// @Tags         Roles
+// @Description  Get the reward roles each member of the guild should have at its level, and the ones to remove,
+// @Description  to apply to every member after the reward roles or the lvlReplace of the guild changed.
+// @Param        guildID  path     string       true   "guild id"
+// @Param        after    query    string       false  "higher last member id fetched"  default(0)
+// @Param        limit    query    int          false  "limit to fetch, at most 1000"  default(100)
+// @Success      200      {array}  rewardRoles  "OK"
+// @Failure      403      "Forbidden"
+// @Failure      404      "Not Found"
+// @Failure      500      "Server error"
+// @Router       /guilds/{guildID}/rewards/members [GET]
+func getMembersRewards(c echo.Context) error {
+	guildID := c.Param("guildID")
+	after := c.QueryParam("after")
+	if after == "" {
+		after = "0"
+	}
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultRewardsLimit
+	} else if limit > maxRewardsLimit {
+		limit = maxRewardsLimit
+	}
+
+	guild, rewards, err := guildRewards(guildID)
+	if err != nil {
+		return rewardsError(c, "GetMembersRewards", err)
+	}
+
+	members, err := stores.Members.List(guildID, after, limit)
+	if err != nil {
+		log.Warn("GetMembersRewards/ Error getting members: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	// Members of the same level get the same roles.
+	byLevel := map[int]rewardRoles{}
+	resolved := make([]rewardRoles, 0, len(members))
+	for _, m := range members {
+		r, ok := byLevel[m.Level]
+		if !ok {
+			r = resolveRewards(rewards, guild.LvlReplace, m.Level, nil)
+			byLevel[m.Level] = r
+		}
+		r.MemberID = m.MemberID
+		resolved = append(resolved, r)
+	}
+
+	return c.JSON(http.StatusOK, resolved)
+}
+
+// guildRewards returns the guild and its reward roles, ordered by level.
+func guildRewards(guildID string) (models.Guild, []models.Role, error) {
+	guild, err := stores.Guilds.Get(guildID)
+	if err != nil {
+		return guild, nil, err
+	}
+
+	roles, err := stores.Roles.List(guildID, store.RoleFilter{})
+	if err != nil {
+		return guild, nil, err
+	}
+	rewards := []models.Role{}
+	for _, r := range roles {
+		if r.Reward > 0 {
+			rewards = append(rewards, r)
+		}
+	}
+	sort.Slice(rewards, func(i, j int) bool {
+		if rewards[i].Reward != rewards[j].Reward {
+			return rewards[i].Reward < rewards[j].Reward
+		}
+		return rewards[i].RoleID < rewards[j].RoleID
+	})
+
+	return guild, rewards, nil
+}
+
+func rewardsError(c echo.Context, handler string, err error) error {
+	if errors.Is(err, store.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + c.Param("guildID") + " not found"})
+	}
+	log.Warn(handler+"/ Error getting reward roles: ", err)
+	return c.JSON(http.StatusInternalServerError, nil)
+}
+
+// resolveRewards returns the rewards, ordered by level, reached at the level.
+// With replace, only the rewards of the highest level reached are kept.
+// current are the roles of the member, nil when unknown.
+func resolveRewards(rewards []models.Role, replace bool, level int, current []string) rewardRoles {
+	reached := 0
+	for reached < len(rewards) && rewards[reached].Reward <= level {
+		reached++
+	}
+	first := 0
+	if replace && reached > 0 {
+		first = reached - 1
+		for first > 0 && rewards[first-1].Reward == rewards[reached-1].Reward {
+			first--
+		}
+	}
+
+	has := map[string]bool{}
+	for _, id := range current {
+		has[id] = true
+	}
+
+	r := rewardRoles{Level: level, Roles: []string{}, Add: []string{}, Remove: []string{}}
+	for i, role := range rewards {
+		if i >= first && i < reached {
+			r.Roles = append(r.Roles, role.RoleID)
+			if current == nil || !has[role.RoleID] {
+				r.Add = append(r.Add, role.RoleID)
+			}
+		} else if current == nil || has[role.RoleID] {
+			r.Remove = append(r.Remove, role.RoleID)
+		}
+	}
+	return r
+}
+
+// roleIDsParam returns the role ids of the roleIDs query parameter, repeated or separated by commas,
+// or nil when it is missing.
+func roleIDsParam(c echo.Context) []string {
+	values, ok := c.QueryParams()["roleIDs"]
+	if !ok {
+		return nil
+	}
+	roleIDs := []string{}
+	for _, v := range values {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				roleIDs = append(roleIDs, id)
+			}
+		}
+	}
+	return roleIDs
+}
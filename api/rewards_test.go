@@ -0,0 +1,56 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+func TestResolveRewards(t *testing.T) {
+	// Rewards ordered by level, r5a and r5b sharing level 5.
+	rewards := []models.Role{
+		{RoleID: "r1", Reward: 1},
+		{RoleID: "r5a", Reward: 5},
+		{RoleID: "r5b", Reward: 5},
+		{RoleID: "r10", Reward: 10},
+	}
+	none := []string{}
+
+	tests := []struct {
+		name    string
+		replace bool
+		level   int
+		current []string
+		roles   []string
+		add     []string
+		remove  []string
+	}{
+		{"level 0", false, 0, nil, none, none, []string{"r1", "r5a", "r5b", "r10"}},
+		{"level 0 replace", true, 0, nil, none, none, []string{"r1", "r5a", "r5b", "r10"}},
+		{"level 0 with roles", false, 0, []string{"r1", "other"}, none, none, []string{"r1"}},
+		{"stacked", false, 7, nil, []string{"r1", "r5a", "r5b"}, []string{"r1", "r5a", "r5b"}, []string{"r10"}},
+		{"stacked at exact level", false, 10, nil,
+			[]string{"r1", "r5a", "r5b", "r10"}, []string{"r1", "r5a", "r5b", "r10"}, none},
+		{"replace keeps ties", true, 7, nil, []string{"r5a", "r5b"}, []string{"r5a", "r5b"}, []string{"r1", "r10"}},
+		{"replace at exact level", true, 5, nil, []string{"r5a", "r5b"}, []string{"r5a", "r5b"}, []string{"r1", "r10"}},
+		{"replace highest", true, 12, nil, []string{"r10"}, []string{"r10"}, []string{"r1", "r5a", "r5b"}},
+		{"replace below ties", true, 4, nil, []string{"r1"}, []string{"r1"}, []string{"r5a", "r5b", "r10"}},
+		{"current adds missing only", false, 7, []string{"r1", "r5a", "other"},
+			[]string{"r1", "r5a", "r5b"}, []string{"r5b"}, none},
+		{"current removes held only", true, 12, []string{"r1", "r5b", "r10"},
+			[]string{"r10"}, none, []string{"r1", "r5b"}},
+		{"current empty", true, 7, []string{}, []string{"r5a", "r5b"}, []string{"r5a", "r5b"}, none},
+	}
+	for _, tt := range tests {
+		got := resolveRewards(rewards, tt.replace, tt.level, tt.current)
+		want := rewardRoles{Level: tt.level, Roles: tt.roles, Add: tt.add, Remove: tt.remove}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: resolveRewards=%+v, want %+v", tt.name, got, want)
+		}
+	}
+
+	if got := resolveRewards(nil, true, 10, nil); len(got.Roles) != 0 || len(got.Add) != 0 || len(got.Remove) != 0 {
+		t.Errorf("resolveRewards without rewards=%+v", got)
+	}
+}
@@ -1,25 +1,24 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
 func initRoles() {
-	r := apiGroupe.Group("/guilds/:guildID/roles")
-	r.GET("/", getRoles).Name = "Fetch all guild roles."
-	r.GET("/:id", getRole).Name = "Fetch a guild role."
-	r.POST("/", createRole).Name = "Create a guild role."
-	r.PATCH("/:id", updateRole).Name = "Update a guild role."
-	r.DELETE("/:id", deleteRole).Name = "Delete a guild role."
+	r := apiGroupe.Group("/guilds/:guildID/roles", guildAccess("guildID"))
+	r.GET("/", getRoles, requires(models.GuildsRead)).Name = "Fetch all guild roles."
+	r.GET("/:id", getRole, requires(models.GuildsRead)).Name = "Fetch a guild role."
+	r.POST("/", createRole, requires(models.GuildsWrite)).Name = "Create a guild role."
+	r.PATCH("/:id", updateRole, requires(models.GuildsWrite)).Name = "Update a guild role."
+	r.DELETE("/:id", deleteRole, requires(models.GuildsWrite)).Name = "Delete a guild role."
 }
 
 // @Summary      Get Guild roles
@@ -47,20 +46,12 @@ func getRoles(c echo.Context) error {
 	if c.QueryParam("reward") != "" {
 		reward, _ = strconv.Atoi(c.QueryParam("reward"))
 	}
-	var roles []models.Role
 
-	query := "SELECT * FROM role WHERE guild_id=?"
-	if ignored {
-		query += " AND ignored=true"
-	}
-	if xpBlacklisted {
-		query += " AND xp_blacklisted=true"
-	}
-	if reward != 0 {
-		query += fmt.Sprintf(" AND reward=%d", reward)
-	}
-
-	err := db.DB.Select(&roles, query, guildID)
+	roles, err := stores.Roles.List(guildID, store.RoleFilter{
+		Ignored:       ignored,
+		XpBlacklisted: xpBlacklisted,
+		Reward:        reward,
+	})
 
 	if err != nil {
 		log.Warn("GetRoles/ Error retrieving roles: ", err)
@@ -83,12 +74,11 @@ func getRoles(c echo.Context) error {
 func getRole(c echo.Context) error {
 	guildID := c.Param("guildID")
 	roleID := c.Param("id")
-	var role models.Role
 
-	err := db.DB.Get(&role, "SELECT * FROM role WHERE guild_id=? AND role_id=?", guildID, roleID)
+	role, err := stores.Roles.Get(guildID, roleID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Warn("GetRoles/ Error retrieving roles: ", err)
@@ -108,20 +98,27 @@ func getRole(c echo.Context) error {
 // @Success      201      {object}  models.Role  "Created role"
 // @Failure      400      "Wrong values"
 // @Failure      403      "Forbidden"
+// @Failure      409      "Conflict"
 // @Failure      500      "Server Error"
 // @Router       /guilds/{guildID}/roles [POST]
 func createRole(c echo.Context) error {
-	var role models.Role
+	role := models.Role{XpMultiplier: 1}
 	guildID := c.Param("guildID")
 
 	if err := c.Bind(&role); err != nil || role.GuildID != guildID {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "role": role})
 	}
+	if !validXpMultiplier(role.XpMultiplier) {
+		return echo.NewHTTPError(http.StatusBadRequest, xpMultiplierError)
+	}
 
-	_, err := db.DB.NamedExec(models.CreateRoleQuery, role)
+	err := stores.Roles.Create(role)
 
 	if err != nil {
-		// TODO switch case of sql errors.
+		if errors.Is(err, store.ErrConflict) {
+			return c.JSON(http.StatusConflict, echo.Map{"message": "The role with id " + role.RoleID + " already exists."})
+		}
+		log.Warn("CreateRole/ Error inserting role: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
@@ -144,12 +141,11 @@ func createRole(c echo.Context) error {
 func updateRole(c echo.Context) error {
 	guildID := c.Param("guildID")
 	roleID := c.Param("id")
-	var role models.Role
 
-	err := db.DB.Get(&role, "SELECT * FROM role WHERE guild_id=? AND role_id=?", guildID, roleID)
+	role, err := stores.Roles.Get(guildID, roleID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Warn("UpdateRole/ Error retrieving roles: ", err)
@@ -159,11 +155,14 @@ func updateRole(c echo.Context) error {
 	if err := json.NewDecoder(c.Request().Body).Decode(&role); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
+	if !validXpMultiplier(role.XpMultiplier) {
+		return echo.NewHTTPError(http.StatusBadRequest, xpMultiplierError)
+	}
 
 	role.GuildID = guildID
 	role.RoleID = roleID
 
-	_, err = db.DB.NamedExec(models.UpdateRoleQuery, role)
+	err = stores.Roles.Update(role)
 	if err != nil {
 		log.Error("UpdateRole/ Error updating role: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
@@ -188,16 +187,15 @@ func deleteRole(c echo.Context) error {
 	guildID := c.Param("guildID")
 	roleID := c.Param("id")
 
-	res, err := db.DB.Exec("DELETE FROM role WHERE guild_id = ? AND role_id = ?", guildID, roleID)
+	err := stores.Roles.Delete(guildID, roleID)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Error("HardDeleteRole/ Error while deleting role from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the role."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
-	}
-
 	return c.JSON(http.StatusNoContent, nil)
 }
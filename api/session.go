@@ -0,0 +1,125 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+func initSessions() {
+	users := apiGroupe.Group("/users")
+	users.GET("/me/sessions", getSessions, notApiKey)
+	users.DELETE("/me/sessions", revokeSessions, notApiKey)
+	users.DELETE("/me/sessions/:sessionID", revokeSession, notApiKey)
+	users.GET("/:username/sessions", getSessions, isAdminOrLoggedIn, notApiKey)
+	users.DELETE("/:username/sessions", revokeSessions, isAdminOrLoggedIn, notApiKey)
+	users.DELETE("/:username/sessions/:sessionID", revokeSession, isAdminOrLoggedIn, notApiKey)
+}
+
+// sessionsOwner returns the user of the username parameter, or the logged in user on the /users/me routes.
+func sessionsOwner(c echo.Context) string {
+	if username := c.Param("username"); username != "" {
+		return username
+	}
+	return loggedUsername(c)
+}
+
+// @Summary      Get sessions
+// @Tags         Users
+// @Description  Get the active sessions of the user, most recently used first.
+// @Description  Each session is a chain of refresh tokens, each one replaced by the next when used,
+// @Description  and comes with its unused refresh token. Revoking the session revokes the chain.
+// @Description  Use /users/me/sessions for the logged in user.
+// @Param        username  path      string          true  "username"
+// @Success      200       {array}   models.Session  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/sessions [GET]
+func getSessions(c echo.Context) error {
+	sessions, err := stores.Sessions.List(sessionsOwner(c), time.Now().UTC())
+	if err != nil {
+		log.Warn("GetSessions/ Error getting sessions: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	tokens, err := stores.Tokens.ListActive(sessionsOwner(c), time.Now().UTC())
+	if err != nil {
+		log.Warn("GetSessions/ Error getting refresh tokens: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	chains := map[int]*models.RefreshToken{}
+	for i := range tokens {
+		if id := int(tokens[i].SessionID.Int64Value()); tokens[i].SessionID.Valid() && chains[id] == nil {
+			chains[id] = &tokens[i]
+		}
+	}
+
+	current := loggedSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == current
+		sessions[i].RefreshToken = chains[sessions[i].SessionID]
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// @Summary      Revoke session
+// @Tags         Users
+// @Description  Revoke the session and its tokens.
+// @Param        username   path  string  true  "username"
+// @Param        sessionID  path  int     true  "session id"
+// @Success      204        "No Content"
+// @Failure      403        "Forbidden"
+// @Failure      404        "Not Found"
+// @Failure      500        "Server error"
+// @Router       /users/{username}/sessions/{sessionID} [DELETE]
+func revokeSession(c echo.Context) error {
+	sessionID, err := strconv.Atoi(c.Param("sessionID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, echo.Map{"error": "session " + c.Param("sessionID") + " not found."})
+	}
+
+	if err := stores.Sessions.Revoke(sessionsOwner(c), sessionID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "session " + c.Param("sessionID") + " not found."})
+		}
+		log.Warn("RevokeSession/ Error revoking session: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
+
+// @Summary      Revoke sessions
+// @Tags         Users
+// @Description  Revoke every session of the user, except the session of the request.
+// @Param        username  path  string  true  "username"
+// @Success      204       "No Content"
+// @Failure      403       "Forbidden"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/sessions [DELETE]
+func revokeSessions(c echo.Context) error {
+	username := sessionsOwner(c)
+	current := 0
+	if username == loggedUsername(c) {
+		current = loggedSessionID(c)
+	}
+
+	if err := stores.Sessions.RevokeAll(username, current); err != nil {
+		log.Warn("RevokeSessions/ Error revoking sessions: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	// Refresh tokens issued before sessions are revoked too.
+	if err := stores.Tokens.RevokeOthers(username, current); err != nil {
+		log.Warn("RevokeSessions/ Error revoking tokens: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
+)
+
+var (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+	// Minimum delay between two updates of the last use of a session
+	sessionTouchInterval = time.Minute
+
+	errTokenRevoked = errors.New("token revoked")
+)
+
+// initTokens reads the lifetime of the tokens from the ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL settings.
+func initTokens() {
+	for env, ttl := range map[string]*time.Duration{
+		"ACCESS_TOKEN_TTL":  &accessTokenTTL,
+		"REFRESH_TOKEN_TTL": &refreshTokenTTL,
+	} {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				log.Fatalf("Invalid %s duration: %s", env, v)
+			}
+			*ttl = d
+		}
+	}
+}
+
+// issueTokens returns a new access token and a new refresh token for the user.
+// A new session is started when sessionID is 0, otherwise the session is extended.
+func issueTokens(c echo.Context, user models.User, sessionID int) (echo.Map, error) {
+	now := time.Now()
+	ip := truncate(c.RealIP(), 45)
+
+	if sessionID == 0 {
+		session := models.Session{
+			Username:   user.Username,
+			IP:         ip,
+			UserAgent:  truncate(c.Request().UserAgent(), 255),
+			CreatedAt:  now.UTC(),
+			LastUsedAt: now.UTC(),
+			ExpiresAt:  now.Add(refreshTokenTTL).UTC(),
+		}
+		if err := stores.Sessions.Create(&session); err != nil {
+			return nil, err
+		}
+		sessionID = session.SessionID
+	} else {
+		if err := stores.Sessions.Extend(sessionID, now.Add(refreshTokenTTL).UTC()); err != nil {
+			return nil, err
+		}
+		if err := stores.Sessions.Touch(sessionID, now.UTC(), ip); err != nil {
+			return nil, err
+		}
+	}
+
+	// Set custom claims
+	claims := &JwtCustomClaims{
+		Username:     user.Username,
+		Access_level: user.AccessLvl,
+		TokenVersion: user.TokenVersion,
+		SessionID:    sessionID,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
+		},
+	}
+
+	t, err := signAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = stores.Tokens.Create(models.RefreshToken{
+		TokenHash: hashToken(refresh),
+		Username:  user.Username,
+		SessionID: nulltype.NullInt64Of(int64(sessionID)),
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(refreshTokenTTL).UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return echo.Map{
+		"token":        t,
+		"refreshToken": refresh,
+		"expiresIn":    int(accessTokenTTL.Seconds()),
+		// The user is restricted until its email is verified.
+		"emailVerified": user.EmailVerified,
+	}, nil
+}
+
+// parseToken validates the access token and checks that it was not revoked since it was issued.
+// The permissions of the user are loaded on each request, so that role changes apply immediately.
+func parseToken(auth string, c echo.Context) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(auth, &JwtCustomClaims{}, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims := token.Claims.(*JwtCustomClaims)
+	if claims.Username == "" {
+		return nil, errors.New("invalid token")
+	}
+	user, err := stores.Users.Get(claims.Username)
+	if err != nil {
+		return nil, err
+	}
+	if user.TokenVersion != claims.TokenVersion {
+		return nil, errTokenRevoked
+	}
+	if user.Banned {
+		return nil, errors.New("user banned")
+	}
+	if claims.SessionID != 0 {
+		session, err := stores.Sessions.Get(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if session.Username != user.Username || session.RevokedAt.Valid() {
+			return nil, errTokenRevoked
+		}
+		if now := time.Now(); now.Sub(session.LastUsedAt) > sessionTouchInterval {
+			if err := stores.Sessions.Touch(session.SessionID, now.UTC(), truncate(c.RealIP(), 45)); err != nil {
+				log.Warn("ParseToken/ Error updating session: ", err)
+			}
+		}
+	}
+	setPermissions(c, user, user.Permissions())
+
+	return token, nil
+}
+
+// revokeTokens revokes every session, access and refresh token of the user.
+func revokeTokens(username string) error {
+	if err := stores.Users.IncrTokenVersion(username); err != nil {
+		return err
+	}
+	if err := stores.Sessions.RevokeAll(username, 0); err != nil {
+		return err
+	}
+	return stores.Tokens.RevokeAll(username)
+}
+
+// generateToken returns a random url safe token.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash under which a token is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
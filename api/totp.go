@@ -0,0 +1,569 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/gyroskan/cardinal/totp"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	totpIssuer         = "cardinal"
+	challengeTTL       = 5 * time.Minute
+	challengeAttempts  = 5
+	recoveryCodesCount = 10
+	// Time after a login during which 2FA can be enrolled or its recovery codes regenerated without password
+	reauthWindow = 5 * time.Minute
+	// Purpose of the cipher sealing the TOTP secrets
+	totpSecretPurpose = "cardinal totp secret"
+	// Length of the secrets enrolled before they were sealed, 20 bytes in base32
+	legacyTotpSecretLength = 32
+)
+
+var (
+	// Encoding of the TOTP secrets
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	// Whether users with the users:admin permission are restricted until they enable 2FA
+	requireAdmin2FA bool
+
+	// Failed attempts of each challenge, so that codes can not be brute forced
+	challengeFailures   = map[string]challengeFailure{}
+	challengeFailuresMu sync.Mutex
+)
+
+type (
+	// challengeClaims is the short lived token returned by the login when 2FA is enabled,
+	// exchanged with a code against the user tokens.
+	challengeClaims struct {
+		Nonce        string `json:"nonce"`
+		TokenVersion int    `json:"ver"`
+		jwt.StandardClaims
+	}
+
+	challengeFailure struct {
+		count     int
+		expiresAt time.Time
+	}
+
+	codeRequest struct {
+		Code     string `json:"code" form:"code"`
+		Password string `json:"password" form:"password"` // Current password, unless the user logged in recently
+	}
+)
+
+func initTotp() {
+	t := apiGroupe.Group("/users/:username/2fa")
+	t.GET("", getTotp, isAdminOrLoggedIn)
+	t.POST("", enrollTotp, isLoggedInUser, notApiKey)
+	t.POST("/confirm", confirmTotp, isLoggedInUser, notApiKey)
+	t.POST("/recovery-codes", regenerateRecoveryCodes, isLoggedInUser, notApiKey)
+	t.DELETE("", disableTotp, isAdminOrLoggedIn, notApiKey)
+}
+
+// completeLogin returns the tokens of the user, or a challenge when the user enabled 2FA.
+func completeLogin(c echo.Context, user models.User, method string) error {
+	if user.Banned {
+		recordLogin(c, user.Username, method, models.UserBanned)
+		return echo.NewHTTPError(http.StatusForbidden, "User banned")
+	}
+
+	if !user.TotpEnabled {
+		usernameThrottle.reset(user.Username)
+		recordLogin(c, user.Username, method, "")
+		tokens, err := issueTokens(c, user, 0)
+		if err != nil {
+			log.Warn("Error generating token: ", err)
+			return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error generating token."})
+		}
+		return c.JSON(http.StatusOK, tokens)
+	}
+
+	nonce, err := generateToken()
+	if err != nil {
+		log.Warn("Error generating challenge: ", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error generating token."})
+	}
+	challenge := jwt.NewWithClaims(jwt.SigningMethodHS256, &challengeClaims{
+		Nonce:        nonce,
+		TokenVersion: user.TokenVersion,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.Username,
+			ExpiresAt: time.Now().Add(challengeTTL).Unix(),
+		},
+	})
+	signed, err := challenge.SignedString([]byte(secret))
+	if err != nil {
+		log.Warn("Error generating challenge: ", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error generating token."})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"challenge": signed,
+		"expiresIn": int(challengeTTL.Seconds()),
+	})
+}
+
+// @Summary      Login second step
+// @Tags         Users
+// @Description  Exchange the challenge returned by the login and a TOTP or recovery code for the user tokens.
+// @Accept       json
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        challenge  body      string  true  "challenge"
+// @Param        code       body      string  true  "TOTP or recovery code"
+// @Success      200        {object}  object  "Access token, refresh token and access token lifetime in seconds"
+// @Failure      400        "Invalid request"
+// @Failure      401        "Invalid challenge or code"
+// @Failure      500        "Server Error"
+// @Router       /users/login/2fa [POST]
+func loginSecondFactor(c echo.Context) error {
+	var req struct {
+		Challenge string `json:"challenge" form:"challenge"`
+		Code      string `json:"code" form:"code"`
+	}
+	if err := c.Bind(&req); err != nil || req.Challenge == "" || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "challenge or code missing")
+	}
+
+	claims := &challengeClaims{}
+	_, err := jwt.ParseWithClaims(req.Challenge, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || claims.Nonce == "" || !challengeAllowed(claims.Nonce) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid challenge")
+	}
+
+	user, err := stores.Users.Get(claims.Subject)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid challenge")
+		}
+		log.Warn("Login2FA/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if !user.TotpEnabled || user.TokenVersion != claims.TokenVersion {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid challenge")
+	}
+	if locked, err := loginLocked(c, user.Username, models.TotpLogin); locked {
+		return err
+	}
+	if user.Banned {
+		recordLogin(c, user.Username, models.TotpLogin, models.UserBanned)
+		return echo.NewHTTPError(http.StatusForbidden, "User banned")
+	}
+
+	valid, err := checkSecondFactor(user, req.Code)
+	if err != nil {
+		log.Warn("Login2FA/ Error verifying code: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if !valid {
+		challengeFailed(claims.Nonce, time.Unix(claims.ExpiresAt, 0))
+		loginFailed(c, user.Username, models.TotpLogin, models.InvalidCode)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid code")
+	}
+	challengeFailed(claims.Nonce, time.Time{}) // a challenge can only be used once
+	usernameThrottle.reset(user.Username)
+	recordLogin(c, user.Username, models.TotpLogin, "")
+
+	tokens, err := issueTokens(c, user, 0)
+	if err != nil {
+		log.Warn("Error generating token: ", err)
+		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Error generating token."})
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// challengeAllowed reports whether the challenge can still be attempted.
+func challengeAllowed(nonce string) bool {
+	challengeFailuresMu.Lock()
+	defer challengeFailuresMu.Unlock()
+
+	return challengeFailures[nonce].count < challengeAttempts
+}
+
+// challengeFailed records a failed attempt of the challenge, expiring at the given date.
+// A zero date exhausts the challenge.
+func challengeFailed(nonce string, expiresAt time.Time) {
+	challengeFailuresMu.Lock()
+	defer challengeFailuresMu.Unlock()
+
+	now := time.Now()
+	for n, f := range challengeFailures {
+		if now.After(f.expiresAt) {
+			delete(challengeFailures, n)
+		}
+	}
+
+	f := challengeFailures[nonce]
+	f.count++
+	if expiresAt.IsZero() {
+		f.count = challengeAttempts
+		expiresAt = now.Add(challengeTTL)
+	}
+	f.expiresAt = expiresAt
+	challengeFailures[nonce] = f
+}
+
+// checkSecondFactor verifies a TOTP or recovery code of the user, each code being accepted once.
+func checkSecondFactor(user models.User, code string) (bool, error) {
+	secret, err := totpSecret(user)
+	if err != nil {
+		return false, err
+	}
+	if step, ok := totp.Validate(secret, code, time.Now()); ok {
+		err := stores.Users.UseTotpStep(user.Username, step)
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err = stores.RecoveryCodes.Use(user.Username, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// newRecoveryCodes replaces the recovery codes of the user and returns the new ones.
+func newRecoveryCodes(username string) ([]string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]models.RecoveryCode, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = models.RecoveryCode{Username: username, CodeHash: hashToken(code)}
+	}
+
+	if err := stores.RecoveryCodes.Replace(username, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// sealTotpSecret encrypts the base32 secret with the SECRET, like the signing keys.
+// The decoded secret is sealed, so that it fits in the totp_secret column.
+func sealTotpSecret(secret string) (string, error) {
+	raw, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	return sealKey(totpSecretPurpose, raw)
+}
+
+// totpSecret returns the base32 secret of the user.
+// Secrets enrolled before they were sealed are stored as is.
+func totpSecret(user models.User) (string, error) {
+	sealed := user.TotpSecret.StringValue()
+	if len(sealed) == legacyTotpSecretLength {
+		return sealed, nil
+	}
+	raw, err := openKey(totpSecretPurpose, sealed)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+// reauthenticated reports whether the logged in user gave its current password,
+// or logged in less than reauthWindow ago.
+func reauthenticated(c echo.Context, user *models.User, pwd string) (bool, error) {
+	if pwd != "" {
+		return checkPassword(user, pwd)
+	}
+	sessionID := loggedSessionID(c)
+	if sessionID == 0 {
+		return false, nil
+	}
+	session, err := stores.Sessions.Get(sessionID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return time.Since(session.CreatedAt) < reauthWindow, nil
+}
+
+// checkReauth reports whether the user reauthenticated, or writes the error response.
+func checkReauth(c echo.Context, handler string, user *models.User, pwd string) (bool, error) {
+	ok, err := reauthenticated(c, user, pwd)
+	if err != nil {
+		log.Warn(handler+"/ Error verifying password: ", err)
+		return false, c.JSON(http.StatusInternalServerError, nil)
+	}
+	if ok {
+		return true, nil
+	}
+	if pwd != "" {
+		return false, echo.NewHTTPError(http.StatusBadRequest, "Invalid password")
+	}
+	return false, echo.NewHTTPError(http.StatusForbidden, "Password required, or a login in the last 5 minutes")
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// getTotpUser returns the user of the username param, or writes the error response.
+func getTotpUser(c echo.Context, handler string) (models.User, error) {
+	username := c.Param("username")
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return user, c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
+		}
+		log.Warn(handler+"/ Error getting user: ", err)
+		return user, c.JSON(http.StatusInternalServerError, err)
+	}
+	return user, nil
+}
+
+// @Summary      Get 2FA status
+// @Tags         Users
+// @Description  Get whether 2FA is enabled and the number of unused recovery codes.
+// @Param        username  path      string  true  "username"
+// @Success      200       {object}  object  "OK"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/2fa [GET]
+func getTotp(c echo.Context) error {
+	user, err := getTotpUser(c, "GetTotp")
+	if err != nil || user.Username == "" {
+		return err
+	}
+
+	remaining, err := stores.RecoveryCodes.Remaining(user.Username)
+	if err != nil {
+		log.Warn("GetTotp/ Error counting recovery codes: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"enabled":       user.TotpEnabled,
+		"recoveryCodes": remaining,
+	})
+}
+
+// @Summary      Enroll 2FA
+// @Tags         Users
+// @Description  Generate a new TOTP secret. 2FA is enabled once a code of the secret is confirmed.
+// @Description  The current password is required, unless the user logged in less than 5 minutes ago.
+// @Accept       json
+// @Produce      json
+// @Param        username  path      string  true   "username"
+// @Param        password  body      string  false  "current password"
+// @Success      200       {object}  object  "Secret and otpauth uri"
+// @Failure      400       "2FA already enabled or invalid password"
+// @Failure      403       "Forbidden or password required"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/2fa [POST]
+func enrollTotp(c echo.Context) error {
+	var req codeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	user, err := getTotpUser(c, "EnrollTotp")
+	if err != nil || user.Username == "" {
+		return err
+	}
+	if user.TotpEnabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "2FA already enabled")
+	}
+	if ok, err := checkReauth(c, "EnrollTotp", &user, req.Password); !ok {
+		return err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Warn("EnrollTotp/ Error generating secret: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	sealed, err := sealTotpSecret(secret)
+	if err != nil {
+		log.Warn("EnrollTotp/ Error sealing secret: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if err := stores.Users.SetTotp(user.Username, nulltype.NullStringOf(sealed), false); err != nil {
+		log.Warn("EnrollTotp/ Error saving secret: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"secret": secret,
+		"uri":    totp.URI(totpIssuer, user.Username, secret),
+	})
+}
+
+// @Summary      Confirm 2FA
+// @Tags         Users
+// @Description  Enable 2FA with a code of the enrolled secret, and get the recovery codes.
+// @Accept       json
+// @Produce      json
+// @Param        username  path      string  true  "username"
+// @Param        code      body      string  true  "TOTP code"
+// @Success      200       {object}  object  "Recovery codes"
+// @Failure      400       "Invalid code"
+// @Failure      403       "Forbidden"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/2fa/confirm [POST]
+func confirmTotp(c echo.Context) error {
+	var req codeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "code missing")
+	}
+
+	user, err := getTotpUser(c, "ConfirmTotp")
+	if err != nil || user.Username == "" {
+		return err
+	}
+	if user.TotpEnabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "2FA already enabled")
+	}
+	if !user.TotpSecret.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "2FA not enrolled")
+	}
+
+	secret, err := totpSecret(user)
+	if err != nil {
+		log.Warn("ConfirmTotp/ Error opening secret: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid code")
+	}
+	if err := stores.Users.SetTotp(user.Username, user.TotpSecret, true); err != nil {
+		log.Warn("ConfirmTotp/ Error enabling 2FA: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if err := stores.Users.UseTotpStep(user.Username, step); err != nil {
+		log.Warn("ConfirmTotp/ Error saving step: ", err)
+	}
+
+	codes, err := newRecoveryCodes(user.Username)
+	if err != nil {
+		log.Warn("ConfirmTotp/ Error generating recovery codes: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"recoveryCodes": codes})
+}
+
+// @Summary      Regenerate recovery codes
+// @Tags         Users
+// @Description  Replace the recovery codes of the user.
+// @Description  The current password is required, unless the user logged in less than 5 minutes ago.
+// @Accept       json
+// @Produce      json
+// @Param        username  path      string  true   "username"
+// @Param        code      body      string  true   "TOTP code"
+// @Param        password  body      string  false  "current password"
+// @Success      200       {object}  object  "Recovery codes"
+// @Failure      400       "Invalid code or password"
+// @Failure      403       "Forbidden or password required"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/2fa/recovery-codes [POST]
+func regenerateRecoveryCodes(c echo.Context) error {
+	var req codeRequest
+	if err := c.Bind(&req); err != nil || req.Code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "code missing")
+	}
+
+	user, err := getTotpUser(c, "RegenerateRecoveryCodes")
+	if err != nil || user.Username == "" {
+		return err
+	}
+	if !user.TotpEnabled {
+		return echo.NewHTTPError(http.StatusBadRequest, "2FA not enabled")
+	}
+	if ok, err := checkReauth(c, "RegenerateRecoveryCodes", &user, req.Password); !ok {
+		return err
+	}
+
+	secret, err := totpSecret(user)
+	if err != nil {
+		log.Warn("RegenerateRecoveryCodes/ Error opening secret: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	step, ok := totp.Validate(secret, req.Code, time.Now())
+	if !ok || stores.Users.UseTotpStep(user.Username, step) != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid code")
+	}
+
+	codes, err := newRecoveryCodes(user.Username)
+	if err != nil {
+		log.Warn("RegenerateRecoveryCodes/ Error generating recovery codes: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{"recoveryCodes": codes})
+}
+
+// @Summary      Disable 2FA
+// @Tags         Users
+// @Description  Disable 2FA. Users must give a TOTP or recovery code, admins can disable it for other users without code.
+// @Accept       json
+// @Param        username  path  string  true   "username"
+// @Param        code      body  string  false  "TOTP or recovery code"
+// @Success      204       "No Content"
+// @Failure      400       "Invalid code"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/2fa [DELETE]
+func disableTotp(c echo.Context) error {
+	user, err := getTotpUser(c, "DisableTotp")
+	if err != nil || user.Username == "" {
+		return err
+	}
+
+	if user.Username == loggedUsername(c) && user.TotpEnabled {
+		var req codeRequest
+		if err := c.Bind(&req); err != nil || req.Code == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "code missing")
+		}
+		valid, err := checkSecondFactor(user, req.Code)
+		if err != nil {
+			log.Warn("DisableTotp/ Error verifying code: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+		if !valid {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid code")
+		}
+	}
+
+	if err := stores.Users.SetTotp(user.Username, nulltype.NullString{}, false); err != nil {
+		log.Warn("DisableTotp/ Error disabling 2FA: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+	if err := stores.RecoveryCodes.Replace(user.Username, nil); err != nil {
+		log.Warn("DisableTotp/ Error deleting recovery codes: ", err)
+		return c.JSON(http.StatusInternalServerError, nil)
+	}
+
+	return c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/totp"
+	"github.com/mattn/go-nulltype"
+)
+
+func TestTotpSecret(t *testing.T) {
+	defer func(s string) { secret = s }(secret)
+	secret = "0123456789abcdef"
+	plain, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := sealTotpSecret(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) > 64 {
+		t.Errorf("sealed secret has %d characters, more than the totp_secret column", len(sealed))
+	}
+	got, err := totpSecret(models.User{TotpSecret: nulltype.NullStringOf(sealed)})
+	if err != nil || got != plain {
+		t.Errorf("opened secret %q (%v), want %q", got, err, plain)
+	}
+
+	if got, err := totpSecret(models.User{TotpSecret: nulltype.NullStringOf(plain)}); err != nil || got != plain {
+		t.Errorf("legacy secret read as %q (%v), want %q", got, err, plain)
+	}
+
+	secret = "fedcba9876543210"
+	if _, err := totpSecret(models.User{TotpSecret: nulltype.NullStringOf(sealed)}); err == nil {
+		t.Error("secret opened with another SECRET")
+	}
+}
@@ -1,28 +1,31 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
 func initUsers() {
 	users := apiGroupe.Group("/users")
-	users.GET("/", getUsers, isAdmin)
+	users.GET("/", getUsers, requires(models.UsersAdmin))
 	users.GET("/me", getLoggedUser)
+	users.GET("/logins", getLoginHistory, requires(models.UsersAdmin))
 	users.GET("/:username", getUser, isAdminOrLoggedIn)
 	users.PATCH("/:username", updateUser, isAdminOrLoggedIn)
-	users.POST("/:username", updateAccessLvl, isAdmin)
-	users.POST("/:username/ban", banUser, isAdmin)
-	users.DELETE("/:username/ban", banUser, isAdmin)
+	users.POST("/:username", updateAccessLvl, requires(models.UsersAdmin))
+	users.POST("/:username/ban", banUser, requires(models.UsersAdmin))
+	users.DELETE("/:username/ban", banUser, requires(models.UsersAdmin))
+	users.PUT("/:username/roles", updateRoles, requires(models.UsersAdmin))
 	users.DELETE("/:username", deleteUser, isAdminOrLoggedIn)
+	users.POST("/:username/verify-email", resendVerification, isAdminOrLoggedIn)
 }
 
 // @Summary      Get User
@@ -36,10 +39,10 @@ func initUsers() {
 // @Router       /users/{username} [GET]
 func getUser(c echo.Context) error {
 	username := c.Param("username")
-	var user models.User
 
-	if err := db.DB.Get(&user, "SELECT * FROM user WHERE username=?", username); err != nil {
-		if err == sql.ErrNoRows {
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
 		}
 		log.Warn("GetUser/ Error getting user: ", err)
@@ -68,9 +71,9 @@ func getLoggedUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, echo.Map{"error": "Invalid token."})
 	}
 	username := claims.Username
-	var user models.User
 
-	if err := db.DB.Get(&user, "SELECT * FROM user WHERE username=?", username); err != nil {
+	user, err := stores.Users.Get(username)
+	if err != nil {
 		log.Warn("GetLoggedUser/ Error getting user: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
@@ -86,9 +89,8 @@ func getLoggedUser(c echo.Context) error {
 // @Failure      500  "Server error"
 // @Router       /users/ [GET]
 func getUsers(c echo.Context) error {
-	var users []models.User
-
-	if err := db.DB.Select(&users, "SELECT * FROM user"); err != nil {
+	users, err := stores.Users.All()
+	if err != nil {
 		log.Warn("GetUsers/ Error getting all users: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
@@ -98,7 +100,7 @@ func getUsers(c echo.Context) error {
 
 // @Summary      Update User
 // @Tags         Users
-// @Description  Update specified User fields
+// @Description  Update specified User fields. The password is changed when both oldPassword and password are given.
 // @Param        username   path      string                   true  "username"
 // @Param        userModif  body      models.UserModification  true  "User modification"
 // @Success      200        {object}  models.User              "OK"
@@ -108,11 +110,11 @@ func getUsers(c echo.Context) error {
 // @Failure      500        "Server error"
 // @Router       /users/{username} [PATCH]
 func updateUser(c echo.Context) error {
-	var user models.User
 	username := c.Param("username")
 
-	if err := db.DB.Get(&user, "SELECT * FROM `user` WHERE username=?", username); err != nil {
-		if err == sql.ErrNoRows {
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
 		}
 		log.Warn("UpdateUser/ Error getting user: ", err)
@@ -128,31 +130,72 @@ func updateUser(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
+	emailChanged := userUp.Email != user.Email
+	if emailChanged {
+		// Users created by a Discord login without verified email set theirs here.
+		if userUp.Email, err = models.ValidEmail(userUp.Email); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		user.EmailVerified = false
+	}
+	if userUp.DiscordID != user.DiscordID {
+		// Only the Discord login can prove the ownership of the new id.
+		user.DiscordVerified = false
+	}
+	user.Email = userUp.Email
+	user.DiscordID = userUp.DiscordID
+
+	if (userUp.OldPassword == "") != (userUp.Password == "") {
+		return echo.NewHTTPError(http.StatusBadRequest, "oldPassword and password must be given together")
+	}
 	if userUp.OldPassword != "" {
-		if hashPassword(userUp.OldPassword, []byte(user.Salt)) != user.PasswordHash {
-			return echo.NewHTTPError(http.StatusBadRequest, "Invalid old password.")
+		if err := models.ValidPassword(userUp.Password); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 		}
-		salt, err := generateSalt()
+		valid, err := checkPassword(&user, userUp.OldPassword)
 		if err != nil {
-			log.Warn("UpdateUser/ Error generating salt: ", err)
+			log.Warn("UpdateUser/ Error verifying password: ", err)
+			return echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		if !valid {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid old password.")
+		}
+		if user.PasswordHash, err = hasher.Hash(userUp.Password); err != nil {
+			log.Warn("UpdateUser/ Error hashing password: ", err)
 			return c.JSON(http.StatusInternalServerError, "Error hashing new password.")
 		}
-		user.PasswordHash = hashPassword(userUp.Password, salt)
-		user.Salt = string(salt)
+		user.Salt = ""
 	}
 
-	_, err := db.DB.Exec(models.UpdateUserQuery, user)
+	err = stores.Users.Update(user)
 	if err != nil {
 		log.Warn("UpdateUser/ Error updating member: ", err)
 		return c.JSON(http.StatusInternalServerError, "Error saving data.")
 	}
 
+	if emailChanged {
+		// Tokens sent to the previous email must not verify the new one.
+		if err := stores.UserTokens.UseAll(username, models.VerifyEmailPurpose); err != nil {
+			log.Warn("UpdateUser/ Error revoking verification tokens: ", err)
+		}
+		if err := sendUserToken(user, models.VerifyEmailPurpose); err != nil {
+			log.Warn("UpdateUser/ Error sending verification email: ", err)
+		}
+	}
+
+	if userUp.OldPassword != "" {
+		if err := revokeTokens(username); err != nil {
+			log.Warn("UpdateUser/ Error revoking tokens: ", err)
+			return c.JSON(http.StatusInternalServerError, "Error revoking tokens.")
+		}
+	}
+
 	return c.JSON(http.StatusOK, user)
 }
 
 // @Summary      Update User access level
 // @Tags         Users
-// @Description  Update User access level
+// @Description  Update User access level. Level 3 grants no global access, only the guild accesses of the user.
 // @Param        username      path      string       true  "username"
 // @Param        access_level  query     int          true  "access_level"
 // @Success      200           {object}  models.User  "OK"
@@ -162,34 +205,82 @@ func updateUser(c echo.Context) error {
 // @Router       /users/{username} [POST]
 func updateAccessLvl(c echo.Context) error {
 	lvl, err := strconv.Atoi(c.QueryParam("access_level"))
-	if err != nil || lvl > 2 || lvl < 0 {
+	if err != nil || lvl > 3 || lvl < 0 {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid access_level")
 	}
 
 	username := c.Param("username")
-	var user models.User
-	if err := db.DB.Get(&user, "SELECT * FROM `user` WHERE username=?", username); err != nil {
-		if err == sql.ErrNoRows {
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
 		}
 		log.Warn("UpdateAccessLvl/ Error getting user: ", err)
 		return c.JSON(http.StatusInternalServerError, err)
 	}
 
-	_, err = db.DB.Exec("UPDATE user SET access_lvl=? WHERE username=?", lvl, username)
+	err = stores.Users.SetAccessLvl(username, lvl)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
-		}
 		log.Warn("UpdateAccessLvl/ Error updating member: ", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Error saving data.")
 	}
 
+	if err := revokeTokens(username); err != nil {
+		log.Warn("UpdateAccessLvl/ Error revoking tokens: ", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Error revoking tokens.")
+	}
+
 	user.AccessLvl = lvl
 
 	return c.JSON(http.StatusOK, user)
 }
 
+// @Summary      Update User roles
+// @Tags         Users
+// @Description  Set the roles granted to the user on top of its access level.
+// @Description  Available roles are admin, editor, viewer, moderator and leveling.
+// @Accept       json
+// @Produce      json
+// @Param        username  path      string       true  "username"
+// @Param        roles     body      string       true  "Roles separated by spaces"
+// @Success      200       {object}  models.User  "OK"
+// @Failure      400       "Invalid roles"
+// @Failure      403       "Forbidden"
+// @Failure      404       "Not Found"
+// @Failure      500       "Server error"
+// @Router       /users/{username}/roles [PUT]
+func updateRoles(c echo.Context) error {
+	var body struct {
+		Roles string `json:"roles" form:"roles"`
+	}
+	if err := c.Bind(&body); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	roles, err := models.ValidateRoles(body.Roles)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	username := c.Param("username")
+	user, err := stores.Users.Get(username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
+		}
+		log.Warn("UpdateRoles/ Error getting user: ", err)
+		return c.JSON(http.StatusInternalServerError, err)
+	}
+
+	if err := stores.Users.SetRoles(username, roles); err != nil {
+		log.Warn("UpdateRoles/ Error updating user: ", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Error saving data.")
+	}
+
+	user.Roles = roles
+
+	return c.JSON(http.StatusOK, user)
+}
+
 // @Summary      Ban User
 // @Tags         Users
 // @Description  Update User ban. POST to unbann, DELETE to ban.
@@ -202,9 +293,8 @@ func updateAccessLvl(c echo.Context) error {
 // @Router       /users/{username}/ban [DELETE]
 func banUser(c echo.Context) error {
 	username := c.Param("username")
-	var tmp int
-	if err := db.DB.Get(&tmp, "SELECT 1 FROM user WHERE username=?", username); err != nil {
-		if err == sql.ErrNoRows {
+	if _, err := stores.Users.Get(username); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, echo.Map{"error": "user " + username + " not found."})
 		}
 		log.Warn("GetUser/ Error getting user: ", err)
@@ -212,12 +302,19 @@ func banUser(c echo.Context) error {
 	}
 
 	ban := c.Request().Method != "POST"
-	_, err := db.DB.Exec("UPDATE user SET banned=? WHERE username=?", ban, username)
+	err := stores.Users.SetBanned(username, ban)
 	if err != nil {
 		log.Warn("BanUser/error: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
+	if ban {
+		if err := revokeTokens(username); err != nil {
+			log.Warn("BanUser/ Error revoking tokens: ", err)
+			return c.JSON(http.StatusInternalServerError, nil)
+		}
+	}
+
 	return c.JSON(http.StatusOK, nil)
 }
 
@@ -232,15 +329,14 @@ func banUser(c echo.Context) error {
 // @Router       /users/{username} [DELETE]
 func deleteUser(c echo.Context) error {
 	username := c.Param("username")
-	res, err := db.DB.Exec("DELETE FROM user WHERE username=?", username)
+	err := stores.Users.Delete(username)
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Warn("deleteUser/ err: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
-	}
-
 	return c.JSON(http.StatusNoContent, nil)
 }
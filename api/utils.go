@@ -1,39 +1,91 @@
 package api
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
-
 	"github.com/golang-jwt/jwt"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/password"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 )
 
-func generateSalt() ([]byte, error) {
-	var salt = make([]byte, 16)
-
-	_, err := rand.Read(salt[:])
+// checkPassword verifies the password of the user.
+// On success, legacy or outdated hashes are transparently replaced by a hash of the current hasher.
+func checkPassword(user *models.User, pwd string) (bool, error) {
+	var ok bool
+	var err error
+	if user.PasswordHash == "" {
+		// Users created through Discord login have no password.
+		return false, nil
+	}
+	if password.IsLegacy(user.PasswordHash) {
+		ok, err = password.VerifyLegacy(pwd, user.PasswordHash, user.Salt)
+	} else {
+		ok, err = password.Verify(pwd, user.PasswordHash)
+	}
+	if err != nil || !ok {
+		return false, err
+	}
 
-	if err != nil {
-		log.Warn("generateSalt/:", err)
-		return nil, err
+	if password.IsLegacy(user.PasswordHash) || hasher.NeedsRehash(user.PasswordHash) {
+		hash, err := hasher.Hash(pwd)
+		if err != nil {
+			log.Warn("checkPassword/ Error rehashing password: ", err)
+			return true, nil
+		}
+		user.PasswordHash = hash
+		user.Salt = ""
+		if err := stores.Users.Update(*user); err != nil {
+			log.Warn("checkPassword/ Error saving rehashed password: ", err)
+		}
 	}
 
-	return salt, nil
+	return true, nil
 }
 
-func hashPassword(password string, salt []byte) string {
-	var passwordBytes = []byte(password)
-	passwordBytes = append(passwordBytes, salt...)
+// permissions returns the permissions of the logged in user.
+func permissions(c echo.Context) models.PermissionSet {
+	perms, _ := c.Get("permissions").(models.PermissionSet)
+	return perms
+}
 
-	var hasher = sha256.New()
-	hasher.Write(passwordBytes)
+// loggedUsername returns the username of the logged in user.
+func loggedUsername(c echo.Context) string {
+	user, success := c.Get("user").(*jwt.Token)
+	if !success {
+		return ""
+	}
+	claims, success := user.Claims.(*JwtCustomClaims)
+	if !success {
+		return ""
+	}
+	return claims.Username
+}
 
-	return hex.EncodeToString(hasher.Sum(nil))
+// setPermissions sets the permissions of the logged in user,
+// none while the user did not confirm its email, or did not enable 2FA when it is required for admins.
+func setPermissions(c echo.Context, user models.User, perms models.PermissionSet) {
+	if !user.EmailVerified || (requireAdmin2FA && !user.TotpEnabled && user.Permissions().Has(models.UsersAdmin)) {
+		c.Set("restricted", true)
+		perms = models.PermissionSet{}
+	}
+	c.Set("permissions", perms)
+}
+
+// requires restricts the route to logged in users having every given permission.
+func requires(perms ...models.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !permissions(c).Has(perms...) {
+				return echo.ErrForbidden
+			}
+			return next(c)
+		}
+	}
 }
 
-func isAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+// isAdminOrLoggedIn restricts the route to the user of the username parameter
+// and to users allowed to manage every user.
+func isAdminOrLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user, success := c.Get("user").(*jwt.Token)
 		if !success {
@@ -43,28 +95,32 @@ func isAdmin(next echo.HandlerFunc) echo.HandlerFunc {
 		if !success {
 			return echo.ErrForbidden
 		}
-		accessLevel := claims.Access_level
-		if accessLevel != 0 {
+		if c.Param("username") != claims.Username && !permissions(c).Has(models.UsersAdmin) {
 			return echo.ErrForbidden
 		}
 		return next(c)
 	}
 }
 
-func isAdminOrLoggedIn(next echo.HandlerFunc) echo.HandlerFunc {
+// isLoggedInUser restricts the route to the user of the username parameter.
+func isLoggedInUser(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		user, success := c.Get("user").(*jwt.Token)
-		if !success {
-			return echo.ErrForbidden
-		}
-		claims, success := user.Claims.(*JwtCustomClaims)
-		if !success {
-			return echo.ErrForbidden
-		}
-		accessLevel := claims.Access_level
-		if accessLevel != 0 && c.Param("username") != claims.Username {
+		if u := loggedUsername(c); u == "" || c.Param("username") != u {
 			return echo.ErrForbidden
 		}
 		return next(c)
 	}
 }
+
+// loggedSessionID returns the session of the access token, 0 for api keys and tokens issued before sessions.
+func loggedSessionID(c echo.Context) int {
+	user, success := c.Get("user").(*jwt.Token)
+	if !success {
+		return 0
+	}
+	claims, success := user.Claims.(*JwtCustomClaims)
+	if !success {
+		return 0
+	}
+	return claims.SessionID
+}
@@ -1,21 +1,32 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/gyroskan/cardinal/db"
 	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"github.com/mattn/go-nulltype"
 )
 
+// warnResult is a created warn, with the ban it escalated to.
+type warnResult struct {
+	models.Warn
+	Escalated bool        `json:"escalated"`     // Whether the member reached the maxWarns of the guild
+	Ban       *models.Ban `json:"ban,omitempty"` // Automatic ban to apply
+}
+
 func initWarn() {
-	w := apiGroupe.Group("/guilds/:guildID/members/:memberID/warns")
-	w.GET("/", getWarns)
-	w.GET("/:warnID", getWarn)
-	w.POST("/", createWarn)
-	w.DELETE("/:warnID", deleteWarn)
+	w := apiGroupe.Group("/guilds/:guildID/members/:memberID/warns", guildAccess("guildID"))
+	w.GET("/", getWarns, requires(models.ModerationRead))
+	w.GET("/:warnID", getWarn, requires(models.ModerationRead))
+	w.POST("/", createWarn, requires(models.ModerationWrite))
+	w.DELETE("/:warnID", deleteWarn, requires(models.ModerationWrite))
 }
 
 // @Summary      Get Member Warns
@@ -30,16 +41,14 @@ func initWarn() {
 func getWarns(c echo.Context) error {
 	guildID := c.Param("guildID")
 	memberID := c.Param("memberID")
-	var bans []models.Warn
-
-	err := db.DB.Select(&bans, "SELECT * FROM warn WHERE guild_id=? AND member_id=?", guildID, memberID)
+	warns, err := stores.Warns.List(guildID, memberID)
 
 	if err != nil {
 		log.Warn("GetWarns/ Error retrieving warns: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
-	return c.JSON(http.StatusOK, bans)
+	return c.JSON(http.StatusOK, warns)
 }
 
 // @Summary      Get one warn
@@ -56,13 +65,15 @@ func getWarns(c echo.Context) error {
 func getWarn(c echo.Context) error {
 	guildID := c.Param("guildID")
 	memberID := c.Param("memberID")
-	warnID := c.Param("warnID")
-	var warn models.Warn
+	warnID, err := strconv.Atoi(c.Param("warnID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, nil)
+	}
 
-	err := db.DB.Get(&warn, "SELECT * FROM warn WHERE guild_id=? AND member_id=? AND warn_id=?", guildID, memberID, warnID)
+	warn, err := stores.Warns.Get(guildID, memberID, warnID)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.JSON(http.StatusNotFound, nil)
 		}
 		log.Warn("Getwarn/ Error retrieving warn: ", err)
@@ -75,14 +86,19 @@ func getWarn(c echo.Context) error {
 // @Summary      Create warn
 // @Tags         Warns
 // @Description  Create a new warn for a member.
+// @Description  When the member reaches the maxWarns of the guild since its last ban, and the guild allows moderation,
+// @Description  an automatic ban lasting banTime days, or permanent for 0, is created with the warn.
+// @Description  escalated is then true, and ban is the ban to apply, dated at the warnedAt of a warn in the future.
+// @Description  The warn and the ban are added to the moderation cases of the guild.
 // @Accept       json
 // @Produce      json
 // @Param        guildID   path      string       true  "guild id"
 // @Param        memberID  path      string       true  "member id"
 // @Param        warn      body      models.Warn  true  "warn values"
-// @Success      201       {object}  models.Warn  "Created warn"
+// @Success      201       {object}  warnResult   "Created warn"
 // @Failure      400       "Wrong values"
 // @Failure      403       "Forbidden"
+// @Failure      404       "Guild not found"
 // @Failure      500       "Server Error"
 // @Router       /guilds/{guildID}/members/{memberID}/warns [POST]
 func createWarn(c echo.Context) error {
@@ -94,29 +110,44 @@ func createWarn(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{"error": err, "warnObj": warn})
 	}
 
-	query, err := db.DB.PrepareNamed(models.CreateWarnQuery)
+	warn.WarnedAt = warn.WarnedAt.UTC()
 
+	ban, err := stores.Warns.CreateEscalating(&warn, func(guild models.Guild, warns int) *models.Ban {
+		return escalation(guild, memberID, warns, warn.WarnedAt)
+	})
 	if err != nil {
-		log.Error("CreateWarn/ error while preparing query:", err)
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "Guild " + guildID + " not found"})
+		}
+		log.Error("CreateWarn/ Error while inserting warn: ", err)
 		return c.JSON(http.StatusInternalServerError, nil)
 	}
 
-	res, err := query.Exec(models.CreateWarnQuery)
+	return c.JSON(http.StatusCreated, warnResult{Warn: warn, Escalated: ban != nil, Ban: ban})
+}
 
-	if err != nil {
-		log.Error("CreateWarn/ Error while inserting warn: ", err)
-		return c.JSON(http.StatusInternalServerError, nil)
+// escalation returns the automatic ban of a member who reached the maxWarns of the guild, or nil.
+// The ban is not dated before the warn at warnedAt, so that the warns before it stop counting.
+func escalation(guild models.Guild, memberID string, warns int, warnedAt time.Time) *models.Ban {
+	if !guild.AllowModeration || guild.MaxWarns <= 0 || warns < guild.MaxWarns {
+		return nil
 	}
 
-	id, err := res.LastInsertId()
-	if err != nil {
-		log.Error("CreateWarn/ Error while getting last index: ", err)
-		// TODO switch case of sql errors.
-		return c.JSON(http.StatusInternalServerError, nil)
+	now := time.Now().UTC()
+	if warnedAt.After(now) {
+		now = warnedAt
 	}
-	warn.WarnID = int(id)
-
-	return c.JSON(http.StatusCreated, warn)
+	ban := &models.Ban{
+		MemberID:  memberID,
+		GuildID:   guild.GuildID,
+		BannedAt:  now,
+		BanReason: nulltype.NullStringOf(fmt.Sprintf("Automatic ban after %d warns", warns)),
+		AutoBan:   true,
+	}
+	if guild.BanTime > 0 {
+		ban.ExpiresAt = nulltype.NullTimeOf(now.AddDate(0, 0, guild.BanTime))
+	}
+	return ban
 }
 
 // @Summary      Delete member's warn
@@ -135,18 +166,20 @@ func createWarn(c echo.Context) error {
 func deleteWarn(c echo.Context) error {
 	guildID := c.Param("guildID")
 	memberID := c.Param("memberID")
-	warnID := c.Param("warnID")
+	warnID, err := strconv.Atoi(c.Param("warnID"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, nil)
+	}
 
-	res, err := db.DB.Exec("DELETE FROM warn WHERE guild_id=? AND member_id=? AND warn_id=?", guildID, memberID, warnID)
+	err = stores.Warns.Delete(guildID, memberID, warnID)
 
 	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, nil)
+		}
 		log.Error("DeleteWarn/ Error while deleting warn from db: ", err)
 		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Could not delete the warn."})
 	}
 
-	if r, _ := res.RowsAffected(); r == 0 {
-		return c.JSON(http.StatusNotFound, nil)
-	}
-
 	return c.JSON(http.StatusNoContent, nil)
 }
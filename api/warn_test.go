@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+func TestEscalation(t *testing.T) {
+	guild := models.Guild{GuildID: "g1", AllowModeration: true, MaxWarns: 3, BanTime: 2}
+	now := time.Now().UTC()
+	future := now.Add(48 * time.Hour)
+
+	if ban := escalation(guild, "m1", 2, now); ban != nil {
+		t.Errorf("2 warns escalated to %v, want no ban below the max warns", ban)
+	}
+	if ban := escalation(models.Guild{GuildID: "g1", MaxWarns: 3}, "m1", 3, now); ban != nil {
+		t.Errorf("guild without moderation escalated to %v", ban)
+	}
+
+	ban := escalation(guild, "m1", 3, now.Add(-time.Hour))
+	if ban == nil || ban.BannedAt.Before(now) || !ban.AutoBan || ban.MemberID != "m1" {
+		t.Fatalf("3 warns escalated to %+v, want an automatic ban of m1 at the current time", ban)
+	}
+	if got := ban.ExpiresAt.TimeValue().Sub(ban.BannedAt); got != 48*time.Hour {
+		t.Errorf("ban lasts %s, want the 2 days of the guild", got)
+	}
+
+	// A ban dated before a warn in the future would leave the warn counting.
+	ban = escalation(guild, "m1", 3, future)
+	if ban == nil || !ban.BannedAt.Equal(future) || !ban.ExpiresAt.TimeValue().Equal(future.AddDate(0, 0, 2)) {
+		t.Errorf("warn in the future escalated to %+v, want a ban from %s", ban, future)
+	}
+}
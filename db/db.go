@@ -2,11 +2,15 @@ package db
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"time"
 
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/gommon/log"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 var (
@@ -14,10 +18,46 @@ var (
 )
 
 func Connect() {
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = "mysql"
+	}
+
+	// The connection string holds the password, only the host or the path is logged.
+	var dbString, target string
+	switch driver {
+	case "mysql":
+		dbString = fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&multiStatements=true",
+			os.Getenv("DB_USER"), os.Getenv("DB_PWD"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME"))
+		target = os.Getenv("DB_HOST")
+	case "postgres":
+		sslMode := os.Getenv("DB_SSLMODE")
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dbString = (&url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(os.Getenv("DB_USER"), os.Getenv("DB_PWD")),
+			Host:     os.Getenv("DB_HOST"),
+			Path:     os.Getenv("DB_NAME"),
+			RawQuery: "sslmode=" + url.QueryEscape(sslMode),
+		}).String()
+		target = os.Getenv("DB_HOST")
+	case "sqlite":
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "cardinal.db"
+		}
+		dbString = fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+		target = path
+	default:
+		log.Fatalf("Unknown database driver %s.", driver)
+	}
+
 	tries := 0
 	for tries < 5 {
 		tries++
-		if connect(os.Getenv("DB_USER"), os.Getenv("DB_PWD"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME")) {
+		if connect(driver, dbString, target) {
 			return
 		}
 		time.Sleep(5 * time.Second)
@@ -26,16 +66,19 @@ func Connect() {
 	log.Fatalf("Could not connect to database after %d tries.", tries)
 }
 
-func connect(db_user string , db_pwd string , db_host string, db_name string) bool {
+func connect(driver string, dbString string, target string) bool {
 	log.Info("Connecting to database...")
 	var err error
-	dbString := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&multiStatements=true", db_user, db_pwd, db_host, db_name)
-	log.Debug("Connecting with string: ", dbString)
-	DB, err = sqlx.Connect("mysql", dbString)
+	log.Debug("Connecting to ", driver, " database on ", target)
+	DB, err = sqlx.Connect(driver, dbString)
 	if err != nil {
 		log.Error("Connection to database failed: ", err)
 		return false
 	}
+	if driver == "sqlite" {
+		// SQLite only allows one writer at a time.
+		DB.SetMaxOpenConns(1)
+	}
 	log.Info("Connected to database.")
 	return true
 }
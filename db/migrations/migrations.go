@@ -0,0 +1,207 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql postgres/*.sql
+var scripts embed.FS
+
+const (
+	createTableQuery = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT       NOT NULL PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP    NOT NULL
+		)
+	`
+	selectAppliedQuery = "SELECT version, name, applied_at FROM schema_migrations ORDER BY version ASC"
+	insertAppliedQuery = "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"
+	deleteAppliedQuery = "DELETE FROM schema_migrations WHERE version=?"
+)
+
+type (
+	// Migration is one versioned schema change with its rollback script.
+	Migration struct {
+		Version int    // Version of the migration, migrations are applied in ascending order
+		Name    string // Name of the migration, taken from the file name
+		Up      string // Script applying the migration
+		Down    string // Script reverting the migration
+	}
+
+	// Status describes whether a migration was applied to the database.
+	Status struct {
+		Migration
+		Applied   bool      // Whether the migration was applied or not
+		AppliedAt time.Time // Date the migration was applied
+	}
+
+	applied struct {
+		Version   int       `db:"version"`
+		Name      string    `db:"name"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+)
+
+// Load returns the migrations embedded for the given sql driver, sorted by version.
+func Load(driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(scripts, driver)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %s: %w", driver, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		// Files are named <version>_<name>.<up|down>.sql
+		file := entry.Name()
+		base := strings.TrimSuffix(file, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		sep := strings.Index(base, "_")
+		if sep < 0 || (direction != ".up" && direction != ".down") {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+		version, err := strconv.Atoi(base[:sep])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", file, err)
+		}
+
+		content, err := fs.ReadFile(scripts, path.Join(driver, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: base[sep+1:]}
+			byVersion[version] = m
+		}
+		if direction == ".up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration and returns the number of migrations applied.
+func Up(db *sqlx.DB) (int, error) {
+	statuses, err := Statuses(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, s := range statuses {
+		if s.Applied {
+			continue
+		}
+		log.Infof("Applying migration %04d_%s...", s.Version, s.Name)
+		if err := run(db, s.Up, insertAppliedQuery, s.Version, s.Name, time.Now().UTC()); err != nil {
+			return count, fmt.Errorf("migration %04d_%s failed: %w", s.Version, s.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down reverts the last steps applied migrations and returns the number of migrations reverted.
+func Down(db *sqlx.DB, steps int) (int, error) {
+	statuses, err := Statuses(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(statuses) - 1; i >= 0 && count < steps; i-- {
+		s := statuses[i]
+		if !s.Applied {
+			continue
+		}
+		if s.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s can not be reverted", s.Version, s.Name)
+		}
+		log.Infof("Reverting migration %04d_%s...", s.Version, s.Name)
+		if err := run(db, s.Down, deleteAppliedQuery, s.Version); err != nil {
+			return count, fmt.Errorf("revert of migration %04d_%s failed: %w", s.Version, s.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Statuses returns every known migration along with its applied state.
+func Statuses(db *sqlx.DB) ([]Status, error) {
+	migrations, err := Load(db.DriverName())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(createTableQuery); err != nil {
+		return nil, fmt.Errorf("could not create migrations table: %w", err)
+	}
+
+	var rows []applied
+	if err := db.Select(&rows, selectAppliedQuery); err != nil {
+		return nil, fmt.Errorf("could not retrieve applied migrations: %w", err)
+	}
+	appliedAt := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		at, ok := appliedAt[m.Version]
+		statuses[i] = Status{Migration: m, Applied: ok, AppliedAt: at}
+	}
+
+	return statuses, nil
+}
+
+// run executes the script then records it with the bookkeeping query, in a single transaction
+// when the database supports transactional DDL.
+func run(db *sqlx.DB, script string, query string, args ...interface{}) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,263 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+func TestLoad(t *testing.T) {
+	reference, err := Load("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, driver := range []string{"mysql", "postgres", "sqlite"} {
+		migrations, err := Load(driver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(migrations) != len(reference) {
+			t.Errorf("%s has %d migrations, mysql has %d", driver, len(migrations), len(reference))
+			continue
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 || m.Name != reference[i].Name {
+				t.Errorf("%s migration %d is %04d_%s, mysql has %04d_%s",
+					driver, i+1, m.Version, m.Name, reference[i].Version, reference[i].Name)
+			}
+			if m.Down == "" {
+				t.Errorf("%s migration %04d_%s has no down script", driver, m.Version, m.Name)
+			}
+		}
+	}
+}
+
+func TestRoundTripSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cardinal.db")
+	db, err := sqlx.Connect("sqlite", "file:"+path+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	testRoundTrip(t, db)
+}
+
+// TestRoundTripMySQL runs against the server of CARDINAL_TEST_MYSQL_DSN, in a database of its own.
+// MySQL commits each DDL statement on its own, so the scripts are not applied atomically.
+func TestRoundTripMySQL(t *testing.T) {
+	dsn := os.Getenv("CARDINAL_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("CARDINAL_TEST_MYSQL_DSN is not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ParseTime, cfg.MultiStatements = true, true
+	admin, err := sqlx.Connect("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	cfg.DBName = fmt.Sprintf("cardinal_migrations_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE DATABASE " + cfg.DBName); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec("DROP DATABASE " + cfg.DBName)
+
+	db, err := sqlx.Connect("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testRoundTrip(t, db)
+}
+
+// TestRoundTripPostgres runs against the database of CARDINAL_TEST_POSTGRES_DSN, in a schema of its own.
+func TestRoundTripPostgres(t *testing.T) {
+	dsn := os.Getenv("CARDINAL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CARDINAL_TEST_POSTGRES_DSN is not set")
+	}
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	schema := fmt.Sprintf("cardinal_migrations_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+
+	sep := "?"
+	if !strings.Contains(dsn, "://") {
+		sep = " "
+	} else if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := sqlx.Connect("postgres", dsn+sep+"search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testRoundTrip(t, db)
+}
+
+// testRoundTrip applies the migrations one by one, checking that reverting each of them
+// restores the schema it was applied to, and that it applies again afterwards.
+func testRoundTrip(t *testing.T, db *sqlx.DB) {
+	// Creates the schema_migrations table, kept by every migration.
+	statuses, err := Statuses(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := schema(t, db)
+	for _, m := range statuses {
+		if err := upTo(db, m.Version); err != nil {
+			t.Fatal(err)
+		}
+		after := schema(t, db)
+
+		if _, err := Down(db, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got := schema(t, db); !reflect.DeepEqual(got, before) {
+			t.Errorf("reverting %04d_%s does not restore the schema:\n%s", m.Version, m.Name, diff(before, got))
+		}
+		if err := upTo(db, m.Version); err != nil {
+			t.Fatalf("applying %04d_%s once reverted: %v", m.Version, m.Name, err)
+		}
+		if got := schema(t, db); !reflect.DeepEqual(got, after) {
+			t.Errorf("applying %04d_%s once reverted changed the schema:\n%s", m.Version, m.Name, diff(after, got))
+		}
+		before = after
+	}
+
+	if applied, err := Up(db); err != nil || applied != 0 {
+		t.Errorf("Up applied %d migrations once every migration was applied: %v", applied, err)
+	}
+	if _, err := Down(db, len(statuses)); err != nil {
+		t.Fatal(err)
+	}
+	for _, object := range schema(t, db) {
+		if !strings.Contains(object, "schema_migrations") {
+			t.Errorf("reverting every migration left %s", object)
+		}
+	}
+}
+
+// upTo applies the pending migrations up to the version.
+func upTo(db *sqlx.DB, version int) error {
+	statuses, err := Statuses(db)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if s.Applied || s.Version > version {
+			continue
+		}
+		if err := run(db, s.Up, insertAppliedQuery, s.Version, s.Name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("migration %04d_%s failed: %w", s.Version, s.Name, err)
+		}
+	}
+	return nil
+}
+
+// schema describes the tables, columns, indexes and constraints of the database, sorted.
+func schema(t *testing.T, db *sqlx.DB) []string {
+	t.Helper()
+	var queries []string
+	switch db.DriverName() {
+	case "mysql":
+		queries = []string{`
+			SELECT CONCAT(table_name, ' column ', column_name, ' ', column_type, ' nullable=', is_nullable,
+				' default=', COALESCE(column_default, 'NULL'))
+			FROM information_schema.columns WHERE table_schema=DATABASE()`, `
+			SELECT CONCAT(table_name, ' index ', index_name, ' unique=', 1 - non_unique, ' on ',
+				GROUP_CONCAT(column_name ORDER BY seq_in_index))
+			FROM information_schema.statistics WHERE table_schema=DATABASE()
+			GROUP BY table_name, index_name, non_unique`, `
+			SELECT CONCAT(k.table_name, ' references ', k.referenced_table_name, ' ', k.column_name, '->',
+				k.referenced_column_name, ' on delete ', r.delete_rule)
+			FROM information_schema.key_column_usage k
+			JOIN information_schema.referential_constraints r
+				ON r.constraint_schema=k.constraint_schema AND r.constraint_name=k.constraint_name
+			WHERE k.table_schema=DATABASE() AND k.referenced_table_name IS NOT NULL`,
+		}
+	case "sqlite":
+		queries = []string{`
+			SELECT m.name || ' column ' || c.name || ' ' || c.type || ' notnull=' || c."notnull" ||
+				' default=' || COALESCE(c.dflt_value, 'NULL') || ' pk=' || c.pk
+			FROM sqlite_master m, pragma_table_info(m.name) c WHERE m.type='table' AND m.name NOT LIKE 'sqlite_%'`, `
+			SELECT m.name || ' index ' || i.name || ' unique=' || i."unique" || ' on ' ||
+				(SELECT group_concat(COALESCE(ii.name, 'expr'), ',') FROM pragma_index_info(i.name) ii)
+			FROM sqlite_master m, pragma_index_list(m.name) i WHERE m.type='table' AND m.name NOT LIKE 'sqlite_%'`, `
+			SELECT m.name || ' references ' || f."table" || ' ' || f."from" || '->' || COALESCE(f."to", '') ||
+				' on delete ' || f.on_delete
+			FROM sqlite_master m, pragma_foreign_key_list(m.name) f WHERE m.type='table' AND m.name NOT LIKE 'sqlite_%'`,
+		}
+	case "postgres":
+		queries = []string{`
+			SELECT table_name || ' column ' || column_name || ' ' || data_type || ' nullable=' || is_nullable ||
+				' default=' || COALESCE(column_default, 'NULL')
+			FROM information_schema.columns WHERE table_schema=current_schema()`, `
+			SELECT tablename || ' index ' || indexname || ' ' || indexdef
+			FROM pg_indexes WHERE schemaname=current_schema()`, `
+			SELECT conrelid::regclass || ' constraint ' || conname || ' ' || pg_get_constraintdef(oid)
+			FROM pg_constraint WHERE connamespace=current_schema()::regnamespace`,
+		}
+	default:
+		t.Fatalf("no schema description for driver %s", db.DriverName())
+	}
+
+	objects := []string{}
+	for _, query := range queries {
+		var rows []string
+		if err := db.Select(&rows, query+" ORDER BY 1"); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, rows...)
+	}
+	return objects
+}
+
+// diff lists the objects missing from got or added to it.
+func diff(want []string, got []string) string {
+	in := func(objects []string, object string) bool {
+		for _, o := range objects {
+			if o == object {
+				return true
+			}
+		}
+		return false
+	}
+	var b strings.Builder
+	for _, object := range want {
+		if !in(got, object) {
+			b.WriteString("- " + object + "\n")
+		}
+	}
+	for _, object := range got {
+		if !in(want, object) {
+			b.WriteString("+ " + object + "\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS user;
+DROP TABLE IF EXISTS channel;
+DROP TABLE IF EXISTS role;
+DROP TABLE IF EXISTS ban;
+DROP TABLE IF EXISTS warn;
+DROP TABLE IF EXISTS member;
+DROP TABLE IF EXISTS guild;
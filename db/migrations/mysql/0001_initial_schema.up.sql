@@ -0,0 +1,85 @@
+CREATE TABLE IF NOT EXISTS guild (
+	guild_id            VARCHAR(21)  NOT NULL,
+	guild_name          VARCHAR(100) NOT NULL DEFAULT '',
+	prefix              VARCHAR(10)  NOT NULL DEFAULT '!',
+	report_channel      VARCHAR(21)  NULL DEFAULT NULL,
+	welcome_channel     VARCHAR(21)  NULL DEFAULT NULL,
+	welcome_message     TEXT         NULL DEFAULT NULL,
+	private_welcome_msg TEXT         NULL DEFAULT NULL,
+	level_channel       VARCHAR(21)  NULL DEFAULT NULL,
+	level_replace       BOOLEAN      NOT NULL DEFAULT FALSE,
+	level_response      INT          NOT NULL DEFAULT 1,
+	disabled_commands   TEXT         NULL DEFAULT NULL,
+	allow_moderation    BOOLEAN      NOT NULL DEFAULT TRUE,
+	max_warns           INT          NOT NULL DEFAULT 3,
+	ban_time            INT          NOT NULL DEFAULT 0,
+	PRIMARY KEY (guild_id)
+);
+
+CREATE TABLE IF NOT EXISTS member (
+	member_id VARCHAR(21) NOT NULL,
+	guild_id  VARCHAR(21) NOT NULL,
+	joined_at DATETIME    NULL DEFAULT NULL,
+	`left`    INT         NOT NULL DEFAULT 0,
+	xp        INT         NOT NULL DEFAULT 0,
+	level     INT         NOT NULL DEFAULT 0,
+	PRIMARY KEY (guild_id, member_id),
+	CONSTRAINT fk_member_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS warn (
+	warn_id     INT         NOT NULL AUTO_INCREMENT,
+	member_id   VARCHAR(21) NOT NULL,
+	guild_id    VARCHAR(21) NOT NULL,
+	warner_id   VARCHAR(21) NULL DEFAULT NULL,
+	warned_at   DATETIME    NOT NULL,
+	warn_reason TEXT        NULL DEFAULT NULL,
+	PRIMARY KEY (warn_id),
+	INDEX idx_warn_member (guild_id, member_id),
+	CONSTRAINT fk_warn_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS ban (
+	ban_id     INT         NOT NULL AUTO_INCREMENT,
+	member_id  VARCHAR(21) NOT NULL,
+	guild_id   VARCHAR(21) NOT NULL,
+	banner_id  VARCHAR(21) NULL DEFAULT NULL,
+	banned_at  DATETIME    NOT NULL,
+	ban_reason TEXT        NULL DEFAULT NULL,
+	auto_ban   BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (ban_id),
+	INDEX idx_ban_member (guild_id, member_id),
+	CONSTRAINT fk_ban_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS role (
+	role_id        VARCHAR(21) NOT NULL,
+	guild_id       VARCHAR(21) NOT NULL,
+	is_default     BOOLEAN     NOT NULL DEFAULT FALSE,
+	ignored        BOOLEAN     NOT NULL DEFAULT FALSE,
+	reward         INT         NOT NULL DEFAULT 0,
+	xp_blacklisted BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guild_id, role_id),
+	CONSTRAINT fk_role_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS channel (
+	channel_id     VARCHAR(21) NOT NULL,
+	guild_id       VARCHAR(21) NOT NULL,
+	ignored        BOOLEAN     NOT NULL DEFAULT FALSE,
+	xp_blacklisted BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guild_id, channel_id),
+	CONSTRAINT fk_channel_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user (
+	username   VARCHAR(20)  NOT NULL,
+	email      VARCHAR(80)  NOT NULL,
+	discord_id VARCHAR(21)  NULL DEFAULT NULL,
+	pwd_hash   VARCHAR(255) NOT NULL,
+	salt       VARCHAR(64)  NOT NULL DEFAULT '',
+	access_lvl TINYINT      NOT NULL DEFAULT 2,
+	created_at DATETIME     NOT NULL,
+	banned     BOOLEAN      NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (username)
+);
@@ -0,0 +1,12 @@
+ALTER TABLE user ADD COLUMN token_version INT NOT NULL DEFAULT 0;
+
+CREATE TABLE refresh_token (
+	token_hash CHAR(64)    NOT NULL,
+	username   VARCHAR(20) NOT NULL,
+	created_at DATETIME    NOT NULL,
+	expires_at DATETIME    NOT NULL,
+	revoked_at DATETIME    NULL DEFAULT NULL,
+	PRIMARY KEY (token_hash),
+	INDEX idx_refresh_token_user (username),
+	CONSTRAINT fk_refresh_token_user FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
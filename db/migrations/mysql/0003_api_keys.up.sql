@@ -0,0 +1,15 @@
+CREATE TABLE api_key (
+	key_id       INT          NOT NULL AUTO_INCREMENT,
+	username     VARCHAR(20)  NOT NULL,
+	name         VARCHAR(50)  NOT NULL,
+	prefix       VARCHAR(12)  NOT NULL,
+	key_hash     CHAR(64)     NOT NULL,
+	scopes       VARCHAR(255) NOT NULL,
+	created_at   DATETIME     NOT NULL,
+	expires_at   DATETIME     NULL DEFAULT NULL,
+	last_used_at DATETIME     NULL DEFAULT NULL,
+	PRIMARY KEY (key_id),
+	UNIQUE INDEX idx_api_key_hash (key_hash),
+	INDEX idx_api_key_user (username),
+	CONSTRAINT fk_api_key_user FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
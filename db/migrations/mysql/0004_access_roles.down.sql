@@ -0,0 +1,4 @@
+UPDATE api_key SET roles = CASE WHEN roles LIKE '%editor%' OR roles LIKE '%admin%' THEN 'read write' ELSE 'read' END;
+ALTER TABLE api_key CHANGE roles scopes VARCHAR(255) NOT NULL;
+
+ALTER TABLE user DROP COLUMN roles;
@@ -0,0 +1,10 @@
+CREATE TABLE guild_access (
+	guild_id   VARCHAR(21) NOT NULL,
+	username   VARCHAR(20) NOT NULL,
+	access     VARCHAR(10) NOT NULL,
+	granted_at DATETIME    NOT NULL,
+	PRIMARY KEY (guild_id, username),
+	INDEX idx_guild_access_user (username),
+	CONSTRAINT fk_guild_access_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE,
+	CONSTRAINT fk_guild_access_user FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user ADD COLUMN discord_verified BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_user_discord_id ON user (discord_id);
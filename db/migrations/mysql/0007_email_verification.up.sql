@@ -0,0 +1,15 @@
+ALTER TABLE user ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+-- Existing accounts are not restricted.
+UPDATE user SET email_verified = TRUE;
+
+CREATE TABLE user_token (
+	token_hash CHAR(64)    NOT NULL,
+	username   VARCHAR(20) NOT NULL,
+	purpose    VARCHAR(20) NOT NULL,
+	created_at DATETIME    NOT NULL,
+	expires_at DATETIME    NOT NULL,
+	used_at    DATETIME    NULL DEFAULT NULL,
+	PRIMARY KEY (token_hash),
+	INDEX idx_user_token_user (username),
+	CONSTRAINT fk_user_token_user FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
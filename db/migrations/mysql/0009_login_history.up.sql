@@ -0,0 +1,13 @@
+CREATE TABLE login_history (
+	attempt_id INT          NOT NULL AUTO_INCREMENT,
+	username   VARCHAR(20)  NOT NULL,
+	ip         VARCHAR(45)  NOT NULL,
+	user_agent VARCHAR(255) NOT NULL,
+	method     VARCHAR(10)  NOT NULL,
+	success    BOOLEAN      NOT NULL,
+	reason     VARCHAR(20)  NOT NULL,
+	created_at DATETIME     NOT NULL,
+	PRIMARY KEY (attempt_id),
+	INDEX idx_login_history_user (username),
+	INDEX idx_login_history_ip (ip)
+);
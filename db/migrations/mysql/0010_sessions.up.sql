@@ -0,0 +1,17 @@
+CREATE TABLE user_session (
+	session_id   INT          NOT NULL AUTO_INCREMENT,
+	username     VARCHAR(20)  NOT NULL,
+	ip           VARCHAR(45)  NOT NULL,
+	user_agent   VARCHAR(255) NOT NULL,
+	created_at   DATETIME     NOT NULL,
+	last_used_at DATETIME     NOT NULL,
+	expires_at   DATETIME     NOT NULL,
+	revoked_at   DATETIME     NULL DEFAULT NULL,
+	PRIMARY KEY (session_id),
+	INDEX idx_user_session_user (username),
+	CONSTRAINT fk_user_session_user FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
+
+-- Refresh tokens issued before sessions have none.
+ALTER TABLE refresh_token ADD COLUMN session_id INT NULL DEFAULT NULL;
+CREATE INDEX idx_refresh_token_session ON refresh_token (session_id);
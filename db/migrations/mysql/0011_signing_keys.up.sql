@@ -0,0 +1,7 @@
+CREATE TABLE signing_key (
+	key_id      VARCHAR(32) NOT NULL,
+	algorithm   VARCHAR(10) NOT NULL,
+	private_key TEXT        NOT NULL,
+	created_at  DATETIME    NOT NULL,
+	PRIMARY KEY (key_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE guild ADD COLUMN level_curve TEXT NULL DEFAULT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE member DROP COLUMN last_xp_at;
+ALTER TABLE guild DROP COLUMN xp_cooldown;
@@ -0,0 +1 @@
+ALTER TABLE ban DROP COLUMN expires_at;
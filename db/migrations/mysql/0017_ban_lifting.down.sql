@@ -0,0 +1,4 @@
+DROP INDEX idx_ban_expiry ON ban;
+ALTER TABLE ban DROP COLUMN unbanned_at;
+ALTER TABLE ban DROP COLUMN lifted_by;
+ALTER TABLE ban DROP COLUMN lifted_at;
@@ -0,0 +1,40 @@
+CREATE TABLE mod_case (
+	case_id      INT         NOT NULL AUTO_INCREMENT,
+	guild_id     VARCHAR(21) NOT NULL,
+	case_number  INT         NOT NULL,
+	case_type    VARCHAR(10) NOT NULL,
+	member_id    VARCHAR(21) NOT NULL,
+	moderator_id VARCHAR(21) NULL DEFAULT NULL,
+	reason       TEXT        NULL DEFAULT NULL,
+	created_at   DATETIME    NOT NULL,
+	updated_at   DATETIME    NULL DEFAULT NULL,
+	expires_at   DATETIME    NULL DEFAULT NULL,
+	warn_id      INT         NULL DEFAULT NULL,
+	ban_id       INT         NULL DEFAULT NULL,
+	PRIMARY KEY (case_id),
+	UNIQUE INDEX idx_mod_case_number (guild_id, case_number),
+	INDEX idx_mod_case_member (guild_id, member_id),
+	INDEX idx_mod_case_moderator (guild_id, moderator_id),
+	CONSTRAINT fk_mod_case_guild FOREIGN KEY (guild_id) REFERENCES guild (guild_id) ON DELETE CASCADE,
+	CONSTRAINT fk_mod_case_warn FOREIGN KEY (warn_id) REFERENCES warn (warn_id) ON DELETE SET NULL,
+	CONSTRAINT fk_mod_case_ban FOREIGN KEY (ban_id) REFERENCES ban (ban_id) ON DELETE SET NULL
+);
+
+-- Existing warns, bans and lifted bans become the first cases of their guild.
+INSERT INTO mod_case
+	(guild_id, case_number, case_type, member_id, moderator_id, reason, created_at, expires_at, warn_id, ban_id)
+SELECT
+	guild_id,
+	ROW_NUMBER() OVER (PARTITION BY guild_id ORDER BY created_at, ordering, source_id),
+	case_type, member_id, moderator_id, reason, created_at, expires_at, warn_id, ban_id
+FROM (
+	SELECT guild_id, 'warn' AS case_type, member_id, warner_id AS moderator_id, warn_reason AS reason,
+		warned_at AS created_at, NULL AS expires_at, warn_id, NULL AS ban_id, 0 AS ordering, warn_id AS source_id
+	FROM warn
+	UNION ALL
+	SELECT guild_id, 'ban', member_id, banner_id, ban_reason, banned_at, expires_at, NULL, ban_id, 1, ban_id
+	FROM ban
+	UNION ALL
+	SELECT guild_id, 'unban', member_id, lifted_by, NULL, lifted_at, NULL, NULL, ban_id, 2, ban_id
+	FROM ban WHERE lifted_at IS NOT NULL
+) cases;
@@ -0,0 +1,76 @@
+CREATE TABLE IF NOT EXISTS guild (
+	guild_id            VARCHAR(21)  NOT NULL PRIMARY KEY,
+	guild_name          VARCHAR(100) NOT NULL DEFAULT '',
+	prefix              VARCHAR(10)  NOT NULL DEFAULT '!',
+	report_channel      VARCHAR(21)  NULL DEFAULT NULL,
+	welcome_channel     VARCHAR(21)  NULL DEFAULT NULL,
+	welcome_message     TEXT         NULL DEFAULT NULL,
+	private_welcome_msg TEXT         NULL DEFAULT NULL,
+	level_channel       VARCHAR(21)  NULL DEFAULT NULL,
+	level_replace       BOOLEAN      NOT NULL DEFAULT FALSE,
+	level_response      INTEGER      NOT NULL DEFAULT 1,
+	disabled_commands   TEXT         NULL DEFAULT NULL,
+	allow_moderation    BOOLEAN      NOT NULL DEFAULT TRUE,
+	max_warns           INTEGER      NOT NULL DEFAULT 3,
+	ban_time            INTEGER      NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS member (
+	member_id VARCHAR(21) NOT NULL,
+	guild_id  VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	joined_at TIMESTAMP   NULL DEFAULT NULL,
+	"left"    INTEGER     NOT NULL DEFAULT 0,
+	xp        INTEGER     NOT NULL DEFAULT 0,
+	level     INTEGER     NOT NULL DEFAULT 0,
+	PRIMARY KEY (guild_id, member_id)
+);
+
+CREATE TABLE IF NOT EXISTS warn (
+	warn_id     SERIAL      NOT NULL PRIMARY KEY,
+	member_id   VARCHAR(21) NOT NULL,
+	guild_id    VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	warner_id   VARCHAR(21) NULL DEFAULT NULL,
+	warned_at   TIMESTAMP   NOT NULL,
+	warn_reason TEXT        NULL DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_warn_member ON warn (guild_id, member_id);
+
+CREATE TABLE IF NOT EXISTS ban (
+	ban_id     SERIAL      NOT NULL PRIMARY KEY,
+	member_id  VARCHAR(21) NOT NULL,
+	guild_id   VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	banner_id  VARCHAR(21) NULL DEFAULT NULL,
+	banned_at  TIMESTAMP   NOT NULL,
+	ban_reason TEXT        NULL DEFAULT NULL,
+	auto_ban   BOOLEAN     NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS idx_ban_member ON ban (guild_id, member_id);
+
+CREATE TABLE IF NOT EXISTS role (
+	role_id        VARCHAR(21) NOT NULL,
+	guild_id       VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	is_default     BOOLEAN     NOT NULL DEFAULT FALSE,
+	ignored        BOOLEAN     NOT NULL DEFAULT FALSE,
+	reward         INTEGER     NOT NULL DEFAULT 0,
+	xp_blacklisted BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guild_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS channel (
+	channel_id     VARCHAR(21) NOT NULL,
+	guild_id       VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	ignored        BOOLEAN     NOT NULL DEFAULT FALSE,
+	xp_blacklisted BOOLEAN     NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (guild_id, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS "user" (
+	username   VARCHAR(20)  NOT NULL PRIMARY KEY,
+	email      VARCHAR(80)  NOT NULL,
+	discord_id VARCHAR(21)  NULL DEFAULT NULL,
+	pwd_hash   VARCHAR(255) NOT NULL,
+	salt       VARCHAR(64)  NOT NULL DEFAULT '',
+	access_lvl SMALLINT     NOT NULL DEFAULT 2,
+	created_at TIMESTAMP    NOT NULL,
+	banned     BOOLEAN      NOT NULL DEFAULT FALSE
+);
@@ -0,0 +1,2 @@
+DROP TABLE refresh_token;
+ALTER TABLE "user" DROP COLUMN token_version;
@@ -0,0 +1,10 @@
+ALTER TABLE "user" ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE refresh_token (
+	token_hash CHAR(64)    NOT NULL PRIMARY KEY,
+	username   VARCHAR(20) NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	created_at TIMESTAMP   NOT NULL,
+	expires_at TIMESTAMP   NOT NULL,
+	revoked_at TIMESTAMP   NULL DEFAULT NULL
+);
+CREATE INDEX idx_refresh_token_user ON refresh_token (username);
@@ -0,0 +1,12 @@
+CREATE TABLE api_key (
+	key_id       SERIAL       NOT NULL PRIMARY KEY,
+	username     VARCHAR(20)  NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	name         VARCHAR(50)  NOT NULL,
+	prefix       VARCHAR(12)  NOT NULL,
+	key_hash     CHAR(64)     NOT NULL UNIQUE,
+	scopes       VARCHAR(255) NOT NULL,
+	created_at   TIMESTAMP    NOT NULL,
+	expires_at   TIMESTAMP    NULL DEFAULT NULL,
+	last_used_at TIMESTAMP    NULL DEFAULT NULL
+);
+CREATE INDEX idx_api_key_user ON api_key (username);
@@ -0,0 +1,4 @@
+ALTER TABLE "user" ADD COLUMN roles VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE api_key RENAME COLUMN scopes TO roles;
+UPDATE api_key SET roles = CASE WHEN roles LIKE '%write%' THEN 'editor' ELSE 'viewer' END;
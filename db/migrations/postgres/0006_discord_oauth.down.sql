@@ -0,0 +1,2 @@
+DROP INDEX idx_user_discord_id;
+ALTER TABLE "user" DROP COLUMN discord_verified;
@@ -0,0 +1,13 @@
+ALTER TABLE "user" ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+-- Existing accounts are not restricted.
+UPDATE "user" SET email_verified = TRUE;
+
+CREATE TABLE user_token (
+	token_hash CHAR(64)    NOT NULL PRIMARY KEY,
+	username   VARCHAR(20) NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	purpose    VARCHAR(20) NOT NULL,
+	created_at TIMESTAMP   NOT NULL,
+	expires_at TIMESTAMP   NOT NULL,
+	used_at    TIMESTAMP   NULL DEFAULT NULL
+);
+CREATE INDEX idx_user_token_user ON user_token (username);
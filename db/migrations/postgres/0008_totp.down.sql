@@ -0,0 +1,4 @@
+DROP TABLE recovery_code;
+ALTER TABLE "user" DROP COLUMN totp_last_step;
+ALTER TABLE "user" DROP COLUMN totp_enabled;
+ALTER TABLE "user" DROP COLUMN totp_secret;
@@ -0,0 +1,10 @@
+ALTER TABLE "user" ADD COLUMN totp_secret VARCHAR(64) NULL DEFAULT NULL;
+ALTER TABLE "user" ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE "user" ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_code (
+	username  VARCHAR(20) NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	code_hash CHAR(64)    NOT NULL,
+	used_at   TIMESTAMP   NULL DEFAULT NULL,
+	PRIMARY KEY (username, code_hash)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE user_session (
+	session_id   SERIAL       NOT NULL PRIMARY KEY,
+	username     VARCHAR(20)  NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	ip           VARCHAR(45)  NOT NULL,
+	user_agent   VARCHAR(255) NOT NULL,
+	created_at   TIMESTAMP    NOT NULL,
+	last_used_at TIMESTAMP    NOT NULL,
+	expires_at   TIMESTAMP    NOT NULL,
+	revoked_at   TIMESTAMP    NULL DEFAULT NULL
+);
+CREATE INDEX idx_user_session_user ON user_session (username);
+
+-- Refresh tokens issued before sessions have none.
+ALTER TABLE refresh_token ADD COLUMN session_id INTEGER NULL DEFAULT NULL;
+CREATE INDEX idx_refresh_token_session ON refresh_token (session_id);
@@ -0,0 +1,6 @@
+CREATE TABLE signing_key (
+	key_id      VARCHAR(32) NOT NULL PRIMARY KEY,
+	algorithm   VARCHAR(10) NOT NULL,
+	private_key TEXT        NOT NULL,
+	created_at  TIMESTAMP   NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE guild ADD COLUMN xp_cooldown INT NOT NULL DEFAULT 0;
+ALTER TABLE member ADD COLUMN last_xp_at TIMESTAMP NULL DEFAULT NULL;
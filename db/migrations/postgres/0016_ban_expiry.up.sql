@@ -0,0 +1 @@
+ALTER TABLE ban ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL;
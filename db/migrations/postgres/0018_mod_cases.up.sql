@@ -0,0 +1,36 @@
+CREATE TABLE mod_case (
+	case_id      SERIAL      NOT NULL PRIMARY KEY,
+	guild_id     VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	case_number  INTEGER     NOT NULL,
+	case_type    VARCHAR(10) NOT NULL,
+	member_id    VARCHAR(21) NOT NULL,
+	moderator_id VARCHAR(21) NULL DEFAULT NULL,
+	reason       TEXT        NULL DEFAULT NULL,
+	created_at   TIMESTAMP   NOT NULL,
+	updated_at   TIMESTAMP   NULL DEFAULT NULL,
+	expires_at   TIMESTAMP   NULL DEFAULT NULL,
+	warn_id      INTEGER     NULL DEFAULT NULL REFERENCES warn (warn_id) ON DELETE SET NULL,
+	ban_id       INTEGER     NULL DEFAULT NULL REFERENCES ban (ban_id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX idx_mod_case_number ON mod_case (guild_id, case_number);
+CREATE INDEX idx_mod_case_member ON mod_case (guild_id, member_id);
+CREATE INDEX idx_mod_case_moderator ON mod_case (guild_id, moderator_id);
+
+-- Existing warns, bans and lifted bans become the first cases of their guild.
+INSERT INTO mod_case
+	(guild_id, case_number, case_type, member_id, moderator_id, reason, created_at, expires_at, warn_id, ban_id)
+SELECT
+	guild_id,
+	ROW_NUMBER() OVER (PARTITION BY guild_id ORDER BY created_at, ordering, source_id),
+	case_type, member_id, moderator_id, reason, created_at, expires_at, warn_id, ban_id
+FROM (
+	SELECT guild_id, 'warn' AS case_type, member_id, warner_id AS moderator_id, warn_reason AS reason,
+		warned_at AS created_at, CAST(NULL AS TIMESTAMP) AS expires_at, warn_id, CAST(NULL AS INTEGER) AS ban_id, 0 AS ordering, warn_id AS source_id
+	FROM warn
+	UNION ALL
+	SELECT guild_id, 'ban', member_id, banner_id, ban_reason, banned_at, expires_at, NULL, ban_id, 1, ban_id
+	FROM ban
+	UNION ALL
+	SELECT guild_id, 'unban', member_id, lifted_by, NULL, lifted_at, NULL, NULL, ban_id, 2, ban_id
+	FROM ban WHERE lifted_at IS NOT NULL
+) cases;
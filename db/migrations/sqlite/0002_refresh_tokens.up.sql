@@ -0,0 +1,10 @@
+ALTER TABLE "user" ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE refresh_token (
+	token_hash CHAR(64)    NOT NULL PRIMARY KEY,
+	username   VARCHAR(20) NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	created_at DATETIME    NOT NULL,
+	expires_at DATETIME    NOT NULL,
+	revoked_at DATETIME    NULL DEFAULT NULL
+);
+CREATE INDEX idx_refresh_token_user ON refresh_token (username);
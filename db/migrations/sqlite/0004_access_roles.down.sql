@@ -0,0 +1,4 @@
+UPDATE api_key SET roles = CASE WHEN roles LIKE '%editor%' OR roles LIKE '%admin%' THEN 'read write' ELSE 'read' END;
+ALTER TABLE api_key RENAME COLUMN roles TO scopes;
+
+ALTER TABLE "user" DROP COLUMN roles;
@@ -0,0 +1,8 @@
+CREATE TABLE guild_access (
+	guild_id   VARCHAR(21) NOT NULL REFERENCES guild (guild_id) ON DELETE CASCADE,
+	username   VARCHAR(20) NOT NULL REFERENCES "user" (username) ON DELETE CASCADE,
+	access     VARCHAR(10) NOT NULL,
+	granted_at DATETIME    NOT NULL,
+	PRIMARY KEY (guild_id, username)
+);
+CREATE INDEX idx_guild_access_user ON guild_access (username);
@@ -0,0 +1,12 @@
+CREATE TABLE login_history (
+	attempt_id INTEGER      NOT NULL PRIMARY KEY AUTOINCREMENT,
+	username   VARCHAR(20)  NOT NULL,
+	ip         VARCHAR(45)  NOT NULL,
+	user_agent VARCHAR(255) NOT NULL,
+	method     VARCHAR(10)  NOT NULL,
+	success    BOOLEAN      NOT NULL,
+	reason     VARCHAR(20)  NOT NULL,
+	created_at DATETIME     NOT NULL
+);
+CREATE INDEX idx_login_history_user ON login_history (username);
+CREATE INDEX idx_login_history_ip ON login_history (ip);
@@ -0,0 +1,3 @@
+DROP INDEX idx_refresh_token_session;
+ALTER TABLE refresh_token DROP COLUMN session_id;
+DROP TABLE user_session;
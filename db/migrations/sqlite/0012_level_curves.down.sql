@@ -0,0 +1 @@
+ALTER TABLE guild DROP COLUMN level_curve;
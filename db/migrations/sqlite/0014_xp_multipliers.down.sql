@@ -0,0 +1,3 @@
+ALTER TABLE guild DROP COLUMN xp_stacking;
+ALTER TABLE channel DROP COLUMN xp_multiplier;
+ALTER TABLE role DROP COLUMN xp_multiplier;
@@ -0,0 +1,3 @@
+ALTER TABLE role ADD COLUMN xp_multiplier REAL NOT NULL DEFAULT 1;
+ALTER TABLE channel ADD COLUMN xp_multiplier REAL NOT NULL DEFAULT 1;
+ALTER TABLE guild ADD COLUMN xp_stacking VARCHAR(10) NOT NULL DEFAULT 'max';
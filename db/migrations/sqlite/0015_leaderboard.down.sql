@@ -0,0 +1 @@
+DROP INDEX idx_member_xp;
@@ -0,0 +1 @@
+CREATE INDEX idx_member_xp ON member (guild_id, xp, member_id);
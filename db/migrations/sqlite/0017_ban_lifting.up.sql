@@ -0,0 +1,5 @@
+ALTER TABLE ban ADD COLUMN lifted_at DATETIME NULL DEFAULT NULL;
+ALTER TABLE ban ADD COLUMN lifted_by VARCHAR(21) NULL DEFAULT NULL;
+ALTER TABLE ban ADD COLUMN unbanned_at DATETIME NULL DEFAULT NULL;
+
+CREATE INDEX idx_ban_expiry ON ban (lifted_at, expires_at);
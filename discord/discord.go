@@ -0,0 +1,128 @@
+// Package discord implements the OAuth2 authorization code flow of Discord.
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the base url of the Discord api.
+const DefaultBaseURL = "https://discord.com/api"
+
+// Scopes requested to Discord.
+const Scopes = "identify email"
+
+type (
+	// Client exchanges authorization codes against the Discord account of the user.
+	Client struct {
+		BaseURL      string       // Base url of the api, without trailing slash
+		ClientID     string       // Id of the Discord application
+		ClientSecret string       // Secret of the Discord application
+		RedirectURI  string       // Callback url registered in the Discord application
+		HTTP         *http.Client // Client used for the requests
+	}
+
+	// User is the Discord account of the user.
+	User struct {
+		ID       string `json:"id"`       // Snowflake of the user
+		Username string `json:"username"` // Username of the user
+		Email    string `json:"email"`    // Email of the user, empty without the email scope
+		Verified bool   `json:"verified"` // Whether the email was verified by Discord
+	}
+
+	tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+)
+
+// New returns a client for the Discord application.
+func New(baseURL, clientID, clientSecret, redirectURI string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  redirectURI,
+		HTTP:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthorizeURL returns the url the user must be redirected to in order to grant access to its account.
+func (c *Client) AuthorizeURL(state string) string {
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {c.ClientID},
+		"scope":         {Scopes},
+		"redirect_uri":  {c.RedirectURI},
+		"state":         {state},
+		"prompt":        {"none"},
+	}
+	return c.BaseURL + "/oauth2/authorize?" + q.Encode()
+}
+
+// Exchange trades the authorization code for an access token.
+func (c *Client) Exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.RedirectURI},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	var token tokenResponse
+	if err := c.do(req, &token); err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token exchange failed: no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// CurrentUser returns the account of the user who granted the access token.
+func (c *Client) CurrentUser(ctx context.Context, accessToken string) (User, error) {
+	var user User
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/users/@me", nil)
+	if err != nil {
+		return user, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	if err := c.do(req, &user); err != nil {
+		return user, fmt.Errorf("could not get user: %w", err)
+	}
+	if user.ID == "" {
+		return user, fmt.Errorf("could not get user: no id")
+	}
+	return user, nil
+}
+
+// do sends the request and decodes the json response in v.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("discord responded %s: %s", res.Status, body)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,120 @@
+// Package leveling converts the xp of the members to levels,
+// following a curve which gives the total xp required to reach each level.
+package leveling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Types of curves.
+const (
+	Linear      = "linear"      // Every level costs Base xp
+	Quadratic   = "quadratic"   // Level n requires Base * n² xp
+	Exponential = "exponential" // Each level costs Factor times the previous one, the first one costing Base xp
+	Table       = "table"       // Level n requires Thresholds[n-1] xp
+)
+
+const (
+	// MaxLevel is the highest level of the formula curves.
+	MaxLevel = 1000
+	// maxXp is the highest xp stored in the database.
+	maxXp = math.MaxInt32
+)
+
+// Curve gives the total xp required to reach each level, level 0 requiring none.
+type Curve struct {
+	Type       string  `json:"type"`                 // linear, quadratic, exponential or table
+	Base       float64 `json:"base,omitempty"`       // Xp of the first level, formula curves only
+	Factor     float64 `json:"factor,omitempty"`     // Ratio between the costs of two consecutive levels, exponential curve only
+	Thresholds []int   `json:"thresholds,omitempty"` // Total xp of each level from level 1, table curve only
+}
+
+// Default is the curve of the guilds which did not choose one.
+var Default = Curve{Type: Quadratic, Base: 100}
+
+// Parse decodes and validates a curve encoded as JSON.
+func Parse(s string) (Curve, error) {
+	var c Curve
+	if err := json.Unmarshal([]byte(s), &c); err != nil {
+		return c, fmt.Errorf("invalid level curve: %w", err)
+	}
+	return c, c.Validate()
+}
+
+// Validate checks that the curve is well defined, every level requiring more xp than the previous one.
+func (c Curve) Validate() error {
+	switch c.Type {
+	case Linear, Quadratic, Exponential:
+		if c.Base < 1 || c.Base > maxXp {
+			return errors.New("level curve base must be between 1 and 2147483647")
+		}
+		if c.Type == Exponential && (c.Factor <= 1 || c.Factor > 10) {
+			return errors.New("exponential level curve factor must be greater than 1 and at most 10")
+		}
+	case Table:
+		if len(c.Thresholds) == 0 || len(c.Thresholds) > MaxLevel {
+			return fmt.Errorf("level curve table must have between 1 and %d thresholds", MaxLevel)
+		}
+		if !sort.IntsAreSorted(c.Thresholds) || c.Thresholds[0] <= 0 || c.Thresholds[len(c.Thresholds)-1] >= maxXp {
+			return errors.New("level curve thresholds must be positive and increasing")
+		}
+		for i := 1; i < len(c.Thresholds); i++ {
+			if c.Thresholds[i] == c.Thresholds[i-1] {
+				return errors.New("level curve thresholds must be positive and increasing")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown level curve type %q", c.Type)
+	}
+	return nil
+}
+
+// MaxLevel returns the highest level of the curve.
+func (c Curve) MaxLevel() int {
+	if c.Type == Table {
+		return len(c.Thresholds)
+	}
+	return MaxLevel
+}
+
+// Threshold returns the total xp required to reach the level,
+// capped to the highest xp for the levels which can not be reached.
+func (c Curve) Threshold(level int) int {
+	if level <= 0 {
+		return 0
+	}
+	if level > c.MaxLevel() {
+		return maxXp
+	}
+
+	var xp float64
+	n := float64(level)
+	switch c.Type {
+	case Linear:
+		xp = c.Base * n
+	case Quadratic:
+		xp = c.Base * n * n
+	case Exponential:
+		xp = c.Base * (math.Pow(c.Factor, n) - 1) / (c.Factor - 1)
+	case Table:
+		return c.Thresholds[level-1]
+	}
+	if xp >= maxXp {
+		return maxXp
+	}
+	return int(math.Round(xp))
+}
+
+// Level returns the level reached with the xp.
+func (c Curve) Level(xp int) int {
+	// The thresholds increase with the level, the first level requiring more than xp is searched.
+	// Thresholds capped to the highest xp are never reached.
+	return sort.Search(c.MaxLevel(), func(i int) bool {
+		t := c.Threshold(i + 1)
+		return t > xp || t == maxXp
+	})
+}
@@ -0,0 +1,134 @@
+package leveling
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	linear      = Curve{Type: Linear, Base: 100}
+	quadratic   = Curve{Type: Quadratic, Base: 100}
+	exponential = Curve{Type: Exponential, Base: 100, Factor: 2}
+	table       = Curve{Type: Table, Thresholds: []int{10, 50, 200}}
+)
+
+func TestThreshold(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve Curve
+		level int
+		want  int
+	}{
+		{"level 0", linear, 0, 0},
+		{"negative level", quadratic, -1, 0},
+		{"linear", linear, 3, 300},
+		{"quadratic", quadratic, 3, 900},
+		{"exponential first", exponential, 1, 100},
+		{"exponential", exponential, 3, 700},
+		{"table", table, 2, 50},
+		{"table last", table, 3, 200},
+		{"table beyond last", table, 4, math.MaxInt32},
+		{"beyond max level", linear, MaxLevel + 1, math.MaxInt32},
+		{"linear max level", linear, MaxLevel, 100 * MaxLevel},
+		{"quadratic max level", quadratic, MaxLevel, 100 * MaxLevel * MaxLevel},
+		{"quadratic capped", Curve{Type: Quadratic, Base: 1e4}, 464, math.MaxInt32},
+		{"exponential capped", exponential, 40, math.MaxInt32},
+		{"base capped", Curve{Type: Linear, Base: math.MaxInt32}, 2, math.MaxInt32},
+	}
+	for _, tt := range tests {
+		if got := tt.curve.Threshold(tt.level); got != tt.want {
+			t.Errorf("%s: Threshold(%d)=%d, want %d", tt.name, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve Curve
+		xp    int
+		want  int
+	}{
+		{"no xp", linear, 0, 0},
+		{"below first threshold", linear, 99, 0},
+		{"linear exact threshold", linear, 100, 1},
+		{"linear between thresholds", linear, 299, 2},
+		{"quadratic exact threshold", quadratic, 400, 2},
+		{"quadratic below threshold", quadratic, 399, 1},
+		{"exponential exact threshold", exponential, 700, 3},
+		{"exponential below threshold", exponential, 699, 2},
+		{"table exact threshold", table, 50, 2},
+		{"table below threshold", table, 49, 1},
+		{"table last threshold", table, 200, 3},
+		{"table beyond last threshold", table, math.MaxInt32, 3},
+		{"linear max level", linear, 100 * MaxLevel, MaxLevel},
+		{"linear beyond max level", linear, math.MaxInt32, MaxLevel},
+		// Levels capped to the highest xp are never reached, even with the highest xp.
+		{"quadratic highest xp", Curve{Type: Quadratic, Base: 1e4}, math.MaxInt32, 463},
+		{"linear capped", Curve{Type: Linear, Base: 1e9}, math.MaxInt32, 2},
+	}
+	for _, tt := range tests {
+		if got := tt.curve.Level(tt.xp); got != tt.want {
+			t.Errorf("%s: Level(%d)=%d, want %d", tt.name, tt.xp, got, tt.want)
+		}
+	}
+}
+
+func TestLevelFollowsThreshold(t *testing.T) {
+	for _, curve := range []Curve{linear, quadratic, exponential, table, Default} {
+		for level := 1; level <= curve.MaxLevel(); level++ {
+			xp := curve.Threshold(level)
+			if xp == math.MaxInt32 {
+				break
+			}
+			if got := curve.Level(xp); got != level {
+				t.Errorf("%s: Level(%d)=%d at the threshold of level %d", curve.Type, xp, got, level)
+			}
+			if got := curve.Level(xp - 1); got != level-1 {
+				t.Errorf("%s: Level(%d)=%d below the threshold of level %d", curve.Type, xp-1, got, level)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve Curve
+		valid bool
+	}{
+		{"linear", linear, true},
+		{"quadratic", quadratic, true},
+		{"exponential", exponential, true},
+		{"table", table, true},
+		{"unknown type", Curve{Type: "cubic", Base: 100}, false},
+		{"base below 1", Curve{Type: Linear, Base: 0.5}, false},
+		{"base above max xp", Curve{Type: Quadratic, Base: math.MaxInt32 + 1}, false},
+		{"exponential factor 1", Curve{Type: Exponential, Base: 100, Factor: 1}, false},
+		{"exponential factor above 10", Curve{Type: Exponential, Base: 100, Factor: 11}, false},
+		{"empty table", Curve{Type: Table}, false},
+		{"table too long", Curve{Type: Table, Thresholds: make([]int, MaxLevel+1)}, false},
+		{"table decreasing", Curve{Type: Table, Thresholds: []int{10, 5}}, false},
+		{"table repeated", Curve{Type: Table, Thresholds: []int{10, 10}}, false},
+		{"table zero", Curve{Type: Table, Thresholds: []int{0, 10}}, false},
+		{"table max xp", Curve{Type: Table, Thresholds: []int{10, math.MaxInt32}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.curve.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate()=%v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	c, err := Parse(`{"type":"exponential","base":100,"factor":1.5}`)
+	if err != nil || c.Type != Exponential || c.Base != 100 || c.Factor != 1.5 {
+		t.Errorf("Parse=%+v, %v", c, err)
+	}
+	if _, err := Parse(`{"type":"linear"}`); err == nil {
+		t.Error("Parse accepted a curve without base")
+	}
+	if _, err := Parse(`linear`); err == nil {
+		t.Error("Parse accepted invalid JSON")
+	}
+}
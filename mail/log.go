@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+)
+
+// Log writes the emails to the logs instead of sending them, for development.
+type Log struct{}
+
+func (Log) Send(to string, subject string, body string) error {
+	log.Infof("Mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// File appends the emails to a file instead of sending them, for development.
+type File struct {
+	Path string // Path of the file
+}
+
+var fileMu sync.Mutex
+
+func (f File) Send(to string, subject string, body string) error {
+	fileMu.Lock()
+	defer fileMu.Unlock()
+
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), to, subject, body)
+	return err
+}
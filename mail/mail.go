@@ -0,0 +1,53 @@
+// Package mail sends the emails of the api.
+package mail
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Mailer sends plain text emails.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// New returns the mailer with the given name, configured from the environment:
+//   - smtp: SMTP_HOST, SMTP_PORT (default 587), SMTP_USER, SMTP_PWD and MAIL_FROM
+//   - file: appends the emails to MAIL_FILE
+//   - log: writes the emails to the logs, tokens included, for development only
+func New(name string) (Mailer, error) {
+	switch name {
+	case "":
+		return nil, fmt.Errorf("MAILER must be set to smtp, file or log")
+	case "log":
+		return Log{}, nil
+	case "file":
+		path := os.Getenv("MAIL_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("MAIL_FILE must be set for the file mailer")
+		}
+		return File{Path: path}, nil
+	case "smtp":
+		port := 587
+		if v := os.Getenv("SMTP_PORT"); v != "" {
+			var err error
+			if port, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid SMTP_PORT %s", v)
+			}
+		}
+		s := SMTP{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PWD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+		if s.Host == "" || s.From == "" {
+			return nil, fmt.Errorf("SMTP_HOST and MAIL_FROM must be set for the smtp mailer")
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %s", name)
+	}
+}
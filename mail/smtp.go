@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP sends the emails through an SMTP server, with STARTTLS when the server supports it.
+type SMTP struct {
+	Host     string // Host of the server
+	Port     int    // Port of the server
+	Username string // Username to authenticate with, no authentication if empty
+	Password string // Password to authenticate with
+	From     string // Sender address
+}
+
+func (s SMTP) Send(to string, subject string, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient %q", to)
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + s.From,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		strings.ReplaceAll(body, "\n", "\r\n"),
+	}, "\r\n")
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	return smtp.SendMail(addr, auth, s.From, []string{to}, []byte(msg))
+}
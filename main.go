@@ -1,8 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+
 	"github.com/gyroskan/cardinal/api"
 	"github.com/gyroskan/cardinal/db"
+	"github.com/gyroskan/cardinal/db/migrations"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/gyroskan/cardinal/store/memstore"
+	"github.com/gyroskan/cardinal/store/sqlstore"
 	"github.com/joho/godotenv"
 	"github.com/labstack/gommon/log"
 )
@@ -12,6 +20,82 @@ func main() {
 		log.Warn("Error loading .env file", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+
+	api.Run(openStore())
+	db.Close()
+}
+
+// openStore returns the storage selected by the DB_DRIVER setting.
+func openStore() *store.Store {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		log.Warn("Using in-memory storage, every data will be lost on exit.")
+		return memstore.New()
+	}
+
+	db.Connect()
+
+	if autoMigrate, _ := strconv.ParseBool(os.Getenv("DB_MIGRATE")); autoMigrate {
+		n, err := migrations.Up(db.DB)
+		if err != nil {
+			log.Fatal("Could not migrate database: ", err)
+		}
+		log.Infof("Applied %d migration(s).", n)
+	}
+
+	return sqlstore.New(db.DB)
+}
+
+// migrate handles the `cardinal migrate up|down [steps]|status` subcommand.
+func migrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: cardinal migrate up|down [steps]|status")
+		os.Exit(2)
+	}
+	if os.Getenv("DB_DRIVER") == "memory" {
+		log.Fatal("The memory driver has no schema to migrate.")
+	}
+
 	db.Connect()
-	api.Run()
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		n, err := migrations.Up(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Applied %d migration(s).\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal("Invalid number of steps: ", args[1])
+			}
+		}
+		n, err := migrations.Down(db.DB, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Reverted %d migration(s).\n", n)
+	case "status":
+		statuses, err := migrations.Statuses(db.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "unknown migrate command: "+args[0])
+		os.Exit(2)
+	}
 }
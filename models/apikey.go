@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	ApiKeyPrefix = "cdl_"
+)
+
+const (
+	SelectUserApiKeysQuery = "SELECT * FROM api_key WHERE username=? ORDER BY key_id ASC"
+	SelectApiKeyQuery      = "SELECT * FROM api_key WHERE key_hash=?"
+	InsertApiKeyQuery      = `
+		INSERT INTO api_key
+			(username, name, prefix, key_hash, roles, created_at, expires_at)
+		VALUES
+			(:username, :name, :prefix, :key_hash, :roles, :created_at, :expires_at)
+	`
+	UpdateApiKeyUsedQuery = "UPDATE api_key SET last_used_at=? WHERE key_id=?"
+	DeleteApiKeyQuery     = "DELETE FROM api_key WHERE username=? AND key_id=?"
+)
+
+type (
+	ApiKey struct {
+		KeyID      int               `json:"keyID" db:"key_id"`                               // ID of the key
+		Username   string            `json:"username" db:"username"`                          // Username of the key owner
+		Name       string            `json:"name" db:"name"`                                  // Name given to the key
+		Prefix     string            `json:"prefix" db:"prefix"`                              // First characters of the key, to identify it
+		KeyHash    string            `json:"-" db:"key_hash"`                                 // SHA-256 of the key, the key itself is never stored
+		Roles      string            `json:"roles" db:"roles"`                                // Roles granted to the key, separated by spaces
+		CreatedAt  time.Time         `json:"createdAt" db:"created_at"`                       // Date the key was created
+		ExpiresAt  nulltype.NullTime `json:"expiresAt" db:"expires_at" format:"date-time"`    // Date the key expires, never if null
+		LastUsedAt nulltype.NullTime `json:"lastUsedAt" db:"last_used_at" format:"date-time"` // Date the key was last used
+		Key        string            `json:"key,omitempty" db:"-"`                            // The key, only returned on creation
+	}
+
+	ApiKeyCreation struct {
+		Name      string            `json:"name"`                         // Name given to the key
+		Roles     string            `json:"roles"`                        // Roles granted to the key, separated by spaces
+		ExpiresAt nulltype.NullTime `json:"expiresAt" format:"date-time"` // Date the key expires, never if null
+	}
+)
+
+// Permissions returns the permissions granted by the roles of the key.
+// They are further restricted to the permissions of the key owner.
+func (k *ApiKey) Permissions() PermissionSet {
+	return RolesPermissions(strings.Fields(k.Roles)...)
+}
+
+// Validate ApiKeyCreation fields
+func (k *ApiKeyCreation) Validate() error {
+	k.Name = strings.TrimSpace(k.Name)
+	if k.Name == "" {
+		return errors.New("name empty")
+	}
+	if len(k.Name) > 50 {
+		return errors.New("name too long")
+	}
+
+	roles, err := ValidateRoles(k.Roles)
+	if err != nil {
+		return err
+	}
+	if roles == "" {
+		return errors.New("roles empty")
+	}
+	k.Roles = roles
+
+	if k.ExpiresAt.Valid() && k.ExpiresAt.TimeValue().Before(time.Now()) {
+		return errors.New("expiresAt in the past")
+	}
+
+	return nil
+}
@@ -7,22 +7,49 @@ import (
 )
 
 const (
-	CreateBanQuery = `
+	SelectMemberBansQuery = "SELECT * FROM ban WHERE guild_id=? AND member_id=?"
+	SelectBanQuery        = "SELECT * FROM ban WHERE guild_id=? AND member_id=? AND ban_id=?"
+	DeleteBanQuery        = "DELETE FROM ban WHERE guild_id=? AND member_id=? AND ban_id=?"
+	CreateBanQuery        = `
 		INSERT INTO ban 
-			(member_id, guild_id, banner_id, banned_at, ban_reason, auto_ban)
+			(member_id, guild_id, banner_id, banned_at, ban_reason, auto_ban, expires_at)
 		VALUES
-			(:member_id, :guild_id, :banner_id, :banned_at, :ban_reason, :auto_ban)
+			(:member_id, :guild_id, :banner_id, :banned_at, :ban_reason, :auto_ban, :expires_at)
+	`
+	LiftBanQuery = `
+		UPDATE ban SET
+			lifted_at=?, lifted_by=?
+		WHERE
+			guild_id=? AND member_id=? AND ban_id=? AND lifted_at IS NULL
+	`
+	SelectExpiredBansQuery   = "SELECT * FROM ban WHERE lifted_at IS NULL AND expires_at <= ?"
+	LiftExpiredBanQuery      = "UPDATE ban SET lifted_at=? WHERE ban_id=? AND lifted_at IS NULL"
+	SelectPendingUnbansQuery = `
+		SELECT * FROM ban
+		WHERE guild_id=? AND lifted_at IS NOT NULL AND unbanned_at IS NULL
+		ORDER BY lifted_at ASC, ban_id ASC
+		LIMIT ?
+	`
+	SetUnbannedQuery = `
+		UPDATE ban SET
+			unbanned_at=?
+		WHERE
+			guild_id=? AND ban_id=? AND lifted_at IS NOT NULL AND unbanned_at IS NULL
 	`
 )
 
 type (
 	Ban struct {
-		BanID     int                 `json:"banID" db:"ban_id"`         // ID of the ban
-		MemberID  string              `json:"memberID" db:"member_id"`   // ID of the member
-		GuildID   string              `json:"guildID" db:"guild_id"`     // ID of the guild
-		BannerID  nulltype.NullString `json:"bannerID" db:"banner_id"`   // ID of the user who banned the member
-		BannedAt  time.Time           `json:"bannedAt" db:"banned_at"`   // Date the member was banned
-		BanReason nulltype.NullString `json:"banReason" db:"ban_reason"` // Reason for the ban
-		AutoBan   bool                `json:"autoBan" db:"auto_ban"`     // Whether the ban was automatic or not
+		BanID      int                 `json:"banID" db:"ban_id"`                              // ID of the ban
+		MemberID   string              `json:"memberID" db:"member_id"`                        // ID of the member
+		GuildID    string              `json:"guildID" db:"guild_id"`                          // ID of the guild
+		BannerID   nulltype.NullString `json:"bannerID" db:"banner_id"`                        // ID of the user who banned the member
+		BannedAt   time.Time           `json:"bannedAt" db:"banned_at"`                        // Date the member was banned
+		BanReason  nulltype.NullString `json:"banReason" db:"ban_reason"`                      // Reason for the ban
+		AutoBan    bool                `json:"autoBan" db:"auto_ban"`                          // Whether the ban was automatic or not
+		ExpiresAt  nulltype.NullTime   `json:"expiresAt" db:"expires_at" format:"date-time"`   // Date the ban ends, null for a permanent ban
+		LiftedAt   nulltype.NullTime   `json:"liftedAt" db:"lifted_at" format:"date-time"`     // Date the ban was lifted, on expiry or by a moderator
+		LiftedBy   nulltype.NullString `json:"liftedBy" db:"lifted_by"`                        // ID of the user who lifted the ban, null on expiry
+		UnbannedAt nulltype.NullTime   `json:"unbannedAt" db:"unbanned_at" format:"date-time"` // Date the bot unbanned the member once the ban was lifted
 	}
 )
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mattn/go-nulltype"
+)
+
+// Types of moderation cases
+const (
+	WarnCase  = "warn"
+	BanCase   = "ban"
+	UnbanCase = "unban"
+	KickCase  = "kick"
+	MuteCase  = "mute"
+	NoteCase  = "note"
+)
+
+// Reason of the unban cases of expired bans
+const ExpiredBanReason = "Ban expired"
+
+const (
+	SelectCasesQuery = "SELECT * FROM mod_case WHERE guild_id=? AND case_number < ?"
+	SelectCaseQuery  = "SELECT * FROM mod_case WHERE guild_id=? AND case_number=?"
+	// Next case number of the guild, to select once the guild row is locked
+	NextCaseNumberQuery = "SELECT COALESCE(MAX(case_number), 0) + 1 FROM mod_case WHERE guild_id=?"
+	CreateCaseQuery     = `
+		INSERT INTO mod_case
+			(guild_id, case_number, case_type, member_id, moderator_id, reason, created_at, expires_at, warn_id, ban_id)
+		VALUES
+			(:guild_id, :case_number, :case_type, :member_id, :moderator_id, :reason, :created_at, :expires_at, :warn_id, :ban_id)
+	`
+	UpdateCaseReasonQuery = "UPDATE mod_case SET reason=?, updated_at=? WHERE guild_id=? AND case_number=?"
+	// Keep the reason of the warn or ban of the case in sync
+	UpdateCaseWarnReasonQuery = `
+		UPDATE warn SET warn_reason=?
+		WHERE warn_id=(SELECT warn_id FROM mod_case WHERE guild_id=? AND case_number=? AND case_type='warn')
+	`
+	UpdateCaseBanReasonQuery = `
+		UPDATE ban SET ban_reason=?
+		WHERE ban_id=(SELECT ban_id FROM mod_case WHERE guild_id=? AND case_number=? AND case_type='ban')
+	`
+)
+
+type (
+	// Case is an entry of the moderation log of a guild.
+	Case struct {
+		CaseID      int                 `json:"-" db:"case_id"`
+		GuildID     string              `json:"guildID" db:"guild_id"`                        // ID of the guild
+		CaseNumber  int                 `json:"caseNumber" db:"case_number"`                  // Number of the case in the guild, from 1
+		Type        string              `json:"type" db:"case_type"`                          // warn, ban, unban, kick, mute or note
+		MemberID    string              `json:"memberID" db:"member_id"`                      // ID of the member
+		ModeratorID nulltype.NullString `json:"moderatorID" db:"moderator_id"`                // ID of the moderator, null for automatic cases
+		Reason      nulltype.NullString `json:"reason" db:"reason"`                           // Reason of the case
+		CreatedAt   time.Time           `json:"createdAt" db:"created_at"`                    // Date of the case
+		UpdatedAt   nulltype.NullTime   `json:"updatedAt" db:"updated_at" format:"date-time"` // Date the reason was last edited
+		ExpiresAt   nulltype.NullTime   `json:"expiresAt" db:"expires_at" format:"date-time"` // Date a ban or mute ends, null if permanent
+		WarnID      nulltype.NullInt64  `json:"warnID" db:"warn_id"`                          // Warn of a warn case
+		BanID       nulltype.NullInt64  `json:"banID" db:"ban_id"`                            // Ban of a ban or unban case
+	}
+)
+
+// ValidCaseType returns whether t is a type of moderation case.
+func ValidCaseType(t string) bool {
+	switch t {
+	case WarnCase, BanCase, UnbanCase, KickCase, MuteCase, NoteCase:
+		return true
+	}
+	return false
+}
+
+// Case returns the case of the warn.
+func (w Warn) Case() Case {
+	return Case{
+		GuildID:     w.GuildID,
+		Type:        WarnCase,
+		MemberID:    w.MemberID,
+		ModeratorID: w.WarnerID,
+		Reason:      w.WarnReason,
+		CreatedAt:   w.WarnedAt,
+		WarnID:      nulltype.NullInt64Of(int64(w.WarnID)),
+	}
+}
+
+// Case returns the case of the ban.
+func (b Ban) Case() Case {
+	return Case{
+		GuildID:     b.GuildID,
+		Type:        BanCase,
+		MemberID:    b.MemberID,
+		ModeratorID: b.BannerID,
+		Reason:      b.BanReason,
+		CreatedAt:   b.BannedAt,
+		ExpiresAt:   b.ExpiresAt,
+		BanID:       nulltype.NullInt64Of(int64(b.BanID)),
+	}
+}
+
+// UnbanCase returns the case of the lifted ban.
+func (b Ban) UnbanCase(reason nulltype.NullString) Case {
+	return Case{
+		GuildID:     b.GuildID,
+		Type:        UnbanCase,
+		MemberID:    b.MemberID,
+		ModeratorID: b.LiftedBy,
+		Reason:      reason,
+		CreatedAt:   b.LiftedAt.TimeValue(),
+		BanID:       nulltype.NullInt64Of(int64(b.BanID)),
+	}
+}
@@ -1,15 +1,18 @@
 package models
 
 const (
-	CreateChannelQuery = `
+	SelectGuildChannelsQuery = "SELECT * FROM channel WHERE guild_id=?"
+	SelectChannelQuery       = "SELECT * FROM channel WHERE guild_id=? AND channel_id=?"
+	DeleteChannelQuery       = "DELETE FROM channel WHERE guild_id=? AND channel_id=?"
+	CreateChannelQuery       = `
 		INSERT INTO channel
-			(channel_id, guild_id, ignored, xp_blacklisted)
+			(channel_id, guild_id, ignored, xp_blacklisted, xp_multiplier)
 		VALUES
-			(:channel_id, :guild_id, :ignored, :xp_blacklisted)
+			(:channel_id, :guild_id, :ignored, :xp_blacklisted, :xp_multiplier)
 	`
 	UpdateChannelQuery = `
 		UPDATE channel SET
-			ignored=:ignored, xp_blacklisted=:xp_blacklisted
+			ignored=:ignored, xp_blacklisted=:xp_blacklisted, xp_multiplier=:xp_multiplier
 		WHERE
 			guild_id=:guild_id AND channel_id=:channel_id
 	`
@@ -17,9 +20,10 @@ const (
 
 type (
 	Channel struct {
-		ChannelID     string `json:"channelID" db:"channel_id"`         // ID of the channel
-		GuildID       string `json:"guildID" db:"guild_id"`             // ID of the guild
-		Ignored       bool   `json:"ignored" db:"ignored"`              // Wether the channel is ignored by the bot or not
-		XpBlacklisted bool   `json:"xpBlacklisted" db:"xp_blacklisted"` // Wether the channel is blacklisted from xp or not
+		ChannelID     string  `json:"channelID" db:"channel_id"`         // ID of the channel
+		GuildID       string  `json:"guildID" db:"guild_id"`             // ID of the guild
+		Ignored       bool    `json:"ignored" db:"ignored"`              // Wether the channel is ignored by the bot or not
+		XpBlacklisted bool    `json:"xpBlacklisted" db:"xp_blacklisted"` // Wether the channel is blacklisted from xp or not
+		XpMultiplier  float64 `json:"xpMultiplier" db:"xp_multiplier"`   // Multiplier of the xp gained in the channel, 0 blacklisting it
 	}
 )
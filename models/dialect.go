@@ -0,0 +1,29 @@
+package models
+
+import "strings"
+
+// Dialect is the flavour of sql spoken by a database driver.
+// Queries are written for MySQL, other dialects override the ones they do not support.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+var dialectQueries = map[Dialect]map[string]string{
+	SQLite: sqliteQueries,
+}
+
+// Query returns the version of the MySQL query written for the dialect.
+func (d Dialect) Query(query string) string {
+	if q, ok := dialectQueries[d][query]; ok {
+		return q
+	}
+	if d == Postgres {
+		// Postgres quotes identifiers with double quotes instead of backticks.
+		return strings.ReplaceAll(query, "`", `"`)
+	}
+	return query
+}
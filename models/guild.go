@@ -1,17 +1,27 @@
 package models
 
-import "github.com/mattn/go-nulltype"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gyroskan/cardinal/leveling"
+	"github.com/mattn/go-nulltype"
+)
 
 const (
-	CreateGuildQuery = `
+	SelectGuildsQuery = "SELECT * FROM guild"
+	SelectGuildQuery  = "SELECT * FROM guild WHERE guild_id=?"
+	DeleteGuildQuery  = "DELETE FROM guild WHERE guild_id=?"
+	CreateGuildQuery  = `
 		INSERT INTO guild
 			(guild_id, guild_name, prefix, report_channel, welcome_channel, welcome_message,
 			private_welcome_msg, level_channel, level_replace, level_response, disabled_commands,
-			allow_moderation, max_warns, ban_time)
+			allow_moderation, max_warns, ban_time, level_curve, xp_cooldown, xp_stacking)
 		VALUES
 			(:guild_id, :guild_name, :prefix, :report_channel, :welcome_channel, :welcome_message,
 			:private_welcome_msg, :level_channel, :level_replace, :level_response, :disabled_commands,
-			:allow_moderation, :max_warns, :ban_time)
+			:allow_moderation, :max_warns, :ban_time, :level_curve, :xp_cooldown, :xp_stacking)
 		`
 	UpdateGuildQuery = `
 		UPDATE guild SET
@@ -19,20 +29,28 @@ const (
 			welcome_channel=:welcome_channel, welcome_message=:welcome_message, 
 			private_welcome_msg=:private_welcome_msg, level_channel=:level_channel, level_replace=:level_replace,
 			level_response=:level_response,disabled_commands=:disabled_commands,
-			allow_moderation=:allow_moderation, max_warns=:max_warns, ban_time=:ban_time
+			allow_moderation=:allow_moderation, max_warns=:max_warns, ban_time=:ban_time,
+			level_curve=:level_curve, xp_cooldown=:xp_cooldown, xp_stacking=:xp_stacking
 		WHERE
 			guild_id=:guild_id
 		`
 	ResetGuildQuery = `
 		UPDATE guild SET
-			prefix=DEFAULT,report_channel=DEFAUT,welcome_channel=DEFAUT, welcome_message=DEFAULT,
-			private_welcome_msg=DEFAULT,level_channel=DEFAUT,level_response=DEFAULT,level_replace=DEFAULT,
-			allow_moderation=DEFAULT, max_warns=DEFAULT, ban_time=DEFAULT
+			prefix=DEFAULT,report_channel=DEFAULT,welcome_channel=DEFAULT, welcome_message=DEFAULT,
+			private_welcome_msg=DEFAULT,level_channel=DEFAULT,level_response=DEFAULT,level_replace=DEFAULT,
+			allow_moderation=DEFAULT, max_warns=DEFAULT, ban_time=DEFAULT, level_curve=DEFAULT, xp_cooldown=DEFAULT,
+			xp_stacking=DEFAULT
 		WHERE
 			guild_id=?
 	`
 )
 
+// Rules combining the xp multipliers of the channel and of the roles of a member
+const (
+	MaxStacking      = "max"      // The highest multiplier applies
+	MultiplyStacking = "multiply" // The multipliers are multiplied together
+)
+
 type (
 	Guild struct {
 		GuildID           string              `json:"guildID" db:"guild_id"`                      // Guild ID
@@ -49,12 +67,79 @@ type (
 		AllowModeration   bool                `json:"allowModeration" db:"allow_moderation"`      // Whether or not to allow moderation commands
 		MaxWarns          int                 `json:"maxWarns" db:"max_warns"`                    // Max number of warnings before a user is banned
 		BanTime           int                 `json:"banTime" db:"ban_time"`                      // Time in days to ban a user for
+		LvlCurve          LevelCurve          `json:"lvlCurve" db:"level_curve"`                  // Xp required to reach each level, null for the default curve
+		XpCooldown        int                 `json:"xpCooldown" db:"xp_cooldown"`                // Seconds before a member gains xp again from a message
+		XpStacking        string              `json:"xpStacking" db:"xp_stacking"`                // How the xp multipliers of the channel and roles combine, max or multiply
 		// TODO is Members field needed?
 	}
 
+	// LevelCurve is the level curve of a guild, stored as JSON.
+	// Guilds without curve use the default one.
+	LevelCurve struct {
+		Curve leveling.Curve
+		Valid bool
+	}
+
 	GuildPres struct {
 		GuildID   string `json:"guildID" db:"guildID"`     // Guild ID
 		GuildName string `json:"guildName" db:"guildname"` // Name of the guild
 		Prefix    string `json:"prefix" db:"prefix"`       // Prefix used for calling the bot
 	}
 )
+
+// Curve returns the level curve of the guild.
+func (g Guild) Curve() leveling.Curve {
+	if g.LvlCurve.Valid {
+		return g.LvlCurve.Curve
+	}
+	return leveling.Default
+}
+
+func (c LevelCurve) MarshalJSON() ([]byte, error) {
+	if !c.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(c.Curve)
+}
+
+// UnmarshalJSON decodes and validates the curve, null resetting it to the default one.
+func (c *LevelCurve) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*c = LevelCurve{}
+		return nil
+	}
+	curve, err := leveling.Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*c = LevelCurve{Curve: curve, Valid: true}
+	return nil
+}
+
+func (c *LevelCurve) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*c = LevelCurve{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a level curve", value)
+	}
+	if err := json.Unmarshal(b, &c.Curve); err != nil {
+		return err
+	}
+	c.Valid = true
+	return nil
+}
+
+func (c LevelCurve) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(c.Curve)
+	return string(b), err
+}
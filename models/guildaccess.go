@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	SelectUserGuildAccessesQuery = "SELECT * FROM guild_access WHERE username=? ORDER BY guild_id ASC"
+	SelectGuildAccessesQuery     = "SELECT * FROM guild_access WHERE guild_id=? ORDER BY username ASC"
+	SelectGuildAccessQuery       = "SELECT * FROM guild_access WHERE guild_id=? AND username=?"
+	InsertGuildAccessQuery       = `
+		INSERT INTO guild_access
+			(guild_id, username, access, granted_at)
+		VALUES
+			(:guild_id, :username, :access, :granted_at)
+	`
+	UpdateGuildAccessQuery = `
+		UPDATE guild_access SET
+			access=:access, granted_at=:granted_at
+		WHERE
+			guild_id=:guild_id AND username=:username
+	`
+	DeleteGuildAccessQuery = "DELETE FROM guild_access WHERE guild_id=? AND username=?"
+)
+
+// GuildAccessLevels lists the permissions granted on a single guild by each access.
+var GuildAccessLevels = map[string][]Permission{
+	"read":     {GuildsRead, ModerationRead},
+	"moderate": {GuildsRead, ModerationRead, ModerationWrite},
+	"admin":    {GuildsRead, GuildsWrite, GuildsAdmin, MembersXp, ModerationRead, ModerationWrite},
+}
+
+type (
+	GuildAccess struct {
+		GuildID   string    `json:"guildID" db:"guild_id"`     // Guild ID
+		Username  string    `json:"username" db:"username"`    // Username of the user granted the access
+		Access    string    `json:"access" db:"access"`        // Access granted on the guild: read, moderate or admin
+		GrantedAt time.Time `json:"grantedAt" db:"granted_at"` // Date the access was granted
+	}
+)
+
+// Permissions returns the permissions granted on the guild.
+func (a *GuildAccess) Permissions() PermissionSet {
+	set := PermissionSet{}
+	for _, p := range GuildAccessLevels[a.Access] {
+		set[p] = true
+	}
+	return set
+}
+
+// Validate GuildAccess fields
+func (a *GuildAccess) Validate() error {
+	if _, ok := GuildAccessLevels[a.Access]; !ok {
+		return errors.New("access must be read, moderate or admin")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+const (
+	SelectLeaderboardQuery = `
+		SELECT * FROM member
+		WHERE guild_id=?
+		ORDER BY xp DESC, member_id DESC
+		LIMIT ? OFFSET ?
+		`
+	// Next page of the leaderboard, after the member with the given xp and id
+	SelectLeaderboardAfterQuery = `
+		SELECT * FROM member
+		WHERE guild_id=? AND (xp < ? OR (xp = ? AND member_id < ?))
+		ORDER BY xp DESC, member_id DESC
+		LIMIT ?
+		`
+	CountMembersAboveQuery = "SELECT COUNT(*) FROM member WHERE guild_id=? AND xp > ?"
+	// Members before the member with the given xp and id in the leaderboard
+	CountMembersBeforeQuery = "SELECT COUNT(*) FROM member WHERE guild_id=? AND (xp > ? OR (xp = ? AND member_id > ?))"
+	CountGuildMembersQuery  = "SELECT COUNT(*) FROM member WHERE guild_id=?"
+)
+
+type (
+	RankedMember struct {
+		Rank int `json:"rank" db:"-"` // Position of the member in the leaderboard, members with the same xp sharing it
+		Member
+	}
+
+	// LeaderboardCursor is the last member of a leaderboard page, after which the next page starts.
+	// The zero value is not a cursor.
+	LeaderboardCursor struct {
+		Xp       int
+		MemberID string
+	}
+)
+
+// Rank ranks the members of a leaderboard page, ordered by xp, following before members.
+// above is the number of members with more xp than the first one.
+func Rank(members []Member, before int, above int) []RankedMember {
+	ranked := make([]RankedMember, len(members))
+	for i, m := range members {
+		ranked[i] = RankedMember{Rank: before + i + 1, Member: m}
+		if i == 0 {
+			ranked[i].Rank = above + 1
+		} else if m.Xp == members[i-1].Xp {
+			ranked[i].Rank = ranked[i-1].Rank
+		}
+	}
+	return ranked
+}
+
+// Cursor returns the cursor of the page following the member.
+func (m RankedMember) Cursor() LeaderboardCursor {
+	return LeaderboardCursor{Xp: m.Xp, MemberID: m.MemberID}
+}
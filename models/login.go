@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	PasswordLogin = "password"
+	DiscordLogin  = "discord"
+	TotpLogin     = "2fa"
+)
+
+const (
+	InvalidCredentials = "invalid_credentials"
+	InvalidCode        = "invalid_code"
+	LoginLocked        = "locked"
+	UserBanned         = "banned"
+)
+
+const (
+	SelectLoginHistoryQuery = "SELECT * FROM login_history WHERE attempt_id < ?"
+	InsertLoginAttemptQuery = `
+		INSERT INTO login_history
+			(username, ip, user_agent, method, success, reason, created_at)
+		VALUES
+			(:username, :ip, :user_agent, :method, :success, :reason, :created_at)
+	`
+)
+
+type (
+	// LoginAttempt is an entry of the login history.
+	LoginAttempt struct {
+		AttemptID int       `json:"attemptID" db:"attempt_id"` // ID of the attempt
+		Username  string    `json:"username" db:"username"`    // Username sent, which may not exist
+		IP        string    `json:"ip" db:"ip"`                // IP address of the client
+		UserAgent string    `json:"userAgent" db:"user_agent"` // User agent of the client
+		Method    string    `json:"method" db:"method"`        // password, discord or 2fa
+		Success   bool      `json:"success" db:"success"`      // Whether the user was logged in
+		Reason    string    `json:"reason" db:"reason"`        // Why the attempt failed
+		CreatedAt time.Time `json:"createdAt" db:"created_at"` // Date of the attempt
+	}
+)
@@ -3,6 +3,8 @@ package models
 import "github.com/mattn/go-nulltype"
 
 const (
+	SelectMemberQuery       = "SELECT * FROM member WHERE guild_id=? AND member_id=?"
+	DeleteMemberQuery       = "DELETE FROM member WHERE guild_id=? AND member_id=?"
 	SelectGuildMembersQuery = `
 			SELECT * FROM member
 			WHERE guild_id=? AND member_id > ?
@@ -11,9 +13,9 @@ const (
 		`
 	CreateMemberQuery = `
 		INSERT INTO member 
-			(member_id, guild_id, joined_at, ` + "`left`" + `, xp, level)
+			(member_id, guild_id, joined_at, ` + "`left`" + `, xp, level, last_xp_at)
 		VALUES
-			(:member_id, :guild_id, :joined_at, :left, :xp, :level)
+			(:member_id, :guild_id, :joined_at, :left, :xp, :level, :last_xp_at)
 		`
 	ResetMemberQuery = `
 		UPDATE member SET
@@ -27,7 +29,16 @@ const (
 		WHERE
 			guild_id=?
 		`
-	UpdateMemberQuery = `
+	AddMemberXpQuery         = "UPDATE member SET xp=xp+? WHERE guild_id=? AND member_id=?"
+	AddMemberXpCooldownQuery = `
+		UPDATE member SET
+			xp=xp+?, last_xp_at=?
+		WHERE
+			guild_id=? AND member_id=? AND (last_xp_at IS NULL OR last_xp_at <= ?)
+		`
+	UpdateMemberLevelQuery = "UPDATE member SET level=? WHERE guild_id=? AND member_id=?"
+	SetMemberLevelQuery    = "UPDATE member SET level=? WHERE guild_id=? AND member_id=? AND xp=?"
+	UpdateMemberQuery      = `
 		UPDATE member SET 
 			` + "`left`" + `=:left, xp=:xp, level=:level
 		WHERE 
@@ -36,10 +47,11 @@ const (
 )
 
 type Member struct {
-	MemberID string            `json:"memberID" db:"member_id"`                    // Member ID
-	GuildID  string            `json:"guildID" db:"guild_id"`                      // Guild ID
-	JoinedAt nulltype.NullTime `json:"joinedAt" db:"joined_at" format:"date-time"` // Date for when the member joined the guild
-	Left     int               `json:"left" db:"left"`                             // Number of times the member left the guild
-	Xp       int               `json:"xp" db:"xp"`                                 // Amount of xp the member has
-	Level    int               `json:"level" db:"level"`                           // Level of the member
+	MemberID string            `json:"memberID" db:"member_id"`                     // Member ID
+	GuildID  string            `json:"guildID" db:"guild_id"`                       // Guild ID
+	JoinedAt nulltype.NullTime `json:"joinedAt" db:"joined_at" format:"date-time"`  // Date for when the member joined the guild
+	Left     int               `json:"left" db:"left"`                              // Number of times the member left the guild
+	Xp       int               `json:"xp" db:"xp"`                                  // Amount of xp the member has
+	Level    int               `json:"level" db:"level"`                            // Level of the member
+	LastXpAt nulltype.NullTime `json:"lastXpAt" db:"last_xp_at" format:"date-time"` // Last time the member gained xp from a message
 }
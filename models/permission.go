@@ -0,0 +1,105 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Permission grants access to a group of api routes.
+type Permission string
+
+const (
+	GuildsRead      Permission = "guilds:read"      // Read guilds, members, roles and channels
+	GuildsWrite     Permission = "guilds:write"     // Create, update and delete guilds, members, roles and channels
+	GuildsAdmin     Permission = "guilds:admin"     // Grant users access to guilds
+	MembersXp       Permission = "members:xp"       // Update and reset the xp of members
+	ModerationRead  Permission = "moderation:read"  // Read warns and bans
+	ModerationWrite Permission = "moderation:write" // Create and delete warns and bans
+	UsersAdmin      Permission = "users:admin"      // Manage every user of the api
+)
+
+// AccessRoles lists the permissions granted by each role that can be assigned to users and api keys.
+var AccessRoles = map[string][]Permission{
+	"admin":     {GuildsRead, GuildsWrite, GuildsAdmin, MembersXp, ModerationRead, ModerationWrite, UsersAdmin},
+	"editor":    {GuildsRead, GuildsWrite, MembersXp, ModerationRead, ModerationWrite},
+	"viewer":    {GuildsRead, ModerationRead},
+	"moderator": {GuildsRead, ModerationRead, ModerationWrite},
+	"leveling":  {GuildsRead, MembersXp},
+}
+
+// levelRoles are the roles granted by the access levels 0, 1 and 2.
+var levelRoles = []string{"admin", "editor", "viewer"}
+
+// PermissionSet is a set of permissions.
+type PermissionSet map[Permission]bool
+
+// LevelRole returns the role granted by the access level, or an empty string if it grants none.
+func LevelRole(lvl int) string {
+	if lvl < 0 || lvl >= len(levelRoles) {
+		return ""
+	}
+	return levelRoles[lvl]
+}
+
+// RolesPermissions returns the permissions granted by the roles. Unknown roles grant nothing.
+func RolesPermissions(roles ...string) PermissionSet {
+	set := PermissionSet{}
+	for _, role := range roles {
+		for _, p := range AccessRoles[role] {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+// Has reports whether every given permission is in the set.
+func (s PermissionSet) Has(perms ...Permission) bool {
+	for _, p := range perms {
+		if !s[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns the permissions present in either set.
+func (s PermissionSet) Union(other PermissionSet) PermissionSet {
+	set := PermissionSet{}
+	for p := range s {
+		set[p] = true
+	}
+	for p := range other {
+		set[p] = true
+	}
+	return set
+}
+
+// Intersect returns the permissions present in both sets.
+func (s PermissionSet) Intersect(other PermissionSet) PermissionSet {
+	set := PermissionSet{}
+	for p := range s {
+		if other[p] {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+// ValidateRoles checks that every role of the list separated by spaces exists,
+// and returns the sorted list without duplicates.
+func ValidateRoles(roles string) (string, error) {
+	seen := map[string]bool{}
+	list := []string{}
+	for _, role := range strings.Fields(roles) {
+		if _, ok := AccessRoles[role]; !ok {
+			return "", errors.New("unknown role " + role)
+		}
+		if !seen[role] {
+			seen[role] = true
+			list = append(list, role)
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, " "), nil
+}
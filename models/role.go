@@ -1,15 +1,19 @@
 package models
 
 const (
-	CreateRoleQuery = `
+	SelectGuildRolesQuery = "SELECT * FROM role WHERE guild_id=?"
+	SelectRoleQuery       = "SELECT * FROM role WHERE guild_id=? AND role_id=?"
+	DeleteRoleQuery       = "DELETE FROM role WHERE guild_id=? AND role_id=?"
+	CreateRoleQuery       = `
 		INSERT INTO role
-			(role_id, guild_id, is_default, ignored, reward, xp_blacklisted)
+			(role_id, guild_id, is_default, ignored, reward, xp_blacklisted, xp_multiplier)
 		VALUES
-			(:role_id, :guild_id, :is_default, :ignored, :reward, :xp_blacklisted)
+			(:role_id, :guild_id, :is_default, :ignored, :reward, :xp_blacklisted, :xp_multiplier)
 	`
 	UpdateRoleQuery = `
 		UPDATE role SET
-			is_default=:is_default, ignored=:ignored, reward=:reward, xp_blacklisted=:xp_blacklisted
+			is_default=:is_default, ignored=:ignored, reward=:reward, xp_blacklisted=:xp_blacklisted,
+			xp_multiplier=:xp_multiplier
 		WHERE
 			guild_id=:guild_id AND role_id=:role_id
 	`
@@ -17,11 +21,12 @@ const (
 
 type (
 	Role struct {
-		RoleID        string `json:"roleID" db:"role_id"`               // ID of the role
-		GuildID       string `json:"guildID" db:"guild_id"`             // ID of the guild
-		IsDefault     bool   `json:"isDefault" db:"is_default"`         // Wether to give the role to new members
-		Reward        int    `json:"reward" db:"reward"`                // The level corresponding to the reward
-		Ignored       bool   `json:"ignored" db:"ignored"`              // Wether the role is ignored by the bot or not
-		XpBlacklisted bool   `json:"xpBlacklisted" db:"xp_blacklisted"` // Wether the role is blacklisted from xp or not
+		RoleID        string  `json:"roleID" db:"role_id"`               // ID of the role
+		GuildID       string  `json:"guildID" db:"guild_id"`             // ID of the guild
+		IsDefault     bool    `json:"isDefault" db:"is_default"`         // Wether to give the role to new members
+		Reward        int     `json:"reward" db:"reward"`                // The level corresponding to the reward
+		Ignored       bool    `json:"ignored" db:"ignored"`              // Wether the role is ignored by the bot or not
+		XpBlacklisted bool    `json:"xpBlacklisted" db:"xp_blacklisted"` // Wether the role is blacklisted from xp or not
+		XpMultiplier  float64 `json:"xpMultiplier" db:"xp_multiplier"`   // Multiplier of the xp gained by the members with the role, 0 blacklisting them
 	}
 )
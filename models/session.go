@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	InsertSessionQuery = `
+		INSERT INTO user_session
+			(username, ip, user_agent, created_at, last_used_at, expires_at)
+		VALUES
+			(:username, :ip, :user_agent, :created_at, :last_used_at, :expires_at)
+	`
+	SelectSessionQuery      = "SELECT * FROM user_session WHERE session_id=?"
+	SelectUserSessionsQuery = "SELECT * FROM user_session WHERE username=? AND revoked_at IS NULL AND expires_at>? ORDER BY last_used_at DESC"
+	TouchSessionQuery       = "UPDATE user_session SET last_used_at=?, ip=? WHERE session_id=?"
+	ExtendSessionQuery      = "UPDATE user_session SET expires_at=? WHERE session_id=?"
+	RevokeSessionQuery      = "UPDATE user_session SET revoked_at=? WHERE username=? AND session_id=? AND revoked_at IS NULL"
+	RevokeUserSessionsQuery = "UPDATE user_session SET revoked_at=? WHERE username=? AND session_id<>? AND revoked_at IS NULL"
+)
+
+type (
+	// Session groups the tokens issued from a login, until it expires or is revoked.
+	// Each refresh token is replaced by the next one when used, so that a session is a single chain of tokens
+	// with at most one unused token.
+	Session struct {
+		SessionID  int               `json:"sessionID" db:"session_id"`    // ID of the session
+		Username   string            `json:"username" db:"username"`       // Username of the session owner
+		IP         string            `json:"ip" db:"ip"`                   // IP address of the last use
+		UserAgent  string            `json:"userAgent" db:"user_agent"`    // User agent of the login
+		CreatedAt  time.Time         `json:"createdAt" db:"created_at"`    // Date of the login
+		LastUsedAt time.Time         `json:"lastUsedAt" db:"last_used_at"` // Date the session was last used
+		ExpiresAt  time.Time         `json:"expiresAt" db:"expires_at"`    // Date the refresh token of the session expires
+		RevokedAt  nulltype.NullTime `json:"-" db:"revoked_at"`            // Date the session was revoked
+		Current    bool              `json:"current" db:"-"`               // Whether the request was made with this session
+
+		// Unused refresh token of the session, null once used without being replaced
+		RefreshToken *RefreshToken `json:"refreshToken" db:"-"`
+	}
+)
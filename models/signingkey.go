@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	SelectSigningKeysQuery = "SELECT * FROM signing_key ORDER BY created_at ASC"
+	InsertSigningKeyQuery  = `
+		INSERT INTO signing_key
+			(key_id, algorithm, private_key, created_at)
+		VALUES
+			(:key_id, :algorithm, :private_key, :created_at)
+	`
+	DeleteSigningKeyQuery = "DELETE FROM signing_key WHERE key_id=?"
+)
+
+type (
+	// SigningKey is a key pair signing the access tokens.
+	SigningKey struct {
+		KeyID      string    `json:"keyID" db:"key_id"`         // ID of the key, sent as the kid header of the tokens
+		Algorithm  string    `json:"algorithm" db:"algorithm"`  // RS256 or EdDSA
+		PrivateKey string    `json:"-" db:"private_key"`        // Encrypted PKCS #8 private key
+		CreatedAt  time.Time `json:"createdAt" db:"created_at"` // Date the key started signing tokens
+	}
+)
@@ -0,0 +1,27 @@
+package models
+
+// SQLite does not support DEFAULT as a value in UPDATE statements,
+// so the reset queries write the default values of the schema explicitly.
+var sqliteQueries = map[string]string{
+	ResetGuildQuery: `
+		UPDATE guild SET
+			prefix='!',report_channel=NULL,welcome_channel=NULL, welcome_message=NULL,
+			private_welcome_msg=NULL,level_channel=NULL,level_response=1,level_replace=false,
+			allow_moderation=true, max_warns=3, ban_time=0, level_curve=NULL, xp_cooldown=0,
+			xp_stacking='max'
+		WHERE
+			guild_id=?
+	`,
+	ResetMemberQuery: `
+		UPDATE member SET
+			"left"=0, xp=0, level=0
+		WHERE
+			guild_id=? AND member_id=?
+		`,
+	ResetGuildMembersQuery: `
+		UPDATE member SET
+			"left"=0, xp=0, level=0
+		WHERE
+			guild_id=?
+		`,
+}
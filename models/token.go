@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	InsertRefreshTokenQuery = `
+		INSERT INTO refresh_token
+			(token_hash, username, session_id, created_at, expires_at)
+		VALUES
+			(:token_hash, :username, :session_id, :created_at, :expires_at)
+	`
+	SelectRefreshTokenQuery        = "SELECT * FROM refresh_token WHERE token_hash=?"
+	SelectActiveRefreshTokensQuery = `
+		SELECT * FROM refresh_token
+		WHERE username=? AND revoked_at IS NULL AND expires_at>?
+		ORDER BY created_at DESC
+	`
+	RevokeRefreshTokenQuery       = "UPDATE refresh_token SET revoked_at=? WHERE token_hash=? AND revoked_at IS NULL"
+	RevokeUserRefreshTokensQuery  = "UPDATE refresh_token SET revoked_at=? WHERE username=? AND revoked_at IS NULL"
+	RevokeOtherRefreshTokensQuery = `
+		UPDATE refresh_token SET revoked_at=?
+		WHERE username=? AND (session_id IS NULL OR session_id<>?) AND revoked_at IS NULL
+	`
+)
+
+type (
+	RefreshToken struct {
+		TokenHash string             `json:"-" db:"token_hash"`                            // SHA-256 of the token, the token itself is never stored
+		Username  string             `json:"username" db:"username"`                       // Username of the token owner
+		SessionID nulltype.NullInt64 `json:"sessionID" db:"session_id"`                    // Session of the token, null for tokens issued before sessions
+		CreatedAt time.Time          `json:"createdAt" db:"created_at"`                    // Date the token was issued
+		ExpiresAt time.Time          `json:"expiresAt" db:"expires_at"`                    // Date the token expires
+		RevokedAt nulltype.NullTime  `json:"revokedAt" db:"revoked_at" format:"date-time"` // Date the token was used or revoked
+	}
+)
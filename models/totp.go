@@ -0,0 +1,27 @@
+package models
+
+import "github.com/mattn/go-nulltype"
+
+const (
+	UpdateUserTotpQuery     = "UPDATE `user` SET totp_secret=?, totp_enabled=?, totp_last_step=0 WHERE username=?"
+	UpdateUserTotpStepQuery = "UPDATE `user` SET totp_last_step=? WHERE username=? AND totp_last_step<?"
+
+	SelectRecoveryCodeCountQuery = "SELECT COUNT(*) FROM recovery_code WHERE username=? AND used_at IS NULL"
+	InsertRecoveryCodeQuery      = `
+		INSERT INTO recovery_code
+			(username, code_hash)
+		VALUES
+			(:username, :code_hash)
+	`
+	UseRecoveryCodeQuery     = "UPDATE recovery_code SET used_at=? WHERE username=? AND code_hash=? AND used_at IS NULL"
+	DeleteRecoveryCodesQuery = "DELETE FROM recovery_code WHERE username=?"
+)
+
+type (
+	// RecoveryCode can be used once instead of a TOTP code, when the authenticator is lost.
+	RecoveryCode struct {
+		Username string            `json:"username" db:"username"`                 // Username of the code owner
+		CodeHash string            `json:"-" db:"code_hash"`                       // SHA-256 of the code, the code itself is never stored
+		UsedAt   nulltype.NullTime `json:"usedAt" db:"used_at" format:"date-time"` // Date the code was used
+	}
+)
@@ -14,15 +14,29 @@ import (
 var discRegex = regexp.MustCompile(`^[0-9]{17,21}`)
 
 const (
-	InsertUserQuery = `
-		INSERT INTO user
-			(username, email, discord_id, pwd_hash, salt, access_lvl, created_at, banned)
+	SelectUsersQuery       = "SELECT * FROM `user`"
+	SelectUserQuery        = "SELECT * FROM `user` WHERE username=?"
+	UpdateUserAccessQuery  = "UPDATE `user` SET access_lvl=? WHERE username=?"
+	UpdateUserBannedQuery  = "UPDATE `user` SET banned=? WHERE username=?"
+	DeleteUserQuery        = "DELETE FROM `user` WHERE username=?"
+	IncrTokenVersionQuery  = "UPDATE `user` SET token_version=token_version+1 WHERE username=?"
+	UpdateUserRolesQuery   = "UPDATE `user` SET roles=? WHERE username=?"
+	SelectDiscordUserQuery = "SELECT * FROM `user` WHERE discord_id=? AND discord_verified=?"
+	// Unverified claims of the discord id are dropped when its owner links it.
+	ClearDiscordClaimsQuery  = "UPDATE `user` SET discord_id=NULL WHERE discord_id=? AND discord_verified=? AND username<>?"
+	LinkDiscordQuery         = "UPDATE `user` SET discord_id=?, discord_verified=? WHERE username=?"
+	SelectUserByEmailQuery   = "SELECT * FROM `user` WHERE email=? ORDER BY created_at ASC"
+	UpdateEmailVerifiedQuery = "UPDATE `user` SET email_verified=? WHERE username=?"
+	InsertUserQuery          = `
+		INSERT INTO ` + "`user`" + `
+			(username, email, email_verified, discord_id, discord_verified, pwd_hash, salt, access_lvl, created_at, banned)
 		VALUES
-			(:username,:email,:discord_id,:pwd_hash,:salt,:access_lvl,:created_at,:banned)
+			(:username,:email,:email_verified,:discord_id,:discord_verified,:pwd_hash,:salt,:access_lvl,:created_at,:banned)
 			`
 	UpdateUserQuery = `
-		UPDATE user SET
-			email=:email, discord_id=:discord_id, pwd_hash=:pwd_hash, salt=:salt
+		UPDATE ` + "`user`" + ` SET
+			email=:email, email_verified=:email_verified, discord_id=:discord_id, discord_verified=:discord_verified,
+			pwd_hash=:pwd_hash, salt=:salt
 		WHERE 
 			username=:username
 			`
@@ -30,14 +44,21 @@ const (
 
 type (
 	User struct {
-		Username     string              `json:"username" db:"username"`    // Username of the user
-		Email        string              `json:"email" db:"email"`          // Email of the user
-		DiscordID    nulltype.NullString `json:"discordID" db:"discord_id"` // Discord ID of the user
-		PasswordHash string              `json:"-" db:"pwd_hash"`           // Password hash of the user
-		Salt         string              `json:"-" db:"salt"`               // Salt used on the password
-		AccessLvl    int                 `json:"accessLvl" db:"access_lvl"` // Access level to the api of the user
-		CreatedAt    time.Time           `json:"createdAt" db:"created_at"` // Date the user was created
-		Banned       bool                `json:"banned" db:"banned"`        // Whether the user is banned or not
+		Username        string              `json:"username" db:"username"`                // Username of the user
+		Email           string              `json:"email" db:"email"`                      // Email of the user
+		EmailVerified   bool                `json:"emailVerified" db:"email_verified"`     // Whether the email was confirmed
+		DiscordID       nulltype.NullString `json:"discordID" db:"discord_id"`             // Discord ID of the user
+		DiscordVerified bool                `json:"discordVerified" db:"discord_verified"` // Whether the discord id was proven through Discord login
+		PasswordHash    string              `json:"-" db:"pwd_hash"`                       // Password hash of the user
+		Salt            string              `json:"-" db:"salt"`                           // Salt used on the password
+		AccessLvl       int                 `json:"accessLvl" db:"access_lvl"`             // Access level to the api of the user
+		CreatedAt       time.Time           `json:"createdAt" db:"created_at"`             // Date the user was created
+		Banned          bool                `json:"banned" db:"banned"`                    // Whether the user is banned or not
+		TokenVersion    int                 `json:"-" db:"token_version"`                  // Incremented to revoke every token of the user
+		TotpSecret      nulltype.NullString `json:"-" db:"totp_secret"`                    // Secret of the TOTP authenticator
+		TotpEnabled     bool                `json:"totpEnabled" db:"totp_enabled"`         // Whether logins require a TOTP code
+		TotpLastStep    int64               `json:"-" db:"totp_last_step"`                 // Last TOTP step used, to prevent replays
+		Roles           string              `json:"roles" db:"roles"`                      // Roles granted on top of the access level, separated by spaces
 	}
 
 	UserCreation struct {
@@ -55,6 +76,34 @@ type (
 	}
 )
 
+// Permissions returns the permissions granted by the access level and the roles of the user.
+func (u *User) Permissions() PermissionSet {
+	return RolesPermissions(append(strings.Fields(u.Roles), LevelRole(u.AccessLvl))...)
+}
+
+// ValidEmail returns the address of the email, or an error if it is empty, too long or invalid.
+func ValidEmail(email string) (string, error) {
+	if email == "" {
+		return "", errors.New("email empty")
+	}
+	if l := len(email); l > 80 {
+		return "", errors.New("email too long")
+	}
+	m, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", errors.New("email invalid")
+	}
+	return m.Address, nil
+}
+
+// ValidPassword returns an error if the password can not be set, that is when it is empty.
+func ValidPassword(password string) error {
+	if password == "" {
+		return errors.New("password empty")
+	}
+	return nil
+}
+
 // Validate UserCreation fields
 func (u *UserCreation) Validate() error {
 	u.Username = strings.TrimSpace(u.Username)
@@ -68,25 +117,15 @@ func (u *UserCreation) Validate() error {
 		return errors.New("username too long")
 	}
 
-	if u.Email == "" {
-		return errors.New("email empty")
-	}
-	if l := len(u.Email); l > 80 {
-		return errors.New("email too long")
-	}
-	m, err := mail.ParseAddress(u.Email)
+	email, err := ValidEmail(u.Email)
 	if err != nil {
-		return errors.New("email invalid")
+		return err
 	}
-	u.Email = m.Address
+	u.Email = email
 
 	if u.DiscordID.Valid() && !discRegex.MatchString(u.DiscordID.StringValue()) {
 		return errors.New("invalid discordID")
 	}
 
-	if u.Password == "" {
-		return errors.New("password empty")
-	}
-
-	return nil
+	return ValidPassword(u.Password)
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/mattn/go-nulltype"
+)
+
+const (
+	VerifyEmailPurpose   = "verify_email"
+	ResetPasswordPurpose = "reset_password"
+)
+
+const (
+	InsertUserTokenQuery = `
+		INSERT INTO user_token
+			(token_hash, username, purpose, created_at, expires_at)
+		VALUES
+			(:token_hash, :username, :purpose, :created_at, :expires_at)
+	`
+	SelectUserTokenQuery = "SELECT * FROM user_token WHERE token_hash=?"
+	UseUserTokenQuery    = "UPDATE user_token SET used_at=? WHERE token_hash=? AND used_at IS NULL"
+	UseUserTokensQuery   = "UPDATE user_token SET used_at=? WHERE username=? AND purpose=? AND used_at IS NULL"
+)
+
+type (
+	// UserToken is a single use token sent by email to prove the ownership of the address.
+	UserToken struct {
+		TokenHash string            `json:"-" db:"token_hash"`                      // SHA-256 of the token, the token itself is never stored
+		Username  string            `json:"username" db:"username"`                 // Username of the token owner
+		Purpose   string            `json:"purpose" db:"purpose"`                   // Action allowed by the token
+		CreatedAt time.Time         `json:"createdAt" db:"created_at"`              // Date the token was issued
+		ExpiresAt time.Time         `json:"expiresAt" db:"expires_at"`              // Date the token expires
+		UsedAt    nulltype.NullTime `json:"usedAt" db:"used_at" format:"date-time"` // Date the token was used
+	}
+)
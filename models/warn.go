@@ -7,12 +7,25 @@ import (
 )
 
 const (
-	CreateWarnQuery = `
+	SelectMemberWarnsQuery = "SELECT * FROM warn WHERE guild_id=? AND member_id=?"
+	SelectWarnQuery        = "SELECT * FROM warn WHERE guild_id=? AND member_id=? AND warn_id=?"
+	DeleteWarnQuery        = "DELETE FROM warn WHERE guild_id=? AND member_id=? AND warn_id=?"
+	CreateWarnQuery        = `
 		INSERT INTO warn 
 			(member_id, guild_id, warner_id, warned_at, warn_reason)
 		VALUES
 			(:member_id, :guild_id, :warner_id, :warned_at, :warn_reason)
 	`
+	// Warns given since the last ban of the member
+	CountActiveWarnsQuery = `
+		SELECT COUNT(*) FROM warn
+		WHERE guild_id=? AND member_id=? AND NOT EXISTS (
+			SELECT 1 FROM ban
+			WHERE ban.guild_id=warn.guild_id AND ban.member_id=warn.member_id AND ban.banned_at >= warn.warned_at
+		)
+	`
+	// Locks the guild row until the end of the transaction, so that its warns are created one at a time
+	LockGuildQuery = "UPDATE guild SET max_warns=max_warns WHERE guild_id=?"
 )
 
 type (
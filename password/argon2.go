@@ -0,0 +1,88 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id hashes passwords with argon2id, encoded as
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>.
+type Argon2id struct {
+	Time    uint32 // Number of passes over the memory
+	Memory  uint32 // Memory used in KiB
+	Threads uint8  // Number of threads used
+	SaltLen uint32 // Length of the random salt in bytes
+	KeyLen  uint32 // Length of the hash in bytes
+}
+
+// DefaultArgon2id follows the second recommended option of RFC 9106.
+var DefaultArgon2id = &Argon2id{Time: 3, Memory: 64 * 1024, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+var b64 = base64.RawStdEncoding
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Time != a.Time || params.Memory != a.Memory || params.Threads != a.Threads ||
+		uint32(len(salt)) != a.SaltLen || uint32(len(key)) != a.KeyLen
+}
+
+func verifyArgon2id(password string, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+func decodeArgon2id(encoded string) (params Argon2id, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, err
+	}
+
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, err
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, err
+	}
+
+	return params, salt, key, nil
+}
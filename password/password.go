@@ -0,0 +1,66 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownHash is returned when an encoded hash was not produced by any supported hasher.
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher hashes passwords into self describing PHC strings.
+type Hasher interface {
+	// Hash returns the encoded hash of the password, salt and parameters included.
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether the encoded hash was produced by another algorithm
+	// or with other parameters than the ones of the hasher.
+	NeedsRehash(encoded string) bool
+}
+
+// New returns the hasher with the given name, argon2id when empty.
+func New(name string) (Hasher, error) {
+	switch name {
+	case "", "argon2id":
+		return DefaultArgon2id, nil
+	case "bcrypt":
+		return DefaultBcrypt, nil
+	default:
+		return nil, fmt.Errorf("unknown password hasher %s", name)
+	}
+}
+
+// Verify reports whether the password matches the encoded hash, whatever hasher produced it.
+func Verify(password string, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return verifyBcrypt(password, encoded)
+	default:
+		return false, ErrUnknownHash
+	}
+}
+
+// IsLegacy reports whether the hash was produced by the former salted SHA-256 scheme,
+// stored as hex without any PHC prefix.
+func IsLegacy(encoded string) bool {
+	return encoded != "" && !strings.HasPrefix(encoded, "$")
+}
+
+// VerifyLegacy reports whether the password matches a hash of the former scheme,
+// that is the hex SHA-256 of the password followed by the hex decoded salt.
+func VerifyLegacy(password string, encoded string, hexSalt string) (bool, error) {
+	salt, err := hex.DecodeString(hexSalt)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(append([]byte(password), salt...))
+	computed := hex.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
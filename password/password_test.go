@@ -0,0 +1,106 @@
+package password
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Cheap parameters, the default ones taking too long for tests.
+var (
+	testArgon2id = &Argon2id{Time: 1, Memory: 1024, Threads: 1, SaltLen: 16, KeyLen: 32}
+	testBcrypt   = &Bcrypt{Cost: 4}
+)
+
+func TestVerify(t *testing.T) {
+	for name, hasher := range map[string]Hasher{"argon2id": testArgon2id, "bcrypt": testBcrypt} {
+		hash, err := hasher.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := Verify("correct horse", hash); !ok || err != nil {
+			t.Errorf("%s: password does not match its hash %s: %v", name, hash, err)
+		}
+		if ok, err := Verify("battery staple", hash); ok || err != nil {
+			t.Errorf("%s: other password matches %s: %v", name, hash, err)
+		}
+		if IsLegacy(hash) {
+			t.Errorf("%s: hash %s is legacy", name, hash)
+		}
+	}
+
+	if _, err := Verify("correct horse", "$md5$abc"); !errors.Is(err, ErrUnknownHash) {
+		t.Errorf("unknown hash verified with %v, want ErrUnknownHash", err)
+	}
+	if _, err := Verify("correct horse", "$argon2id$v=19$m=1024$abc"); err == nil {
+		t.Error("malformed argon2id hash verified without error")
+	}
+}
+
+func TestVerifyLegacy(t *testing.T) {
+	salt := "0a1b2c3d"
+	raw, _ := hex.DecodeString(salt)
+	sum := sha256.Sum256(append([]byte("correct horse"), raw...))
+	hash := hex.EncodeToString(sum[:])
+
+	if !IsLegacy(hash) || IsLegacy("") {
+		t.Errorf("IsLegacy(%q)=%t, IsLegacy(\"\")=%t", hash, IsLegacy(hash), IsLegacy(""))
+	}
+	if ok, err := VerifyLegacy("correct horse", hash, salt); !ok || err != nil {
+		t.Errorf("password does not match its legacy hash: %v", err)
+	}
+	if ok, err := VerifyLegacy("battery staple", hash, salt); ok || err != nil {
+		t.Errorf("other password matches the legacy hash: %v", err)
+	}
+	if ok, _ := VerifyLegacy("correct horse", hash, "ffff"); ok {
+		t.Error("password matches the legacy hash with another salt")
+	}
+	if _, err := VerifyLegacy("correct horse", hash, "not hex"); err == nil {
+		t.Error("invalid salt verified without error")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcrypt, err := testBcrypt.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hasher Hasher
+		hash   string
+		want   bool
+	}{
+		{"same argon2id", testArgon2id, argon, false},
+		{"argon2id time", &Argon2id{Time: 2, Memory: 1024, Threads: 1, SaltLen: 16, KeyLen: 32}, argon, true},
+		{"argon2id memory", &Argon2id{Time: 1, Memory: 2048, Threads: 1, SaltLen: 16, KeyLen: 32}, argon, true},
+		{"argon2id key length", &Argon2id{Time: 1, Memory: 1024, Threads: 1, SaltLen: 16, KeyLen: 16}, argon, true},
+		{"bcrypt to argon2id", testArgon2id, bcrypt, true},
+		{"same bcrypt", testBcrypt, bcrypt, false},
+		{"bcrypt cost", &Bcrypt{Cost: 5}, bcrypt, true},
+		{"argon2id to bcrypt", testBcrypt, argon, true},
+		{"legacy", testArgon2id, "0a1b2c", true},
+	}
+	for _, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: NeedsRehash=%t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for name, want := range map[string]Hasher{"": DefaultArgon2id, "argon2id": DefaultArgon2id, "bcrypt": DefaultBcrypt} {
+		if got, err := New(name); got != want || err != nil {
+			t.Errorf("New(%q)=%v, %v", name, got, err)
+		}
+	}
+	if _, err := New("md5"); err == nil {
+		t.Error("New accepted an unknown hasher")
+	}
+}
@@ -0,0 +1,87 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type apiKeyStore struct {
+	*memory
+}
+
+func (s *apiKeyStore) List(username string) ([]models.ApiKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.ApiKey{}
+	for _, k := range s.apiKeys {
+		if k.Username == username {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KeyID < keys[j].KeyID
+	})
+
+	return keys, nil
+}
+
+func (s *apiKeyStore) Get(keyHash string) (models.ApiKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.apiKeys {
+		if k.KeyHash == keyHash {
+			return k, nil
+		}
+	}
+	return models.ApiKey{}, store.ErrNotFound
+}
+
+func (s *apiKeyStore) Create(key *models.ApiKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[key.Username]; !ok {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", key.Username)
+	}
+	for _, k := range s.apiKeys {
+		if k.KeyHash == key.KeyHash {
+			return conflict("api key")
+		}
+	}
+	s.apiKeySeq++
+	key.KeyID = s.apiKeySeq
+	s.apiKeys[key.KeyID] = *key
+	return nil
+}
+
+func (s *apiKeyStore) Touch(keyID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[keyID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	k.LastUsedAt = nulltype.NullTimeOf(at)
+	s.apiKeys[keyID] = k
+	return nil
+}
+
+func (s *apiKeyStore) Delete(username string, keyID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[keyID]
+	if !ok || k.Username != username {
+		return store.ErrNotFound
+	}
+	delete(s.apiKeys, keyID)
+	return nil
+}
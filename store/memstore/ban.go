@@ -0,0 +1,150 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type banStore struct {
+	*memory
+}
+
+func (s *banStore) List(guildID string, memberID string) ([]models.Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bans := []models.Ban{}
+	for _, w := range s.bans {
+		if w.GuildID == guildID && w.MemberID == memberID {
+			bans = append(bans, w)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].BanID < bans[j].BanID
+	})
+
+	return bans, nil
+}
+
+func (s *banStore) Get(guildID string, memberID string, banID int) (models.Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ban, ok := s.bans[banID]
+	if !ok || ban.GuildID != guildID || ban.MemberID != memberID {
+		return models.Ban{}, store.ErrNotFound
+	}
+	return ban, nil
+}
+
+func (s *banStore) Create(ban *models.Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[ban.GuildID]; !ok {
+		return store.ErrNotFound
+	}
+	s.banSeq++
+	ban.BanID = s.banSeq
+	s.bans[ban.BanID] = *ban
+	modCase := ban.Case()
+	s.addCase(&modCase)
+	return nil
+}
+
+func (s *banStore) Lift(guildID string, memberID string, banID int, liftedBy nulltype.NullString, reason nulltype.NullString, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ban, ok := s.bans[banID]
+	if !ok || ban.GuildID != guildID || ban.MemberID != memberID || ban.LiftedAt.Valid() {
+		return store.ErrNotFound
+	}
+	ban.LiftedAt = nulltype.NullTimeOf(at)
+	ban.LiftedBy = liftedBy
+	s.bans[banID] = ban
+	modCase := ban.UnbanCase(reason)
+	s.addCase(&modCase)
+	return nil
+}
+
+func (s *banStore) LiftExpired(at time.Time) ([]models.Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lifted := []models.Ban{}
+	for id, ban := range s.bans {
+		if !ban.LiftedAt.Valid() && ban.ExpiresAt.Valid() && !ban.ExpiresAt.TimeValue().After(at) {
+			ban.LiftedAt = nulltype.NullTimeOf(at)
+			s.bans[id] = ban
+			lifted = append(lifted, ban)
+		}
+	}
+	sort.Slice(lifted, func(i, j int) bool {
+		return lifted[i].BanID < lifted[j].BanID
+	})
+	for _, ban := range lifted {
+		modCase := ban.UnbanCase(nulltype.NullStringOf(models.ExpiredBanReason))
+		s.addCase(&modCase)
+	}
+	return lifted, nil
+}
+
+func (s *banStore) PendingUnbans(guildID string, limit int) ([]models.Ban, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bans := []models.Ban{}
+	for _, ban := range s.bans {
+		if ban.GuildID == guildID && ban.LiftedAt.Valid() && !ban.UnbannedAt.Valid() {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].LiftedAt.TimeValue().Equal(bans[j].LiftedAt.TimeValue()) {
+			return bans[i].LiftedAt.TimeValue().Before(bans[j].LiftedAt.TimeValue())
+		}
+		return bans[i].BanID < bans[j].BanID
+	})
+	if len(bans) > limit {
+		bans = bans[:limit]
+	}
+
+	return bans, nil
+}
+
+func (s *banStore) SetUnbanned(guildID string, banID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ban, ok := s.bans[banID]
+	if !ok || ban.GuildID != guildID || !ban.LiftedAt.Valid() || ban.UnbannedAt.Valid() {
+		return store.ErrNotFound
+	}
+	ban.UnbannedAt = nulltype.NullTimeOf(at)
+	s.bans[banID] = ban
+	return nil
+}
+
+func (s *banStore) Delete(guildID string, memberID string, banID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ban, ok := s.bans[banID]
+	if !ok || ban.GuildID != guildID || ban.MemberID != memberID {
+		return store.ErrNotFound
+	}
+	delete(s.bans, banID)
+
+	// ON DELETE SET NULL
+	for i, c := range s.cases[guildID] {
+		if c.BanID.Valid() && int(c.BanID.Int64Value()) == banID {
+			s.cases[guildID][i].BanID = nulltype.NullInt64{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package memstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type caseStore struct {
+	*memory
+}
+
+func (s *caseStore) List(guildID string, filter store.CaseFilter) ([]models.Case, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cases := []models.Case{}
+	guildCases := s.cases[guildID]
+	for i := len(guildCases) - 1; i >= 0 && len(cases) < filter.Limit; i-- {
+		c := guildCases[i]
+		if (filter.Before > 0 && c.CaseNumber >= filter.Before) ||
+			(filter.MemberID != "" && c.MemberID != filter.MemberID) ||
+			(filter.ModeratorID != "" && c.ModeratorID.StringValue() != filter.ModeratorID) ||
+			(filter.Type != "" && c.Type != filter.Type) ||
+			(!filter.From.IsZero() && c.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !c.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		cases = append(cases, c)
+	}
+
+	return cases, nil
+}
+
+func (s *caseStore) Get(guildID string, caseNumber int) (models.Case, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	guildCases := s.cases[guildID]
+	if caseNumber < 1 || caseNumber > len(guildCases) {
+		return models.Case{}, store.ErrNotFound
+	}
+	return guildCases[caseNumber-1], nil
+}
+
+func (s *caseStore) Create(c *models.Case) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[c.GuildID]; !ok {
+		return store.ErrNotFound
+	}
+	s.addCase(c)
+	return nil
+}
+
+// addCase adds the case with the next case number of its guild, and sets its CaseID and CaseNumber.
+// The caller must hold the lock.
+func (m *memory) addCase(c *models.Case) {
+	m.caseSeq++
+	c.CaseID = m.caseSeq
+	c.CaseNumber = len(m.cases[c.GuildID]) + 1
+	m.cases[c.GuildID] = append(m.cases[c.GuildID], *c)
+}
+
+func (s *caseStore) SetReason(guildID string, caseNumber int, reason nulltype.NullString, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guildCases := s.cases[guildID]
+	if caseNumber < 1 || caseNumber > len(guildCases) {
+		return store.ErrNotFound
+	}
+	c := &guildCases[caseNumber-1]
+	c.Reason = reason
+	c.UpdatedAt = nulltype.NullTimeOf(at)
+
+	if c.Type == models.WarnCase && c.WarnID.Valid() {
+		if warn, ok := s.warns[int(c.WarnID.Int64Value())]; ok {
+			warn.WarnReason = reason
+			s.warns[warn.WarnID] = warn
+		}
+	}
+	if c.Type == models.BanCase && c.BanID.Valid() {
+		if ban, ok := s.bans[int(c.BanID.Int64Value())]; ok {
+			ban.BanReason = reason
+			s.bans[ban.BanID] = ban
+		}
+	}
+	return nil
+}
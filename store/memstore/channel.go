@@ -0,0 +1,81 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type channelStore struct {
+	*memory
+}
+
+func (s *channelStore) List(guildID string, filter store.ChannelFilter) ([]models.Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channels := []models.Channel{}
+	for k, c := range s.channels {
+		if k.guildID != guildID ||
+			(filter.Ignored && !c.Ignored) ||
+			(filter.XpBlacklisted && !c.XpBlacklisted) {
+			continue
+		}
+		channels = append(channels, c)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].ChannelID < channels[j].ChannelID
+	})
+
+	return channels, nil
+}
+
+func (s *channelStore) Get(guildID string, channelID string) (models.Channel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channel, ok := s.channels[key{guildID, channelID}]
+	if !ok {
+		return models.Channel{}, store.ErrNotFound
+	}
+	return channel, nil
+}
+
+func (s *channelStore) Create(channel models.Channel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{channel.GuildID, channel.ChannelID}
+	if _, ok := s.channels[k]; ok {
+		return conflict("channel %s of guild %s", channel.ChannelID, channel.GuildID)
+	}
+	if err := s.checkGuild(channel.GuildID); err != nil {
+		return err
+	}
+	s.channels[k] = channel
+	return nil
+}
+
+func (s *channelStore) Update(channel models.Channel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{channel.GuildID, channel.ChannelID}
+	if _, ok := s.channels[k]; ok {
+		s.channels[k] = channel
+	}
+	return nil
+}
+
+func (s *channelStore) Delete(guildID string, channelID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, channelID}
+	if _, ok := s.channels[k]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.channels, k)
+	return nil
+}
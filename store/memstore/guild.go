@@ -0,0 +1,134 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type guildStore struct {
+	*memory
+}
+
+func (s *guildStore) All() ([]models.Guild, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	guilds := make([]models.Guild, 0, len(s.guilds))
+	for _, g := range s.guilds {
+		guilds = append(guilds, g)
+	}
+	sort.Slice(guilds, func(i, j int) bool {
+		return guilds[i].GuildID < guilds[j].GuildID
+	})
+
+	return guilds, nil
+}
+
+func (s *guildStore) Get(guildID string) (models.Guild, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return models.Guild{}, store.ErrNotFound
+	}
+	return guild, nil
+}
+
+func (s *guildStore) Create(guild models.Guild) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[guild.GuildID]; ok {
+		return conflict("guild %s", guild.GuildID)
+	}
+	s.guilds[guild.GuildID] = guild
+	return nil
+}
+
+func (s *guildStore) Update(guild models.Guild) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[guild.GuildID]; ok {
+		s.guilds[guild.GuildID] = guild
+	}
+	return nil
+}
+
+func (s *guildStore) Reset(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guild, ok := s.guilds[guildID]
+	if !ok {
+		return nil
+	}
+
+	// Same default values as the sql schema
+	guild.Prefix = "!"
+	guild.ReportChannel = nulltype.NullString{}
+	guild.WelcomeChannel = nulltype.NullString{}
+	guild.WelcomeMsg = nulltype.NullString{}
+	guild.PrivateWelcomeMsg = nulltype.NullString{}
+	guild.LvlChannel = nulltype.NullString{}
+	guild.LvlResponse = 1
+	guild.LvlReplace = false
+	guild.AllowModeration = true
+	guild.MaxWarns = 3
+	guild.BanTime = 0
+	guild.LvlCurve = models.LevelCurve{}
+	guild.XpCooldown = 0
+	guild.XpStacking = models.MaxStacking
+	s.guilds[guildID] = guild
+
+	return nil
+}
+
+func (s *guildStore) Delete(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[guildID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.guilds, guildID)
+
+	// ON DELETE CASCADE
+	for k := range s.members {
+		if k.guildID == guildID {
+			delete(s.members, k)
+		}
+	}
+	for k := range s.roles {
+		if k.guildID == guildID {
+			delete(s.roles, k)
+		}
+	}
+	for k := range s.channels {
+		if k.guildID == guildID {
+			delete(s.channels, k)
+		}
+	}
+	for id, w := range s.warns {
+		if w.GuildID == guildID {
+			delete(s.warns, id)
+		}
+	}
+	for id, b := range s.bans {
+		if b.GuildID == guildID {
+			delete(s.bans, id)
+		}
+	}
+	delete(s.cases, guildID)
+	for k := range s.access {
+		if k.guildID == guildID {
+			delete(s.access, k)
+		}
+	}
+
+	return nil
+}
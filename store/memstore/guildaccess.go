@@ -0,0 +1,84 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type guildAccessStore struct {
+	*memory
+}
+
+func (s *guildAccessStore) List(username string) ([]models.GuildAccess, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accesses := []models.GuildAccess{}
+	for k, a := range s.access {
+		if k.id == username {
+			accesses = append(accesses, a)
+		}
+	}
+	sort.Slice(accesses, func(i, j int) bool {
+		return accesses[i].GuildID < accesses[j].GuildID
+	})
+
+	return accesses, nil
+}
+
+func (s *guildAccessStore) ListGuild(guildID string) ([]models.GuildAccess, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	accesses := []models.GuildAccess{}
+	for k, a := range s.access {
+		if k.guildID == guildID {
+			accesses = append(accesses, a)
+		}
+	}
+	sort.Slice(accesses, func(i, j int) bool {
+		return accesses[i].Username < accesses[j].Username
+	})
+
+	return accesses, nil
+}
+
+func (s *guildAccessStore) Get(guildID string, username string) (models.GuildAccess, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	access, ok := s.access[key{guildID, username}]
+	if !ok {
+		return models.GuildAccess{}, store.ErrNotFound
+	}
+	return access, nil
+}
+
+func (s *guildAccessStore) Set(access models.GuildAccess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkGuild(access.GuildID); err != nil {
+		return err
+	}
+	if _, ok := s.users[access.Username]; !ok {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", access.Username)
+	}
+	s.access[key{access.GuildID, access.Username}] = access
+	return nil
+}
+
+func (s *guildAccessStore) Delete(guildID string, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, username}
+	if _, ok := s.access[k]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.access, k)
+	return nil
+}
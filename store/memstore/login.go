@@ -0,0 +1,39 @@
+package memstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type loginHistoryStore struct {
+	*memory
+}
+
+func (s *loginHistoryStore) Create(attempt *models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loginSeq++
+	attempt.AttemptID = s.loginSeq
+	s.logins = append(s.logins, *attempt)
+	return nil
+}
+
+func (s *loginHistoryStore) List(filter store.LoginFilter) ([]models.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attempts := []models.LoginAttempt{}
+	for i := len(s.logins) - 1; i >= 0 && len(attempts) < filter.Limit; i-- {
+		a := s.logins[i]
+		if (filter.Before > 0 && a.AttemptID >= filter.Before) ||
+			(filter.Username != "" && a.Username != filter.Username) ||
+			(filter.IP != "" && a.IP != filter.IP) ||
+			(filter.Failed && a.Success) {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,242 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type memberStore struct {
+	*memory
+}
+
+func (s *memberStore) List(guildID string, after string, limit int) ([]models.Member, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid limit %d", limit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []models.Member{}
+	for k, m := range s.members {
+		// member_id is a varchar, so the cursor compares strings as sql does.
+		if k.guildID == guildID && k.id > after {
+			members = append(members, m)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].MemberID < members[j].MemberID
+	})
+	if len(members) > limit {
+		members = members[:limit]
+	}
+
+	return members, nil
+}
+
+func (s *memberStore) Get(guildID string, memberID string) (models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[key{guildID, memberID}]
+	if !ok {
+		return models.Member{}, store.ErrNotFound
+	}
+	return member, nil
+}
+
+func (s *memberStore) Create(member models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{member.GuildID, member.MemberID}
+	if _, ok := s.members[k]; ok {
+		return conflict("member %s of guild %s", member.MemberID, member.GuildID)
+	}
+	if err := s.checkGuild(member.GuildID); err != nil {
+		return err
+	}
+	s.members[k] = member
+	return nil
+}
+
+func (s *memberStore) Update(member models.Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{member.GuildID, member.MemberID}
+	if prev, ok := s.members[k]; ok {
+		prev.Left = member.Left
+		prev.Xp = member.Xp
+		prev.Level = member.Level
+		s.members[k] = prev
+	}
+	return nil
+}
+
+func (s *memberStore) Reset(guildID string, memberID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, memberID}
+	if m, ok := s.members[k]; ok {
+		s.members[k] = reset(m)
+	}
+	return nil
+}
+
+func (s *memberStore) ResetGuild(guildID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, m := range s.members {
+		if k.guildID == guildID {
+			s.members[k] = reset(m)
+		}
+	}
+	return nil
+}
+
+func (s *memberStore) Delete(guildID string, memberID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, memberID}
+	if _, ok := s.members[k]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.members, k)
+	return nil
+}
+
+func reset(m models.Member) models.Member {
+	m.Left = 0
+	m.Xp = 0
+	m.Level = 0
+	return m
+}
+
+func (s *memberStore) Leaderboard(guildID string, after models.LeaderboardCursor, offset int, limit int) ([]models.RankedMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []models.Member{}
+	for k, m := range s.members {
+		if k.guildID == guildID {
+			members = append(members, m)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Xp != members[j].Xp {
+			return members[i].Xp > members[j].Xp
+		}
+		return members[i].MemberID > members[j].MemberID
+	})
+
+	before := offset
+	if after.MemberID != "" {
+		before = sort.Search(len(members), func(i int) bool {
+			m := members[i]
+			return m.Xp < after.Xp || (m.Xp == after.Xp && m.MemberID < after.MemberID)
+		})
+	}
+	if before > len(members) {
+		before = len(members)
+	}
+	page := members[before:]
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	if len(page) == 0 {
+		return []models.RankedMember{}, nil
+	}
+
+	above := 0
+	for _, m := range members {
+		if m.Xp > page[0].Xp {
+			above++
+		}
+	}
+	return models.Rank(page, before, above), nil
+}
+
+func (s *memberStore) Count(guildID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total := 0
+	for k := range s.members {
+		if k.guildID == guildID {
+			total++
+		}
+	}
+	return total, nil
+}
+
+func (s *memberStore) Rank(guildID string, memberID string) (models.RankedMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[key{guildID, memberID}]
+	if !ok {
+		return models.RankedMember{}, store.ErrNotFound
+	}
+	return models.RankedMember{Rank: s.above(guildID, member.Xp) + 1, Member: member}, nil
+}
+
+// above returns the number of members of the guild with more xp.
+func (s *memberStore) above(guildID string, xp int) int {
+	n := 0
+	for k, m := range s.members {
+		if k.guildID == guildID && m.Xp > xp {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *memberStore) SetLevel(guildID string, memberID string, xp int, level int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, memberID}
+	member, ok := s.members[k]
+	if !ok || member.Xp != xp {
+		return store.ErrNotFound
+	}
+	member.Level = level
+	s.members[k] = member
+	return nil
+}
+
+func (s *memberStore) AddXp(guildID string, memberID string, xp int, at time.Time, cooldown time.Duration, levelOf func(xp int) int) (models.Member, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, memberID}
+	member, ok := s.members[k]
+	if !ok {
+		if err := s.checkGuild(guildID); err != nil {
+			return member, 0, err
+		}
+		member = models.Member{GuildID: guildID, MemberID: memberID}
+	}
+
+	if cooldown > 0 {
+		if member.LastXpAt.Valid() && member.LastXpAt.TimeValue().After(at.Add(-cooldown)) {
+			return member, member.Level, store.ErrCooldown
+		}
+		member.LastXpAt = nulltype.NullTimeOf(at)
+	}
+
+	previous := member.Level
+	member.Xp += xp
+	member.Level = levelOf(member.Xp)
+	s.members[k] = member
+	return member, previous, nil
+}
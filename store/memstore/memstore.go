@@ -0,0 +1,101 @@
+package memstore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type (
+	// memory holds every table of the in-memory store behind a single lock,
+	// so that cascading deletes stay consistent.
+	memory struct {
+		mu            sync.RWMutex
+		guilds        map[string]models.Guild
+		members       map[key]models.Member
+		warns         map[int]models.Warn
+		bans          map[int]models.Ban
+		cases         map[string][]models.Case // keyed by guild id, ordered by case number
+		roles         map[key]models.Role
+		channels      map[key]models.Channel
+		users         map[string]models.User
+		tokens        map[string]models.RefreshToken
+		sessions      map[int]models.Session
+		userTokens    map[string]models.UserToken
+		recoveryCodes map[string][]models.RecoveryCode // keyed by username
+		apiKeys       map[int]models.ApiKey
+		signingKeys   map[string]models.SigningKey
+		logins        []models.LoginAttempt
+		access        map[key]models.GuildAccess // keyed by guild id and username
+
+		// Last auto increment values of warn, ban, case, api key, session and login attempt ids
+		warnSeq    int
+		banSeq     int
+		caseSeq    int
+		apiKeySeq  int
+		sessionSeq int
+		loginSeq   int
+	}
+
+	// key identifies an entity belonging to a guild.
+	key struct {
+		guildID string
+		id      string
+	}
+)
+
+// New returns an empty store keeping every value in memory.
+// It mimics the constraints of the sql schema and is meant for tests and local development.
+func New() *store.Store {
+	m := &memory{
+		guilds:        map[string]models.Guild{},
+		members:       map[key]models.Member{},
+		warns:         map[int]models.Warn{},
+		bans:          map[int]models.Ban{},
+		cases:         map[string][]models.Case{},
+		roles:         map[key]models.Role{},
+		channels:      map[key]models.Channel{},
+		users:         map[string]models.User{},
+		tokens:        map[string]models.RefreshToken{},
+		sessions:      map[int]models.Session{},
+		userTokens:    map[string]models.UserToken{},
+		recoveryCodes: map[string][]models.RecoveryCode{},
+		apiKeys:       map[int]models.ApiKey{},
+		signingKeys:   map[string]models.SigningKey{},
+		access:        map[key]models.GuildAccess{},
+	}
+
+	return &store.Store{
+		Guilds:        &guildStore{m},
+		Members:       &memberStore{m},
+		Warns:         &warnStore{m},
+		Bans:          &banStore{m},
+		Cases:         &caseStore{m},
+		Roles:         &roleStore{m},
+		Channels:      &channelStore{m},
+		Users:         &userStore{m},
+		Tokens:        &tokenStore{m},
+		Sessions:      &sessionStore{m},
+		ApiKeys:       &apiKeyStore{m},
+		SigningKeys:   &signingKeyStore{m},
+		UserTokens:    &userTokenStore{m},
+		RecoveryCodes: &recoveryCodeStore{m},
+		Logins:        &loginHistoryStore{m},
+		Access:        &guildAccessStore{m},
+	}
+}
+
+// checkGuild mimics the foreign key constraint of guild owned tables.
+// The caller must hold the lock.
+func (m *memory) checkGuild(guildID string) error {
+	if _, ok := m.guilds[guildID]; !ok {
+		return fmt.Errorf("foreign key constraint fails: guild %s does not exist", guildID)
+	}
+	return nil
+}
+
+func conflict(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{store.ErrConflict}, args...)...)
+}
@@ -0,0 +1,15 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"github.com/gyroskan/cardinal/store"
+	"github.com/gyroskan/cardinal/store/memstore"
+	"github.com/gyroskan/cardinal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) *store.Store {
+		return memstore.New()
+	})
+}
@@ -0,0 +1,51 @@
+package memstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type recoveryCodeStore struct {
+	*memory
+}
+
+func (s *recoveryCodeStore) Replace(username string, codes []models.RecoveryCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok && len(codes) > 0 {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", username)
+	}
+	s.recoveryCodes[username] = append([]models.RecoveryCode{}, codes...)
+	return nil
+}
+
+func (s *recoveryCodeStore) Use(username string, codeHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, code := range s.recoveryCodes[username] {
+		if code.CodeHash == codeHash && !code.UsedAt.Valid() {
+			s.recoveryCodes[username][i].UsedAt = nulltype.NullTimeOf(time.Now().UTC())
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (s *recoveryCodeStore) Remaining(username string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, code := range s.recoveryCodes[username] {
+		if !code.UsedAt.Valid() {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type roleStore struct {
+	*memory
+}
+
+func (s *roleStore) List(guildID string, filter store.RoleFilter) ([]models.Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := []models.Role{}
+	for k, r := range s.roles {
+		if k.guildID != guildID ||
+			(filter.Ignored && !r.Ignored) ||
+			(filter.XpBlacklisted && !r.XpBlacklisted) ||
+			(filter.Reward != 0 && r.Reward != filter.Reward) {
+			continue
+		}
+		roles = append(roles, r)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].RoleID < roles[j].RoleID
+	})
+
+	return roles, nil
+}
+
+func (s *roleStore) Get(guildID string, roleID string) (models.Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, ok := s.roles[key{guildID, roleID}]
+	if !ok {
+		return models.Role{}, store.ErrNotFound
+	}
+	return role, nil
+}
+
+func (s *roleStore) Create(role models.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{role.GuildID, role.RoleID}
+	if _, ok := s.roles[k]; ok {
+		return conflict("role %s of guild %s", role.RoleID, role.GuildID)
+	}
+	if err := s.checkGuild(role.GuildID); err != nil {
+		return err
+	}
+	s.roles[k] = role
+	return nil
+}
+
+func (s *roleStore) Update(role models.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{role.GuildID, role.RoleID}
+	if _, ok := s.roles[k]; ok {
+		s.roles[k] = role
+	}
+	return nil
+}
+
+func (s *roleStore) Delete(guildID string, roleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{guildID, roleID}
+	if _, ok := s.roles[k]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.roles, k)
+	return nil
+}
@@ -0,0 +1,110 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type sessionStore struct {
+	*memory
+}
+
+func (s *sessionStore) List(username string, now time.Time) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.Username == username && !session.RevokedAt.Valid() && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+func (s *sessionStore) Get(sessionID int) (models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return session, store.ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *sessionStore) Create(session *models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[session.Username]; !ok {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", session.Username)
+	}
+	s.sessionSeq++
+	session.SessionID = s.sessionSeq
+	s.sessions[session.SessionID] = *session
+	return nil
+}
+
+func (s *sessionStore) Touch(sessionID int, at time.Time, ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	session.LastUsedAt = at
+	session.IP = ip
+	s.sessions[sessionID] = session
+	return nil
+}
+
+func (s *sessionStore) Extend(sessionID int, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	session.ExpiresAt = expiresAt
+	s.sessions[sessionID] = session
+	return nil
+}
+
+func (s *sessionStore) Revoke(username string, sessionID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.Username != username || session.RevokedAt.Valid() {
+		return store.ErrNotFound
+	}
+	session.RevokedAt = nulltype.NullTimeOf(time.Now().UTC())
+	s.sessions[sessionID] = session
+	return nil
+}
+
+func (s *sessionStore) RevokeAll(username string, except int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, session := range s.sessions {
+		if session.Username == username && id != except && !session.RevokedAt.Valid() {
+			session.RevokedAt = nulltype.NullTimeOf(now)
+			s.sessions[id] = session
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type signingKeyStore struct {
+	*memory
+}
+
+func (s *signingKeyStore) List() ([]models.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.SigningKey{}
+	for _, k := range s.signingKeys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func (s *signingKeyStore) Create(key models.SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.signingKeys[key.KeyID]; ok {
+		return conflict("signing key")
+	}
+	s.signingKeys[key.KeyID] = key
+	return nil
+}
+
+func (s *signingKeyStore) Delete(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.signingKeys[keyID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.signingKeys, keyID)
+	return nil
+}
@@ -0,0 +1,97 @@
+package memstore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type tokenStore struct {
+	*memory
+}
+
+func (s *tokenStore) Create(token models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tokens[token.TokenHash]; ok {
+		return conflict("refresh token")
+	}
+	if _, ok := s.users[token.Username]; !ok {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", token.Username)
+	}
+	s.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (s *tokenStore) Get(tokenHash string) (models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[tokenHash]
+	if !ok {
+		return models.RefreshToken{}, store.ErrNotFound
+	}
+	return token, nil
+}
+
+func (s *tokenStore) ListActive(username string, now time.Time) ([]models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []models.RefreshToken{}
+	for _, token := range s.tokens {
+		if token.Username == username && !token.RevokedAt.Valid() && token.ExpiresAt.After(now) {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (s *tokenStore) Revoke(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[tokenHash]
+	if !ok || token.RevokedAt.Valid() {
+		return store.ErrNotFound
+	}
+	token.RevokedAt = nulltype.NullTimeOf(time.Now().UTC())
+	s.tokens[tokenHash] = token
+	return nil
+}
+
+func (s *tokenStore) RevokeAll(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := nulltype.NullTimeOf(time.Now().UTC())
+	for hash, t := range s.tokens {
+		if t.Username == username && !t.RevokedAt.Valid() {
+			t.RevokedAt = now
+			s.tokens[hash] = t
+		}
+	}
+	return nil
+}
+
+func (s *tokenStore) RevokeOthers(username string, sessionID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := nulltype.NullTimeOf(time.Now().UTC())
+	for hash, t := range s.tokens {
+		if t.Username == username && int(t.SessionID.Int64Value()) != sessionID && !t.RevokedAt.Valid() {
+			t.RevokedAt = now
+			s.tokens[hash] = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package memstore
+
+import (
+	"sort"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type userStore struct {
+	*memory
+}
+
+func (s *userStore) All() ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]models.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+func (s *userStore) Get(username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, store.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *userStore) GetByDiscordID(discordID string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.DiscordVerified && u.DiscordID.StringValue() == discordID {
+			return u, nil
+		}
+	}
+	return models.User{}, store.ErrNotFound
+}
+
+func (s *userStore) ListByEmail(email string) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []models.User{}
+	for _, u := range s.users {
+		if u.Email == email {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+
+	return users, nil
+}
+
+func (s *userStore) Create(user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.Username]; ok {
+		return conflict("user %s", user.Username)
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *userStore) Update(user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.users[user.Username]; ok {
+		prev.Email = user.Email
+		prev.EmailVerified = user.EmailVerified
+		prev.DiscordID = user.DiscordID
+		prev.DiscordVerified = user.DiscordVerified
+		prev.PasswordHash = user.PasswordHash
+		prev.Salt = user.Salt
+		s.users[user.Username] = prev
+	}
+	return nil
+}
+
+func (s *userStore) SetAccessLvl(username string, lvl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.AccessLvl = lvl
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) SetBanned(username string, banned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.Banned = banned
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) SetRoles(username string, roles string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.Roles = roles
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) SetEmailVerified(username string, verified bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.EmailVerified = verified
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) SetTotp(username string, secret nulltype.NullString, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.TotpSecret = secret
+		user.TotpEnabled = enabled
+		user.TotpLastStep = 0
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) UseTotpStep(username string, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok || user.TotpLastStep >= step {
+		return store.ErrNotFound
+	}
+	user.TotpLastStep = step
+	s.users[username] = user
+	return nil
+}
+
+func (s *userStore) LinkDiscord(username string, discordID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, u := range s.users {
+		if name != username && !u.DiscordVerified && u.DiscordID.StringValue() == discordID {
+			u.DiscordID = nulltype.NullString{}
+			s.users[name] = u
+		}
+	}
+	if user, ok := s.users[username]; ok {
+		user.DiscordID = nulltype.NullStringOf(discordID)
+		user.DiscordVerified = true
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) IncrTokenVersion(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.TokenVersion++
+		s.users[username] = user
+	}
+	return nil
+}
+
+func (s *userStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.users, username)
+
+	// ON DELETE CASCADE
+	for hash, t := range s.tokens {
+		if t.Username == username {
+			delete(s.tokens, hash)
+		}
+	}
+	for id, session := range s.sessions {
+		if session.Username == username {
+			delete(s.sessions, id)
+		}
+	}
+	delete(s.recoveryCodes, username)
+	for hash, t := range s.userTokens {
+		if t.Username == username {
+			delete(s.userTokens, hash)
+		}
+	}
+	for id, k := range s.apiKeys {
+		if k.Username == username {
+			delete(s.apiKeys, id)
+		}
+	}
+	for k := range s.access {
+		if k.id == username {
+			delete(s.access, k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package memstore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type userTokenStore struct {
+	*memory
+}
+
+func (s *userTokenStore) Create(token models.UserToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userTokens[token.TokenHash]; ok {
+		return conflict("user token")
+	}
+	if _, ok := s.users[token.Username]; !ok {
+		return fmt.Errorf("foreign key constraint fails: user %s does not exist", token.Username)
+	}
+	s.userTokens[token.TokenHash] = token
+	return nil
+}
+
+func (s *userTokenStore) Get(tokenHash string) (models.UserToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.userTokens[tokenHash]
+	if !ok {
+		return models.UserToken{}, store.ErrNotFound
+	}
+	return token, nil
+}
+
+func (s *userTokenStore) Use(tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.userTokens[tokenHash]
+	if !ok || token.UsedAt.Valid() {
+		return store.ErrNotFound
+	}
+	token.UsedAt = nulltype.NullTimeOf(time.Now().UTC())
+	s.userTokens[tokenHash] = token
+	return nil
+}
+
+func (s *userTokenStore) UseAll(username string, purpose string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := nulltype.NullTimeOf(time.Now().UTC())
+	for hash, t := range s.userTokens {
+		if t.Username == username && t.Purpose == purpose && !t.UsedAt.Valid() {
+			t.UsedAt = now
+			s.userTokens[hash] = t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package memstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type warnStore struct {
+	*memory
+}
+
+func (s *warnStore) List(guildID string, memberID string) ([]models.Warn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	warns := []models.Warn{}
+	for _, w := range s.warns {
+		if w.GuildID == guildID && w.MemberID == memberID {
+			warns = append(warns, w)
+		}
+	}
+	sort.Slice(warns, func(i, j int) bool {
+		return warns[i].WarnID < warns[j].WarnID
+	})
+
+	return warns, nil
+}
+
+func (s *warnStore) Get(guildID string, memberID string, warnID int) (models.Warn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	warn, ok := s.warns[warnID]
+	if !ok || warn.GuildID != guildID || warn.MemberID != memberID {
+		return models.Warn{}, store.ErrNotFound
+	}
+	return warn, nil
+}
+
+func (s *warnStore) Create(warn *models.Warn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.guilds[warn.GuildID]; !ok {
+		return store.ErrNotFound
+	}
+	s.warnSeq++
+	warn.WarnID = s.warnSeq
+	s.warns[warn.WarnID] = *warn
+	modCase := warn.Case()
+	s.addCase(&modCase)
+	return nil
+}
+
+func (s *warnStore) CreateEscalating(warn *models.Warn, escalate func(guild models.Guild, warns int) *models.Ban) (*models.Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guild, ok := s.guilds[warn.GuildID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	if warn.WarnedAt.IsZero() {
+		warn.WarnedAt = time.Now().UTC()
+	}
+	s.warnSeq++
+	warn.WarnID = s.warnSeq
+	s.warns[warn.WarnID] = *warn
+	modCase := warn.Case()
+	s.addCase(&modCase)
+
+	warns := 0
+	for _, w := range s.warns {
+		if w.GuildID == warn.GuildID && w.MemberID == warn.MemberID && !s.bannedSince(w) {
+			warns++
+		}
+	}
+	ban := escalate(guild, warns)
+	if ban != nil {
+		s.banSeq++
+		ban.BanID = s.banSeq
+		s.bans[ban.BanID] = *ban
+		modCase := ban.Case()
+		s.addCase(&modCase)
+	}
+	return ban, nil
+}
+
+// bannedSince returns whether the member was banned after the warn.
+func (s *warnStore) bannedSince(w models.Warn) bool {
+	for _, b := range s.bans {
+		if b.GuildID == w.GuildID && b.MemberID == w.MemberID && !b.BannedAt.Before(w.WarnedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *warnStore) Delete(guildID string, memberID string, warnID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	warn, ok := s.warns[warnID]
+	if !ok || warn.GuildID != guildID || warn.MemberID != memberID {
+		return store.ErrNotFound
+	}
+	delete(s.warns, warnID)
+
+	// ON DELETE SET NULL
+	for i, c := range s.cases[guildID] {
+		if c.WarnID.Valid() && int(c.WarnID.Int64Value()) == warnID {
+			s.cases[guildID][i].WarnID = nulltype.NullInt64{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type apiKeyStore struct {
+	*conn
+}
+
+func (s *apiKeyStore) List(username string) ([]models.ApiKey, error) {
+	keys := []models.ApiKey{}
+	err := s.db.Select(&keys, s.query(models.SelectUserApiKeysQuery), username)
+	return keys, classify(err)
+}
+
+func (s *apiKeyStore) Get(keyHash string) (models.ApiKey, error) {
+	var key models.ApiKey
+	err := s.db.Get(&key, s.query(models.SelectApiKeyQuery), keyHash)
+	return key, classify(err)
+}
+
+func (s *apiKeyStore) Create(key *models.ApiKey) error {
+	id, err := s.insert(s.db, models.InsertApiKeyQuery, key, "key_id")
+	if err != nil {
+		return err
+	}
+	key.KeyID = id
+
+	return nil
+}
+
+func (s *apiKeyStore) Touch(keyID int, at time.Time) error {
+	return deleted(s.db.Exec(s.query(models.UpdateApiKeyUsedQuery), at, keyID))
+}
+
+func (s *apiKeyStore) Delete(username string, keyID int) error {
+	return deleted(s.db.Exec(s.query(models.DeleteApiKeyQuery), username, keyID))
+}
@@ -0,0 +1,132 @@
+package sqlstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type banStore struct {
+	*conn
+}
+
+func (s *banStore) List(guildID string, memberID string) ([]models.Ban, error) {
+	bans := []models.Ban{}
+	err := s.db.Select(&bans, s.query(models.SelectMemberBansQuery), guildID, memberID)
+	return bans, classify(err)
+}
+
+func (s *banStore) Get(guildID string, memberID string, banID int) (models.Ban, error) {
+	var ban models.Ban
+	err := s.db.Get(&ban, s.query(models.SelectBanQuery), guildID, memberID, banID)
+	return ban, classify(err)
+}
+
+func (s *banStore) Create(ban *models.Ban) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockGuild(tx, ban.GuildID); err != nil {
+		return err
+	}
+	id, err := s.insert(tx, models.CreateBanQuery, ban, "ban_id")
+	if err != nil {
+		return err
+	}
+	ban.BanID = id
+	modCase := ban.Case()
+	if err := s.insertCase(tx, &modCase); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *banStore) Lift(guildID string, memberID string, banID int, liftedBy nulltype.NullString, reason nulltype.NullString, at time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockGuild(tx, guildID); err != nil {
+		return err
+	}
+	var ban models.Ban
+	if err := tx.Get(&ban, s.query(models.SelectBanQuery), guildID, memberID, banID); err != nil {
+		return classify(err)
+	}
+	if err := deleted(tx.Exec(s.query(models.LiftBanQuery), at, liftedBy, guildID, memberID, banID)); err != nil {
+		return err
+	}
+	ban.LiftedAt, ban.LiftedBy = nulltype.NullTimeOf(at), liftedBy
+	modCase := ban.UnbanCase(reason)
+	if err := s.insertCase(tx, &modCase); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *banStore) LiftExpired(at time.Time) ([]models.Ban, error) {
+	expired := []models.Ban{}
+	if err := s.db.Select(&expired, s.query(models.SelectExpiredBansQuery), at); err != nil {
+		return nil, classify(err)
+	}
+
+	lifted := []models.Ban{}
+	for _, ban := range expired {
+		err := s.liftExpired(&ban, at)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return lifted, err
+		}
+		lifted = append(lifted, ban)
+	}
+	return lifted, nil
+}
+
+// liftExpired lifts the expired ban with its unban case,
+// returning ErrNotFound if another instance of the api lifted it meanwhile.
+func (s *banStore) liftExpired(ban *models.Ban, at time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockGuild(tx, ban.GuildID); err != nil {
+		return err
+	}
+	if err := deleted(tx.Exec(s.query(models.LiftExpiredBanQuery), at, ban.BanID)); err != nil {
+		return err
+	}
+	ban.LiftedAt = nulltype.NullTimeOf(at)
+	modCase := ban.UnbanCase(nulltype.NullStringOf(models.ExpiredBanReason))
+	if err := s.insertCase(tx, &modCase); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *banStore) PendingUnbans(guildID string, limit int) ([]models.Ban, error) {
+	bans := []models.Ban{}
+	err := s.db.Select(&bans, s.query(models.SelectPendingUnbansQuery), guildID, limit)
+	return bans, classify(err)
+}
+
+func (s *banStore) SetUnbanned(guildID string, banID int, at time.Time) error {
+	return deleted(s.db.Exec(s.query(models.SetUnbannedQuery), at, guildID, banID))
+}
+
+func (s *banStore) Delete(guildID string, memberID string, banID int) error {
+	return deleted(s.db.Exec(s.query(models.DeleteBanQuery), guildID, memberID, banID))
+}
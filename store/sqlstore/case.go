@@ -0,0 +1,124 @@
+package sqlstore
+
+import (
+	"math"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-nulltype"
+)
+
+type caseStore struct {
+	*conn
+}
+
+func (s *caseStore) List(guildID string, filter store.CaseFilter) ([]models.Case, error) {
+	query := models.SelectCasesQuery
+	before := filter.Before
+	if before <= 0 {
+		before = math.MaxInt32
+	}
+	args := []interface{}{guildID, before}
+	if filter.MemberID != "" {
+		query += " AND member_id=?"
+		args = append(args, filter.MemberID)
+	}
+	if filter.ModeratorID != "" {
+		query += " AND moderator_id=?"
+		args = append(args, filter.ModeratorID)
+	}
+	if filter.Type != "" {
+		query += " AND case_type=?"
+		args = append(args, filter.Type)
+	}
+	if !filter.From.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.To)
+	}
+	query += " ORDER BY case_number DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	cases := []models.Case{}
+	err := s.db.Select(&cases, s.query(query), args...)
+	return cases, classify(err)
+}
+
+func (s *caseStore) Get(guildID string, caseNumber int) (models.Case, error) {
+	var c models.Case
+	err := s.db.Get(&c, s.query(models.SelectCaseQuery), guildID, caseNumber)
+	return c, classify(err)
+}
+
+func (s *caseStore) Create(c *models.Case) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockGuild(tx, c.GuildID); err != nil {
+		return err
+	}
+	if err := s.insertCase(tx, c); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockGuild locks the guild until the end of the transaction, so that its cases are numbered one at a time,
+// and returns it, or ErrNotFound if it does not exist.
+// It must precede the other writes of the transaction.
+func (c *conn) lockGuild(tx *sqlx.Tx, guildID string) (models.Guild, error) {
+	var guild models.Guild
+	if _, err := tx.Exec(c.query(models.LockGuildQuery), guildID); err != nil {
+		return guild, classify(err)
+	}
+	err := tx.Get(&guild, c.query(models.SelectGuildQuery), guildID)
+	return guild, classify(err)
+}
+
+// insertCase inserts the case with the next case number of its guild, locked by lockGuild,
+// and sets its CaseID and CaseNumber.
+func (c *conn) insertCase(tx *sqlx.Tx, modCase *models.Case) error {
+	if err := tx.Get(&modCase.CaseNumber, c.query(models.NextCaseNumberQuery), modCase.GuildID); err != nil {
+		return classify(err)
+	}
+	id, err := c.insert(tx, models.CreateCaseQuery, modCase, "case_id")
+	if err != nil {
+		return err
+	}
+	modCase.CaseID = id
+	return nil
+}
+
+func (s *caseStore) SetReason(guildID string, caseNumber int, reason nulltype.NullString, at time.Time) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Checked apart, mysql does not count the rows left unchanged as affected.
+	var c models.Case
+	if err := tx.Get(&c, s.query(models.SelectCaseQuery), guildID, caseNumber); err != nil {
+		return classify(err)
+	}
+	if _, err := tx.Exec(s.query(models.UpdateCaseReasonQuery), reason, at, guildID, caseNumber); err != nil {
+		return classify(err)
+	}
+	if _, err := tx.Exec(s.query(models.UpdateCaseWarnReasonQuery), reason, guildID, caseNumber); err != nil {
+		return classify(err)
+	}
+	if _, err := tx.Exec(s.query(models.UpdateCaseBanReasonQuery), reason, guildID, caseNumber); err != nil {
+		return classify(err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+package sqlstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type channelStore struct {
+	*conn
+}
+
+func (s *channelStore) List(guildID string, filter store.ChannelFilter) ([]models.Channel, error) {
+	query := models.SelectGuildChannelsQuery
+	if filter.Ignored {
+		query += " AND ignored=true"
+	}
+	if filter.XpBlacklisted {
+		query += " AND xp_blacklisted=true"
+	}
+
+	channels := []models.Channel{}
+	err := s.db.Select(&channels, s.query(query), guildID)
+	return channels, classify(err)
+}
+
+func (s *channelStore) Get(guildID string, channelID string) (models.Channel, error) {
+	var channel models.Channel
+	err := s.db.Get(&channel, s.query(models.SelectChannelQuery), guildID, channelID)
+	return channel, classify(err)
+}
+
+func (s *channelStore) Create(channel models.Channel) error {
+	_, err := s.db.NamedExec(s.query(models.CreateChannelQuery), channel)
+	return classify(err)
+}
+
+func (s *channelStore) Update(channel models.Channel) error {
+	_, err := s.db.NamedExec(s.query(models.UpdateChannelQuery), channel)
+	return classify(err)
+}
+
+func (s *channelStore) Delete(guildID string, channelID string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteChannelQuery), guildID, channelID))
+}
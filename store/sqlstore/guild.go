@@ -0,0 +1,40 @@
+package sqlstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+)
+
+type guildStore struct {
+	*conn
+}
+
+func (s *guildStore) All() ([]models.Guild, error) {
+	guilds := []models.Guild{}
+	err := s.db.Select(&guilds, s.query(models.SelectGuildsQuery))
+	return guilds, classify(err)
+}
+
+func (s *guildStore) Get(guildID string) (models.Guild, error) {
+	var guild models.Guild
+	err := s.db.Get(&guild, s.query(models.SelectGuildQuery), guildID)
+	return guild, classify(err)
+}
+
+func (s *guildStore) Create(guild models.Guild) error {
+	_, err := s.db.NamedExec(s.query(models.CreateGuildQuery), guild)
+	return classify(err)
+}
+
+func (s *guildStore) Update(guild models.Guild) error {
+	_, err := s.db.NamedExec(s.query(models.UpdateGuildQuery), guild)
+	return classify(err)
+}
+
+func (s *guildStore) Reset(guildID string) error {
+	_, err := s.db.Exec(s.query(models.ResetGuildQuery), guildID)
+	return classify(err)
+}
+
+func (s *guildStore) Delete(guildID string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteGuildQuery), guildID))
+}
@@ -0,0 +1,52 @@
+package sqlstore
+
+import (
+	"errors"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type guildAccessStore struct {
+	*conn
+}
+
+func (s *guildAccessStore) List(username string) ([]models.GuildAccess, error) {
+	accesses := []models.GuildAccess{}
+	err := s.db.Select(&accesses, s.query(models.SelectUserGuildAccessesQuery), username)
+	return accesses, classify(err)
+}
+
+func (s *guildAccessStore) ListGuild(guildID string) ([]models.GuildAccess, error) {
+	accesses := []models.GuildAccess{}
+	err := s.db.Select(&accesses, s.query(models.SelectGuildAccessesQuery), guildID)
+	return accesses, classify(err)
+}
+
+func (s *guildAccessStore) Get(guildID string, username string) (models.GuildAccess, error) {
+	var access models.GuildAccess
+	err := s.db.Get(&access, s.query(models.SelectGuildAccessQuery), guildID, username)
+	return access, classify(err)
+}
+
+func (s *guildAccessStore) Set(access models.GuildAccess) error {
+	// MySQL reports no affected row when the values are unchanged,
+	// so a conflicting insert means the access is already set.
+	res, err := s.db.NamedExec(s.query(models.UpdateGuildAccessQuery), access)
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	_, err = s.db.NamedExec(s.query(models.InsertGuildAccessQuery), access)
+	if err = classify(err); errors.Is(err, store.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+func (s *guildAccessStore) Delete(guildID string, username string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteGuildAccessQuery), guildID, username))
+}
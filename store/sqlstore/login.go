@@ -0,0 +1,48 @@
+package sqlstore
+
+import (
+	"math"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type loginHistoryStore struct {
+	*conn
+}
+
+func (s *loginHistoryStore) Create(attempt *models.LoginAttempt) error {
+	id, err := s.insert(s.db, models.InsertLoginAttemptQuery, attempt, "attempt_id")
+	if err != nil {
+		return err
+	}
+	attempt.AttemptID = id
+
+	return nil
+}
+
+func (s *loginHistoryStore) List(filter store.LoginFilter) ([]models.LoginAttempt, error) {
+	query := models.SelectLoginHistoryQuery
+	before := filter.Before
+	if before <= 0 {
+		before = math.MaxInt32
+	}
+	args := []interface{}{before}
+	if filter.Username != "" {
+		query += " AND username=?"
+		args = append(args, filter.Username)
+	}
+	if filter.IP != "" {
+		query += " AND ip=?"
+		args = append(args, filter.IP)
+	}
+	if filter.Failed {
+		query += " AND success=false"
+	}
+	query += " ORDER BY attempt_id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	attempts := []models.LoginAttempt{}
+	err := s.db.Select(&attempts, s.query(query), args...)
+	return attempts, classify(err)
+}
@@ -0,0 +1,155 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+type memberStore struct {
+	*conn
+}
+
+func (s *memberStore) List(guildID string, after string, limit int) ([]models.Member, error) {
+	members := []models.Member{}
+	err := s.db.Select(&members, s.query(models.SelectGuildMembersQuery), guildID, after, limit)
+	return members, classify(err)
+}
+
+func (s *memberStore) Get(guildID string, memberID string) (models.Member, error) {
+	var member models.Member
+	err := s.db.Get(&member, s.query(models.SelectMemberQuery), guildID, memberID)
+	return member, classify(err)
+}
+
+func (s *memberStore) Create(member models.Member) error {
+	_, err := s.db.NamedExec(s.query(models.CreateMemberQuery), member)
+	return classify(err)
+}
+
+func (s *memberStore) Update(member models.Member) error {
+	_, err := s.db.NamedExec(s.query(models.UpdateMemberQuery), member)
+	return classify(err)
+}
+
+func (s *memberStore) Leaderboard(guildID string, after models.LeaderboardCursor, offset int, limit int) ([]models.RankedMember, error) {
+	members := []models.Member{}
+	var err error
+	if after.MemberID == "" {
+		err = s.db.Select(&members, s.query(models.SelectLeaderboardQuery), guildID, limit, offset)
+	} else {
+		err = s.db.Select(&members, s.query(models.SelectLeaderboardAfterQuery), guildID, after.Xp, after.Xp, after.MemberID, limit)
+	}
+	if err != nil || len(members) == 0 {
+		return []models.RankedMember{}, classify(err)
+	}
+
+	// The ranks are counted on the index of the leaderboard, whatever the page.
+	first := members[0]
+	above, before := 0, offset
+	if err := s.db.Get(&above, s.query(models.CountMembersAboveQuery), guildID, first.Xp); err != nil {
+		return nil, classify(err)
+	}
+	if after.MemberID != "" {
+		err := s.db.Get(&before, s.query(models.CountMembersBeforeQuery), guildID, first.Xp, first.Xp, first.MemberID)
+		if err != nil {
+			return nil, classify(err)
+		}
+	}
+	return models.Rank(members, before, above), nil
+}
+
+func (s *memberStore) Count(guildID string) (int, error) {
+	var total int
+	err := s.db.Get(&total, s.query(models.CountGuildMembersQuery), guildID)
+	return total, classify(err)
+}
+
+func (s *memberStore) Rank(guildID string, memberID string) (models.RankedMember, error) {
+	ranked := models.RankedMember{}
+	if err := s.db.Get(&ranked.Member, s.query(models.SelectMemberQuery), guildID, memberID); err != nil {
+		return ranked, classify(err)
+	}
+	err := s.db.Get(&ranked.Rank, s.query(models.CountMembersAboveQuery), guildID, ranked.Xp)
+	ranked.Rank++
+	return ranked, classify(err)
+}
+
+func (s *memberStore) SetLevel(guildID string, memberID string, xp int, level int) error {
+	return deleted(s.db.Exec(s.query(models.SetMemberLevelQuery), level, guildID, memberID, xp))
+}
+
+func (s *memberStore) Reset(guildID string, memberID string) error {
+	_, err := s.db.Exec(s.query(models.ResetMemberQuery), guildID, memberID)
+	return classify(err)
+}
+
+func (s *memberStore) ResetGuild(guildID string) error {
+	_, err := s.db.Exec(s.query(models.ResetGuildMembersQuery), guildID)
+	return classify(err)
+}
+
+func (s *memberStore) Delete(guildID string, memberID string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteMemberQuery), guildID, memberID))
+}
+
+func (s *memberStore) AddXp(guildID string, memberID string, xp int, at time.Time, cooldown time.Duration, levelOf func(xp int) int) (models.Member, int, error) {
+	member, previous, err := s.addXp(guildID, memberID, xp, at, cooldown, levelOf)
+	if errors.Is(err, store.ErrConflict) { // created concurrently, it can now be updated
+		return s.addXp(guildID, memberID, xp, at, cooldown, levelOf)
+	}
+	return member, previous, err
+}
+
+func (s *memberStore) addXp(guildID string, memberID string, xp int, at time.Time, cooldown time.Duration, levelOf func(xp int) int) (models.Member, int, error) {
+	member := models.Member{GuildID: guildID, MemberID: memberID, Xp: xp}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return member, 0, err
+	}
+	defer tx.Rollback()
+
+	// The update locks the row until the commit, so that the level is computed from the latest xp.
+	var res sql.Result
+	if cooldown > 0 {
+		member.LastXpAt = nulltype.NullTimeOf(at)
+		res, err = tx.Exec(s.query(models.AddMemberXpCooldownQuery), xp, at, guildID, memberID, at.Add(-cooldown))
+	} else {
+		res, err = tx.Exec(s.query(models.AddMemberXpQuery), xp, guildID, memberID)
+	}
+	if err != nil {
+		return member, 0, classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if cooldown > 0 {
+			// The member may exist but be on cooldown.
+			var existing models.Member
+			err := classify(tx.Get(&existing, s.query(models.SelectMemberQuery), guildID, memberID))
+			if err == nil {
+				return existing, existing.Level, store.ErrCooldown
+			} else if !errors.Is(err, store.ErrNotFound) {
+				return member, 0, err
+			}
+		}
+		if _, err := tx.NamedExec(s.query(models.CreateMemberQuery), member); err != nil {
+			return member, 0, classify(err)
+		}
+	} else if err := tx.Get(&member, s.query(models.SelectMemberQuery), guildID, memberID); err != nil {
+		return member, 0, classify(err)
+	}
+
+	previous := member.Level
+	if level := levelOf(member.Xp); level != member.Level {
+		if _, err := tx.Exec(s.query(models.UpdateMemberLevelQuery), level, guildID, memberID); err != nil {
+			return member, 0, classify(err)
+		}
+		member.Level = level
+	}
+
+	return member, previous, tx.Commit()
+}
@@ -0,0 +1,40 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type recoveryCodeStore struct {
+	*conn
+}
+
+func (s *recoveryCodeStore) Replace(username string, codes []models.RecoveryCode) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.query(models.DeleteRecoveryCodesQuery), username); err != nil {
+		return classify(err)
+	}
+	for _, code := range codes {
+		if _, err := tx.NamedExec(s.query(models.InsertRecoveryCodeQuery), code); err != nil {
+			return classify(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *recoveryCodeStore) Use(username string, codeHash string) error {
+	return deleted(s.db.Exec(s.query(models.UseRecoveryCodeQuery), time.Now().UTC(), username, codeHash))
+}
+
+func (s *recoveryCodeStore) Remaining(username string) (int, error) {
+	var n int
+	err := s.db.Get(&n, s.query(models.SelectRecoveryCodeCountQuery), username)
+	return n, classify(err)
+}
@@ -0,0 +1,49 @@
+package sqlstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+type roleStore struct {
+	*conn
+}
+
+func (s *roleStore) List(guildID string, filter store.RoleFilter) ([]models.Role, error) {
+	query := models.SelectGuildRolesQuery
+	args := []interface{}{guildID}
+	if filter.Ignored {
+		query += " AND ignored=true"
+	}
+	if filter.XpBlacklisted {
+		query += " AND xp_blacklisted=true"
+	}
+	if filter.Reward != 0 {
+		query += " AND reward=?"
+		args = append(args, filter.Reward)
+	}
+
+	roles := []models.Role{}
+	err := s.db.Select(&roles, s.query(query), args...)
+	return roles, classify(err)
+}
+
+func (s *roleStore) Get(guildID string, roleID string) (models.Role, error) {
+	var role models.Role
+	err := s.db.Get(&role, s.query(models.SelectRoleQuery), guildID, roleID)
+	return role, classify(err)
+}
+
+func (s *roleStore) Create(role models.Role) error {
+	_, err := s.db.NamedExec(s.query(models.CreateRoleQuery), role)
+	return classify(err)
+}
+
+func (s *roleStore) Update(role models.Role) error {
+	_, err := s.db.NamedExec(s.query(models.UpdateRoleQuery), role)
+	return classify(err)
+}
+
+func (s *roleStore) Delete(guildID string, roleID string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteRoleQuery), guildID, roleID))
+}
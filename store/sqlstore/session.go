@@ -0,0 +1,50 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type sessionStore struct {
+	*conn
+}
+
+func (s *sessionStore) List(username string, now time.Time) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := s.db.Select(&sessions, s.query(models.SelectUserSessionsQuery), username, now)
+	return sessions, classify(err)
+}
+
+func (s *sessionStore) Get(sessionID int) (models.Session, error) {
+	var session models.Session
+	err := s.db.Get(&session, s.query(models.SelectSessionQuery), sessionID)
+	return session, classify(err)
+}
+
+func (s *sessionStore) Create(session *models.Session) error {
+	id, err := s.insert(s.db, models.InsertSessionQuery, session, "session_id")
+	if err != nil {
+		return err
+	}
+	session.SessionID = id
+
+	return nil
+}
+
+func (s *sessionStore) Touch(sessionID int, at time.Time, ip string) error {
+	return deleted(s.db.Exec(s.query(models.TouchSessionQuery), at, ip, sessionID))
+}
+
+func (s *sessionStore) Extend(sessionID int, expiresAt time.Time) error {
+	return deleted(s.db.Exec(s.query(models.ExtendSessionQuery), expiresAt, sessionID))
+}
+
+func (s *sessionStore) Revoke(username string, sessionID int) error {
+	return deleted(s.db.Exec(s.query(models.RevokeSessionQuery), time.Now().UTC(), username, sessionID))
+}
+
+func (s *sessionStore) RevokeAll(username string, except int) error {
+	_, err := s.db.Exec(s.query(models.RevokeUserSessionsQuery), time.Now().UTC(), username, except)
+	return classify(err)
+}
@@ -0,0 +1,24 @@
+package sqlstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+)
+
+type signingKeyStore struct {
+	*conn
+}
+
+func (s *signingKeyStore) List() ([]models.SigningKey, error) {
+	keys := []models.SigningKey{}
+	err := s.db.Select(&keys, s.query(models.SelectSigningKeysQuery))
+	return keys, classify(err)
+}
+
+func (s *signingKeyStore) Create(key models.SigningKey) error {
+	_, err := s.db.NamedExec(s.query(models.InsertSigningKeyQuery), key)
+	return classify(err)
+}
+
+func (s *signingKeyStore) Delete(keyID string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteSigningKeyQuery), keyID))
+}
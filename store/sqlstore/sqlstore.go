@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// conn is the database shared by the repositories, along with its sql dialect.
+type conn struct {
+	db      *sqlx.DB
+	dialect models.Dialect
+}
+
+// New returns a store backed by the given MySQL/MariaDB, SQLite or PostgreSQL database.
+func New(db *sqlx.DB) *store.Store {
+	c := &conn{db: db, dialect: models.Dialect(db.DriverName())}
+
+	return &store.Store{
+		Guilds:        &guildStore{c},
+		Members:       &memberStore{c},
+		Warns:         &warnStore{c},
+		Bans:          &banStore{c},
+		Cases:         &caseStore{c},
+		Roles:         &roleStore{c},
+		Channels:      &channelStore{c},
+		Users:         &userStore{c},
+		Tokens:        &tokenStore{c},
+		Sessions:      &sessionStore{c},
+		ApiKeys:       &apiKeyStore{c},
+		SigningKeys:   &signingKeyStore{c},
+		UserTokens:    &userTokenStore{c},
+		RecoveryCodes: &recoveryCodeStore{c},
+		Logins:        &loginHistoryStore{c},
+		Access:        &guildAccessStore{c},
+	}
+}
+
+// query adapts a query of the models package to the dialect and bind type of the database.
+func (c *conn) query(query string) string {
+	return c.db.Rebind(c.dialect.Query(query))
+}
+
+// insert executes the named insert query and returns the value generated for the id column.
+func (c *conn) insert(ext sqlx.Ext, query string, arg interface{}, idColumn string) (int, error) {
+	if c.dialect == models.Postgres {
+		// lib/pq does not support LastInsertId.
+		rows, err := sqlx.NamedQuery(ext, c.query(query)+" RETURNING "+idColumn, arg)
+		if err != nil {
+			return 0, classify(err)
+		}
+		defer rows.Close()
+
+		var id int
+		if !rows.Next() {
+			return 0, rows.Err()
+		}
+		err = rows.Scan(&id)
+		return id, err
+	}
+
+	res, err := sqlx.NamedExec(ext, c.query(query), arg)
+	if err != nil {
+		return 0, classify(err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// classify converts the errors of every supported driver to the store errors.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return fmt.Errorf("%w: %s", store.ErrConflict, mysqlErr.Message)
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %s", store.ErrConflict, sqliteErr.Error())
+		}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return fmt.Errorf("%w: %s", store.ErrConflict, pqErr.Message)
+	}
+
+	return err
+}
+
+// deleted returns ErrNotFound if the delete query did not affect any row.
+func deleted(res sql.Result, err error) error {
+	if err != nil {
+		return classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package sqlstore_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gyroskan/cardinal/db/migrations"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/gyroskan/cardinal/store/sqlstore"
+	"github.com/gyroskan/cardinal/store/storetest"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) *store.Store {
+		path := filepath.Join(t.TempDir(), "cardinal.db")
+		db, err := sqlx.Connect("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+
+		return migrated(t, db)
+	})
+}
+
+// TestMySQL runs against the server of CARDINAL_TEST_MYSQL_DSN, each case in its own database.
+func TestMySQL(t *testing.T) {
+	dsn := os.Getenv("CARDINAL_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("CARDINAL_TEST_MYSQL_DSN is not set")
+	}
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ParseTime, cfg.MultiStatements = true, true
+	admin, err := sqlx.Connect("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	n := 0
+	storetest.Run(t, func(t *testing.T) *store.Store {
+		n++
+		name := fmt.Sprintf("cardinal_test_%d_%d", time.Now().UnixNano(), n)
+		if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP DATABASE " + name) })
+
+		cfg := cfg.Clone()
+		cfg.DBName = name
+		db, err := sqlx.Connect("mysql", cfg.FormatDSN())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return migrated(t, db)
+	})
+}
+
+// TestPostgres runs against the database of CARDINAL_TEST_POSTGRES_DSN, each case in its own schema.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("CARDINAL_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CARDINAL_TEST_POSTGRES_DSN is not set")
+	}
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	n := 0
+	storetest.Run(t, func(t *testing.T) *store.Store {
+		n++
+		schema := fmt.Sprintf("cardinal_test_%d_%d", time.Now().UnixNano(), n)
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+		db, err := sqlx.Connect("postgres", dsn+sep(dsn)+"search_path="+schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		return migrated(t, db)
+	})
+}
+
+// migrated applies every migration to the database and returns its store.
+func migrated(t *testing.T, db *sqlx.DB) *store.Store {
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return sqlstore.New(db)
+}
+
+// sep returns the separator of a new parameter of the dsn, given as a url or as key=value pairs.
+func sep(dsn string) string {
+	switch {
+	case !strings.Contains(dsn, "://"):
+		return " "
+	case strings.Contains(dsn, "?"):
+		return "&"
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,42 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type tokenStore struct {
+	*conn
+}
+
+func (s *tokenStore) Create(token models.RefreshToken) error {
+	_, err := s.db.NamedExec(s.query(models.InsertRefreshTokenQuery), token)
+	return classify(err)
+}
+
+func (s *tokenStore) Get(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.db.Get(&token, s.query(models.SelectRefreshTokenQuery), tokenHash)
+	return token, classify(err)
+}
+
+func (s *tokenStore) ListActive(username string, now time.Time) ([]models.RefreshToken, error) {
+	tokens := []models.RefreshToken{}
+	err := s.db.Select(&tokens, s.query(models.SelectActiveRefreshTokensQuery), username, now)
+	return tokens, classify(err)
+}
+
+func (s *tokenStore) Revoke(tokenHash string) error {
+	return deleted(s.db.Exec(s.query(models.RevokeRefreshTokenQuery), time.Now().UTC(), tokenHash))
+}
+
+func (s *tokenStore) RevokeAll(username string) error {
+	_, err := s.db.Exec(s.query(models.RevokeUserRefreshTokensQuery), time.Now().UTC(), username)
+	return classify(err)
+}
+
+func (s *tokenStore) RevokeOthers(username string, sessionID int) error {
+	_, err := s.db.Exec(s.query(models.RevokeOtherRefreshTokensQuery), time.Now().UTC(), username, sessionID)
+	return classify(err)
+}
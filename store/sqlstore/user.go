@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"github.com/gyroskan/cardinal/models"
+	"github.com/mattn/go-nulltype"
+)
+
+type userStore struct {
+	*conn
+}
+
+func (s *userStore) All() ([]models.User, error) {
+	users := []models.User{}
+	err := s.db.Select(&users, s.query(models.SelectUsersQuery))
+	return users, classify(err)
+}
+
+func (s *userStore) Get(username string) (models.User, error) {
+	var user models.User
+	err := s.db.Get(&user, s.query(models.SelectUserQuery), username)
+	return user, classify(err)
+}
+
+func (s *userStore) GetByDiscordID(discordID string) (models.User, error) {
+	var user models.User
+	err := s.db.Get(&user, s.query(models.SelectDiscordUserQuery), discordID, true)
+	return user, classify(err)
+}
+
+func (s *userStore) ListByEmail(email string) ([]models.User, error) {
+	users := []models.User{}
+	err := s.db.Select(&users, s.query(models.SelectUserByEmailQuery), email)
+	return users, classify(err)
+}
+
+func (s *userStore) Create(user models.User) error {
+	_, err := s.db.NamedExec(s.query(models.InsertUserQuery), user)
+	return classify(err)
+}
+
+func (s *userStore) Update(user models.User) error {
+	_, err := s.db.NamedExec(s.query(models.UpdateUserQuery), user)
+	return classify(err)
+}
+
+func (s *userStore) SetAccessLvl(username string, lvl int) error {
+	_, err := s.db.Exec(s.query(models.UpdateUserAccessQuery), lvl, username)
+	return classify(err)
+}
+
+func (s *userStore) SetBanned(username string, banned bool) error {
+	_, err := s.db.Exec(s.query(models.UpdateUserBannedQuery), banned, username)
+	return classify(err)
+}
+
+func (s *userStore) SetRoles(username string, roles string) error {
+	_, err := s.db.Exec(s.query(models.UpdateUserRolesQuery), roles, username)
+	return classify(err)
+}
+
+func (s *userStore) SetEmailVerified(username string, verified bool) error {
+	_, err := s.db.Exec(s.query(models.UpdateEmailVerifiedQuery), verified, username)
+	return classify(err)
+}
+
+func (s *userStore) SetTotp(username string, secret nulltype.NullString, enabled bool) error {
+	_, err := s.db.Exec(s.query(models.UpdateUserTotpQuery), secret, enabled, username)
+	return classify(err)
+}
+
+func (s *userStore) UseTotpStep(username string, step int64) error {
+	return deleted(s.db.Exec(s.query(models.UpdateUserTotpStepQuery), step, username, step))
+}
+
+func (s *userStore) LinkDiscord(username string, discordID string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.query(models.ClearDiscordClaimsQuery), discordID, false, username); err != nil {
+		return classify(err)
+	}
+	if _, err := tx.Exec(s.query(models.LinkDiscordQuery), discordID, true, username); err != nil {
+		return classify(err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *userStore) IncrTokenVersion(username string) error {
+	_, err := s.db.Exec(s.query(models.IncrTokenVersionQuery), username)
+	return classify(err)
+}
+
+func (s *userStore) Delete(username string) error {
+	return deleted(s.db.Exec(s.query(models.DeleteUserQuery), username))
+}
@@ -0,0 +1,31 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type userTokenStore struct {
+	*conn
+}
+
+func (s *userTokenStore) Create(token models.UserToken) error {
+	_, err := s.db.NamedExec(s.query(models.InsertUserTokenQuery), token)
+	return classify(err)
+}
+
+func (s *userTokenStore) Get(tokenHash string) (models.UserToken, error) {
+	var token models.UserToken
+	err := s.db.Get(&token, s.query(models.SelectUserTokenQuery), tokenHash)
+	return token, classify(err)
+}
+
+func (s *userTokenStore) Use(tokenHash string) error {
+	return deleted(s.db.Exec(s.query(models.UseUserTokenQuery), time.Now().UTC(), tokenHash))
+}
+
+func (s *userTokenStore) UseAll(username string, purpose string) error {
+	_, err := s.db.Exec(s.query(models.UseUserTokensQuery), time.Now().UTC(), username, purpose)
+	return classify(err)
+}
@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+)
+
+type warnStore struct {
+	*conn
+}
+
+func (s *warnStore) List(guildID string, memberID string) ([]models.Warn, error) {
+	warns := []models.Warn{}
+	err := s.db.Select(&warns, s.query(models.SelectMemberWarnsQuery), guildID, memberID)
+	return warns, classify(err)
+}
+
+func (s *warnStore) Get(guildID string, memberID string, warnID int) (models.Warn, error) {
+	var warn models.Warn
+	err := s.db.Get(&warn, s.query(models.SelectWarnQuery), guildID, memberID, warnID)
+	return warn, classify(err)
+}
+
+func (s *warnStore) Create(warn *models.Warn) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := s.lockGuild(tx, warn.GuildID); err != nil {
+		return err
+	}
+	id, err := s.insert(tx, models.CreateWarnQuery, warn, "warn_id")
+	if err != nil {
+		return err
+	}
+	warn.WarnID = id
+	modCase := warn.Case()
+	if err := s.insertCase(tx, &modCase); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *warnStore) CreateEscalating(warn *models.Warn, escalate func(guild models.Guild, warns int) *models.Ban) (*models.Ban, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	guild, err := s.lockGuild(tx, warn.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	if warn.WarnedAt.IsZero() {
+		warn.WarnedAt = time.Now().UTC()
+	}
+	id, err := s.insert(tx, models.CreateWarnQuery, warn, "warn_id")
+	if err != nil {
+		return nil, err
+	}
+	warn.WarnID = id
+	modCase := warn.Case()
+	if err := s.insertCase(tx, &modCase); err != nil {
+		return nil, err
+	}
+
+	var warns int
+	if err := tx.Get(&warns, s.query(models.CountActiveWarnsQuery), warn.GuildID, warn.MemberID); err != nil {
+		return nil, classify(err)
+	}
+	ban := escalate(guild, warns)
+	if ban != nil {
+		if ban.BanID, err = s.insert(tx, models.CreateBanQuery, ban, "ban_id"); err != nil {
+			return nil, err
+		}
+		modCase := ban.Case()
+		if err := s.insertCase(tx, &modCase); err != nil {
+			return nil, err
+		}
+	}
+
+	return ban, tx.Commit()
+}
+
+func (s *warnStore) Delete(guildID string, memberID string, warnID int) error {
+	return deleted(s.db.Exec(s.query(models.DeleteWarnQuery), guildID, memberID, warnID))
+}
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/mattn/go-nulltype"
+)
+
+var (
+	// ErrNotFound is returned when the requested entity does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when an entity with the same key already exists.
+	ErrConflict = errors.New("already exists")
+	// ErrCooldown is returned when a member already gained xp from a message during the cooldown.
+	ErrCooldown = errors.New("on cooldown")
+)
+
+type (
+	// Store groups the repositories used by the api.
+	Store struct {
+		Guilds        GuildStore
+		Members       MemberStore
+		Warns         WarnStore
+		Bans          BanStore
+		Cases         CaseStore
+		Roles         RoleStore
+		Channels      ChannelStore
+		Users         UserStore
+		Tokens        TokenStore
+		Sessions      SessionStore
+		ApiKeys       ApiKeyStore
+		SigningKeys   SigningKeyStore
+		UserTokens    UserTokenStore
+		RecoveryCodes RecoveryCodeStore
+		Logins        LoginHistoryStore
+		Access        GuildAccessStore
+	}
+
+	GuildStore interface {
+		All() ([]models.Guild, error)
+		Get(guildID string) (models.Guild, error)
+		Create(guild models.Guild) error
+		Update(guild models.Guild) error
+		// Reset sets the guild parameters back to their default values.
+		Reset(guildID string) error
+		Delete(guildID string) error
+	}
+
+	MemberStore interface {
+		// List returns at most limit members of the guild with an id greater than after,
+		// ordered by id.
+		List(guildID string, after string, limit int) ([]models.Member, error)
+		Get(guildID string, memberID string) (models.Member, error)
+		Create(member models.Member) error
+		Update(member models.Member) error
+		// AddXp atomically adds xp to the member, creating it if missing, and sets the level
+		// computed from its new xp. It returns the updated member and its previous level.
+		// With a cooldown, the xp is only added if the member did not gain xp with a cooldown
+		// since at minus the cooldown, ErrCooldown being returned otherwise, and at is recorded.
+		AddXp(guildID string, memberID string, xp int, at time.Time, cooldown time.Duration, levelOf func(xp int) int) (models.Member, int, error)
+		// Leaderboard returns at most limit members of the guild ranked by xp, following the member of the cursor
+		// when it is set, or skipping offset members otherwise.
+		Leaderboard(guildID string, after models.LeaderboardCursor, offset int, limit int) ([]models.RankedMember, error)
+		// Count returns the number of members of the guild.
+		Count(guildID string) (int, error)
+		// Rank returns the member with its rank in the leaderboard of the guild.
+		Rank(guildID string, memberID string) (models.RankedMember, error)
+		// SetLevel sets the level of the member, if its xp is still xp.
+		// It returns ErrNotFound when the member was deleted or its xp changed.
+		SetLevel(guildID string, memberID string, xp int, level int) error
+		// Reset sets left, xp and level of the member back to their default values.
+		Reset(guildID string, memberID string) error
+		// ResetGuild resets every member of the guild.
+		ResetGuild(guildID string) error
+		Delete(guildID string, memberID string) error
+	}
+
+	WarnStore interface {
+		List(guildID string, memberID string) ([]models.Warn, error)
+		Get(guildID string, memberID string, warnID int) (models.Warn, error)
+		// Create inserts the warn with its case, and sets its generated WarnID.
+		// It returns ErrNotFound if the guild does not exist.
+		Create(warn *models.Warn) error
+		// CreateEscalating inserts the warn and, in the same transaction, the ban returned by escalate
+		// from the guild and the number of warns of the member since its last ban, when not nil,
+		// with their cases.
+		// The warns of a guild are created one at a time, so that a single ban is created,
+		// and a zero WarnedAt is set to the current time once the previous warns are created,
+		// before escalate is called. The warns dated before a ban of the member are not counted.
+		CreateEscalating(warn *models.Warn, escalate func(guild models.Guild, warns int) *models.Ban) (*models.Ban, error)
+		Delete(guildID string, memberID string, warnID int) error
+	}
+
+	BanStore interface {
+		List(guildID string, memberID string) ([]models.Ban, error)
+		Get(guildID string, memberID string, banID int) (models.Ban, error)
+		// Create inserts the ban with its case, and sets its generated BanID.
+		// It returns ErrNotFound if the guild does not exist.
+		Create(ban *models.Ban) error
+		// Lift lifts the ban with an unban case of the reason,
+		// returning ErrNotFound if it does not exist or was already lifted.
+		Lift(guildID string, memberID string, banID int, liftedBy nulltype.NullString, reason nulltype.NullString, at time.Time) error
+		// LiftExpired lifts the bans of every guild which expired at the time, with unban cases
+		// of the ExpiredBanReason, and returns them.
+		LiftExpired(at time.Time) ([]models.Ban, error)
+		// PendingUnbans returns at most limit lifted bans of the guild, oldest first,
+		// whose member was not unbanned yet.
+		PendingUnbans(guildID string, limit int) ([]models.Ban, error)
+		// SetUnbanned records that the member of the lifted ban was unbanned,
+		// returning ErrNotFound if the ban is not pending.
+		SetUnbanned(guildID string, banID int, at time.Time) error
+		Delete(guildID string, memberID string, banID int) error
+	}
+
+	CaseStore interface {
+		// List returns the cases of the guild matching the filter, most recent first.
+		List(guildID string, filter CaseFilter) ([]models.Case, error)
+		Get(guildID string, caseNumber int) (models.Case, error)
+		// Create inserts the case with the next case number of its guild, and sets its CaseNumber.
+		// It returns ErrNotFound if the guild does not exist.
+		// The cases of warns and bans are created by their stores, in the same transaction.
+		Create(c *models.Case) error
+		// SetReason edits the reason of the case, and of its warn or ban.
+		SetReason(guildID string, caseNumber int, reason nulltype.NullString, at time.Time) error
+	}
+
+	RoleStore interface {
+		List(guildID string, filter RoleFilter) ([]models.Role, error)
+		Get(guildID string, roleID string) (models.Role, error)
+		Create(role models.Role) error
+		Update(role models.Role) error
+		Delete(guildID string, roleID string) error
+	}
+
+	ChannelStore interface {
+		List(guildID string, filter ChannelFilter) ([]models.Channel, error)
+		Get(guildID string, channelID string) (models.Channel, error)
+		Create(channel models.Channel) error
+		Update(channel models.Channel) error
+		Delete(guildID string, channelID string) error
+	}
+
+	UserStore interface {
+		All() ([]models.User, error)
+		Get(username string) (models.User, error)
+		// GetByDiscordID returns the user who proved to own the discord id.
+		GetByDiscordID(discordID string) (models.User, error)
+		// ListByEmail returns the users with the email, ordered by creation date.
+		ListByEmail(email string) ([]models.User, error)
+		Create(user models.User) error
+		// Update saves the email, discord id and password of the user.
+		Update(user models.User) error
+		SetAccessLvl(username string, lvl int) error
+		SetBanned(username string, banned bool) error
+		// SetRoles sets the roles granted to the user on top of its access level.
+		SetRoles(username string, roles string) error
+		SetEmailVerified(username string, verified bool) error
+		// SetTotp sets the TOTP secret of the user and whether it is required to login.
+		SetTotp(username string, secret nulltype.NullString, enabled bool) error
+		// UseTotpStep records the TOTP step used by the user. It returns ErrNotFound
+		// if the step is not greater than the last one used, so that a code can only be used once.
+		UseTotpStep(username string, step int64) error
+		// LinkDiscord sets the verified discord id of the user,
+		// removing it from the users who claimed it without verification.
+		LinkDiscord(username string, discordID string) error
+		// IncrTokenVersion invalidates every access token issued to the user.
+		IncrTokenVersion(username string) error
+		Delete(username string) error
+	}
+
+	TokenStore interface {
+		Create(token models.RefreshToken) error
+		Get(tokenHash string) (models.RefreshToken, error)
+		// ListActive returns the tokens of the user which are neither revoked nor expired at the given date,
+		// most recent first.
+		ListActive(username string, now time.Time) ([]models.RefreshToken, error)
+		// Revoke marks the token as revoked. It returns ErrNotFound if the token
+		// does not exist or was already revoked, so that a token can only be used once.
+		Revoke(tokenHash string) error
+		// RevokeAll revokes every refresh token of the user.
+		RevokeAll(username string) error
+		// RevokeOthers revokes every refresh token of the user not issued for the session.
+		RevokeOthers(username string, sessionID int) error
+	}
+
+	SessionStore interface {
+		// List returns the sessions of the user which are neither revoked nor expired at the given date,
+		// most recently used first.
+		List(username string, now time.Time) ([]models.Session, error)
+		Get(sessionID int) (models.Session, error)
+		// Create inserts the session and sets its generated SessionID.
+		Create(session *models.Session) error
+		// Touch records that the session was used at the given date from the ip.
+		Touch(sessionID int, at time.Time, ip string) error
+		// Extend sets the expiration date of the session, when a new refresh token is issued.
+		Extend(sessionID int, expiresAt time.Time) error
+		// Revoke marks the session as revoked. It returns ErrNotFound if the session
+		// does not belong to the user or was already revoked.
+		Revoke(username string, sessionID int) error
+		// RevokeAll revokes every session of the user except the given one.
+		RevokeAll(username string, except int) error
+	}
+
+	UserTokenStore interface {
+		Create(token models.UserToken) error
+		Get(tokenHash string) (models.UserToken, error)
+		// Use marks the token as used. It returns ErrNotFound if the token
+		// does not exist or was already used, so that a token can only be used once.
+		Use(tokenHash string) error
+		// UseAll marks every token of the user issued for the purpose as used.
+		UseAll(username string, purpose string) error
+	}
+
+	RecoveryCodeStore interface {
+		// Replace deletes the recovery codes of the user and inserts the given ones.
+		Replace(username string, codes []models.RecoveryCode) error
+		// Use marks the code as used. It returns ErrNotFound if the code
+		// does not exist or was already used.
+		Use(username string, codeHash string) error
+		// Remaining returns the number of unused codes of the user.
+		Remaining(username string) (int, error)
+	}
+
+	LoginHistoryStore interface {
+		// Create inserts the attempt and sets its generated AttemptID.
+		Create(attempt *models.LoginAttempt) error
+		// List returns the attempts matching the filter, most recent first.
+		List(filter LoginFilter) ([]models.LoginAttempt, error)
+	}
+
+	ApiKeyStore interface {
+		List(username string) ([]models.ApiKey, error)
+		// Get returns the key with the given hash.
+		Get(keyHash string) (models.ApiKey, error)
+		// Create inserts the key and sets its generated KeyID.
+		Create(key *models.ApiKey) error
+		// Touch records that the key was used at the given date.
+		Touch(keyID int, at time.Time) error
+		Delete(username string, keyID int) error
+	}
+
+	SigningKeyStore interface {
+		// List returns every key, oldest first.
+		List() ([]models.SigningKey, error)
+		Create(key models.SigningKey) error
+		Delete(keyID string) error
+	}
+
+	GuildAccessStore interface {
+		// List returns the guild accesses granted to the user.
+		List(username string) ([]models.GuildAccess, error)
+		// ListGuild returns the accesses granted on the guild.
+		ListGuild(guildID string) ([]models.GuildAccess, error)
+		Get(guildID string, username string) (models.GuildAccess, error)
+		// Set grants the access, replacing the previous access of the user on the guild.
+		Set(access models.GuildAccess) error
+		Delete(guildID string, username string) error
+	}
+
+	// RoleFilter restricts the roles returned by RoleStore.List.
+	// Zero values do not filter.
+	RoleFilter struct {
+		Ignored       bool // Ignored roles only
+		XpBlacklisted bool // Xp blacklisted roles only
+		Reward        int  // Reward roles of this level only
+	}
+
+	// LoginFilter restricts the attempts returned by LoginHistoryStore.List.
+	// Zero values do not filter.
+	LoginFilter struct {
+		Username string
+		IP       string
+		Failed   bool // Failed attempts only
+		Before   int  // Attempts with a lower id only
+		Limit    int  // Maximum number of attempts returned
+	}
+
+	// CaseFilter restricts the cases returned by CaseStore.List.
+	// Zero values do not filter.
+	CaseFilter struct {
+		MemberID    string
+		ModeratorID string
+		Type        string
+		From        time.Time // Cases created at or after this date only
+		To          time.Time // Cases created before this date only
+		Before      int       // Cases with a lower case number only
+		Limit       int       // Maximum number of cases returned
+	}
+
+	// ChannelFilter restricts the channels returned by ChannelStore.List.
+	// Zero values do not filter.
+	ChannelFilter struct {
+		Ignored       bool // Ignored channels only
+		XpBlacklisted bool // Xp blacklisted channels only
+	}
+)
@@ -0,0 +1,43 @@
+package storetest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testApiKeys checks the ids generated for api keys, and that the keys are deleted with their user.
+func testApiKeys(t *testing.T, s *store.Store) {
+	must(t, s.Users.Create(user("alice")))
+	must(t, s.Users.Create(user("bob")))
+
+	var keyIDs []int
+	for i, username := range []string{"alice", "alice", "bob"} {
+		key := models.ApiKey{Username: username, Name: "bot", Prefix: "abc", KeyHash: fmt.Sprintf("k%d", i), CreatedAt: now}
+		must(t, s.ApiKeys.Create(&key))
+		keyIDs = append(keyIDs, key.KeyID)
+	}
+	ascending(t, "api key", keyIDs)
+
+	_, err := s.ApiKeys.Get("missing")
+	is(t, "ApiKeys.Get", err, store.ErrNotFound)
+	key, err := s.ApiKeys.Get("k1")
+	must(t, err)
+	if key.KeyID != keyIDs[1] || key.Username != "alice" {
+		t.Errorf("ApiKeys.Get returned key %d of %s, want key %d of alice", key.KeyID, key.Username, keyIDs[1])
+	}
+	is(t, "ApiKeys.Delete of another user", s.ApiKeys.Delete("bob", keyIDs[0]), store.ErrNotFound)
+	keys, err := s.ApiKeys.List("alice")
+	must(t, err)
+	if len(keys) != 2 {
+		t.Errorf("alice has %d keys, want 2", len(keys))
+	}
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.ApiKeys.Get("k0")
+	is(t, "ApiKeys.Get of deleted user", err, store.ErrNotFound)
+	_, err = s.ApiKeys.Get("k2")
+	must(t, err)
+}
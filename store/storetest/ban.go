@@ -0,0 +1,51 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+// testBanLifting checks a ban is lifted once, by a moderator or on expiry,
+// and is pending until its member is unbanned.
+func testBanLifting(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	permanent := models.Ban{GuildID: "g1", MemberID: "m1", BannedAt: now}
+	must(t, s.Bans.Create(&permanent))
+	expiring := models.Ban{GuildID: "g1", MemberID: "m2", BannedAt: now, ExpiresAt: nulltype.NullTimeOf(now.Add(time.Hour))}
+	must(t, s.Bans.Create(&expiring))
+	later := models.Ban{GuildID: "g1", MemberID: "m3", BannedAt: now, ExpiresAt: nulltype.NullTimeOf(now.Add(3 * time.Hour))}
+	must(t, s.Bans.Create(&later))
+
+	must(t, s.Bans.Lift("g1", "m1", permanent.BanID, nulltype.NullStringOf("mod"), nulltype.NullStringOf("appeal"), now))
+	is(t, "Bans.Lift twice", s.Bans.Lift("g1", "m1", permanent.BanID, nulltype.NullString{}, nulltype.NullString{}, now), store.ErrNotFound)
+	is(t, "Bans.Lift of another member", s.Bans.Lift("g1", "m2", later.BanID, nulltype.NullString{}, nulltype.NullString{}, now), store.ErrNotFound)
+	ban, err := s.Bans.Get("g1", "m1", permanent.BanID)
+	must(t, err)
+	if !ban.LiftedAt.Valid() || ban.LiftedBy.StringValue() != "mod" {
+		t.Errorf("lifted ban has lifted date %v by %v, want lifted by mod", ban.LiftedAt, ban.LiftedBy)
+	}
+
+	lifted, err := s.Bans.LiftExpired(now.Add(2 * time.Hour))
+	must(t, err)
+	if len(lifted) != 1 || lifted[0].BanID != expiring.BanID || !lifted[0].LiftedAt.Valid() {
+		t.Errorf("Bans.LiftExpired returned %v, want ban %d", lifted, expiring.BanID)
+	}
+
+	pending, err := s.Bans.PendingUnbans("g1", 10)
+	must(t, err)
+	if len(pending) != 2 || pending[0].BanID != permanent.BanID || pending[1].BanID != expiring.BanID {
+		t.Errorf("Bans.PendingUnbans returned %v, want bans %d and %d", pending, permanent.BanID, expiring.BanID)
+	}
+	must(t, s.Bans.SetUnbanned("g1", permanent.BanID, now))
+	is(t, "Bans.SetUnbanned twice", s.Bans.SetUnbanned("g1", permanent.BanID, now), store.ErrNotFound)
+	is(t, "Bans.SetUnbanned of a ban not lifted", s.Bans.SetUnbanned("g1", later.BanID, now), store.ErrNotFound)
+	pending, err = s.Bans.PendingUnbans("g1", 10)
+	must(t, err)
+	if len(pending) != 1 || pending[0].BanID != expiring.BanID {
+		t.Errorf("Bans.PendingUnbans returned %v once unbanned, want ban %d", pending, expiring.BanID)
+	}
+}
@@ -0,0 +1,162 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+// testCaseNumbers checks the cases of each guild are numbered from 1,
+// and are deleted with their guild.
+func testCaseNumbers(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	must(t, s.Guilds.Create(guild("g2")))
+
+	caseIDs := map[int]bool{}
+	for i := 1; i <= 3; i++ {
+		for _, guildID := range []string{"g1", "g2"} {
+			c := models.Case{GuildID: guildID, Type: models.NoteCase, MemberID: "m1", CreatedAt: now}
+			must(t, s.Cases.Create(&c))
+			if c.CaseNumber != i {
+				t.Errorf("case %d of guild %s got number %d", i, guildID, c.CaseNumber)
+			}
+			if c.CaseID <= 0 || caseIDs[c.CaseID] {
+				t.Errorf("case %d of guild %s got id %d, already used or not generated", i, guildID, c.CaseID)
+			}
+			caseIDs[c.CaseID] = true
+		}
+	}
+	is(t, "Cases.Create of a missing guild", s.Cases.Create(&models.Case{GuildID: "missing", Type: models.NoteCase, MemberID: "m1", CreatedAt: now}), store.ErrNotFound)
+	_, err := s.Cases.Get("g1", 4)
+	is(t, "Cases.Get", err, store.ErrNotFound)
+
+	cases, err := s.Cases.List("g1", store.CaseFilter{Limit: 10})
+	must(t, err)
+	if len(cases) != 3 || cases[0].CaseNumber != 3 || cases[2].CaseNumber != 1 {
+		t.Errorf("Cases.List returned %v, want cases 3 to 1", cases)
+	}
+
+	must(t, s.Cases.SetReason("g1", 2, nulltype.NullStringOf("spam"), now))
+	is(t, "Cases.SetReason", s.Cases.SetReason("g1", 4, nulltype.NullStringOf("spam"), now), store.ErrNotFound)
+	c, err := s.Cases.Get("g1", 2)
+	must(t, err)
+	if c.Reason.StringValue() != "spam" || !c.UpdatedAt.Valid() {
+		t.Errorf("case with a new reason has reason %v, updated at %v", c.Reason, c.UpdatedAt)
+	}
+
+	must(t, s.Guilds.Delete("g1"))
+	cases, err = s.Cases.List("g1", store.CaseFilter{Limit: 10})
+	must(t, err)
+	if len(cases) != 0 {
+		t.Errorf("deleted guild kept %d cases", len(cases))
+	}
+	cases, err = s.Cases.List("g2", store.CaseFilter{Limit: 10})
+	must(t, err)
+	if len(cases) != 3 {
+		t.Errorf("other guild has %d cases, want 3", len(cases))
+	}
+}
+
+// testCaseReferences checks the reason of a case is the reason of its warn or ban,
+// and that the cases outlive their warn or ban, as ON DELETE SET NULL.
+func testCaseReferences(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	warn := models.Warn{GuildID: "g1", MemberID: "m1", WarnedAt: now}
+	must(t, s.Warns.Create(&warn))
+	ban := models.Ban{GuildID: "g1", MemberID: "m1", BannedAt: now}
+	must(t, s.Bans.Create(&ban))
+
+	must(t, s.Cases.SetReason("g1", 1, nulltype.NullStringOf("spam"), now))
+	must(t, s.Cases.SetReason("g1", 2, nulltype.NullStringOf("raid"), now))
+	warn, err := s.Warns.Get("g1", "m1", warn.WarnID)
+	must(t, err)
+	ban, err = s.Bans.Get("g1", "m1", ban.BanID)
+	must(t, err)
+	if warn.WarnReason.StringValue() != "spam" || ban.BanReason.StringValue() != "raid" {
+		t.Errorf("warn and ban have reasons %v and %v, want spam and raid", warn.WarnReason, ban.BanReason)
+	}
+
+	must(t, s.Warns.Delete("g1", "m1", warn.WarnID))
+	must(t, s.Bans.Delete("g1", "m1", ban.BanID))
+
+	warnCase, err := s.Cases.Get("g1", 1)
+	must(t, err)
+	banCase, err := s.Cases.Get("g1", 2)
+	must(t, err)
+	if warnCase.Type != models.WarnCase || warnCase.WarnID.Valid() {
+		t.Errorf("case of deleted warn is %s referencing warn %v", warnCase.Type, warnCase.WarnID)
+	}
+	if banCase.Type != models.BanCase || banCase.BanID.Valid() {
+		t.Errorf("case of deleted ban is %s referencing ban %v", banCase.Type, banCase.BanID)
+	}
+}
+
+// testModerationCases checks warns and bans are logged with their cases.
+func testModerationCases(t *testing.T, s *store.Store) {
+	g := guild("g1")
+	g.AllowModeration, g.MaxWarns = true, 2
+	must(t, s.Guilds.Create(g))
+
+	escalate := func(guild models.Guild, warns int) *models.Ban {
+		if warns < guild.MaxWarns {
+			return nil
+		}
+		return &models.Ban{GuildID: guild.GuildID, MemberID: "m1", BannedAt: now, AutoBan: true}
+	}
+	for i := 0; i < 2; i++ {
+		warn := models.Warn{GuildID: "g1", MemberID: "m1", WarnedAt: now.Add(time.Duration(i) * time.Second)}
+		ban, err := s.Warns.CreateEscalating(&warn, escalate)
+		must(t, err)
+		if (ban != nil) != (i == 1) {
+			t.Errorf("warn %d escalated to %v", i+1, ban)
+		}
+	}
+	expiring := models.Ban{GuildID: "g1", MemberID: "m2", BannedAt: now, ExpiresAt: nulltype.NullTimeOf(now.Add(time.Hour))}
+	must(t, s.Bans.Create(&expiring))
+
+	must(t, s.Bans.Lift("g1", "m1", 1, nulltype.NullStringOf("mod"), nulltype.NullStringOf("appeal"), now))
+	is(t, "Bans.Lift twice", s.Bans.Lift("g1", "m1", 1, nulltype.NullString{}, nulltype.NullString{}, now), store.ErrNotFound)
+
+	lifted, err := s.Bans.LiftExpired(now.Add(2 * time.Hour))
+	must(t, err)
+	if len(lifted) != 1 || lifted[0].BanID != expiring.BanID || !lifted[0].LiftedAt.Valid() {
+		t.Errorf("Bans.LiftExpired returned %v, want ban %d", lifted, expiring.BanID)
+	}
+
+	cases, err := s.Cases.List("g1", store.CaseFilter{Limit: 10})
+	must(t, err)
+	want := []struct {
+		typ    string
+		member string
+		reason string
+	}{
+		{models.UnbanCase, "m2", models.ExpiredBanReason},
+		{models.UnbanCase, "m1", "appeal"},
+		{models.BanCase, "m2", ""},
+		{models.BanCase, "m1", ""},
+		{models.WarnCase, "m1", ""},
+		{models.WarnCase, "m1", ""},
+	}
+	if len(cases) != len(want) {
+		t.Fatalf("got %d cases, want %d: %v", len(cases), len(want), cases)
+	}
+	for i, w := range want {
+		c := cases[i]
+		if c.CaseNumber != len(want)-i || c.Type != w.typ || c.MemberID != w.member || c.Reason.StringValue() != w.reason {
+			t.Errorf("case %d is %d %s of %s (%q), want %s of %s (%q)",
+				len(want)-i, c.CaseNumber, c.Type, c.MemberID, c.Reason.StringValue(), w.typ, w.member, w.reason)
+		}
+	}
+	if !cases[0].BanID.Valid() || int(cases[0].BanID.Int64Value()) != expiring.BanID {
+		t.Errorf("unban case of the expired ban references ban %v", cases[0].BanID)
+	}
+	if cases[1].ModeratorID.StringValue() != "mod" {
+		t.Errorf("unban case has moderator %v, want mod", cases[1].ModeratorID)
+	}
+	if !cases[5].WarnID.Valid() {
+		t.Error("warn case does not reference its warn")
+	}
+}
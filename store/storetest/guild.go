@@ -0,0 +1,88 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testGuilds checks the guilds and their members, roles and channels are found by their keys,
+// and created once.
+func testGuilds(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	must(t, s.Members.Create(models.Member{GuildID: "g1", MemberID: "m1"}))
+	must(t, s.Roles.Create(models.Role{GuildID: "g1", RoleID: "r1"}))
+	must(t, s.Channels.Create(models.Channel{GuildID: "g1", ChannelID: "c1"}))
+
+	_, err := s.Guilds.Get("missing")
+	is(t, "Guilds.Get", err, store.ErrNotFound)
+	is(t, "Guilds.Delete", s.Guilds.Delete("missing"), store.ErrNotFound)
+	_, err = s.Members.Get("g1", "missing")
+	is(t, "Members.Get", err, store.ErrNotFound)
+	is(t, "Members.Delete", s.Members.Delete("g1", "missing"), store.ErrNotFound)
+	_, err = s.Roles.Get("g1", "missing")
+	is(t, "Roles.Get", err, store.ErrNotFound)
+	_, err = s.Channels.Get("g1", "missing")
+	is(t, "Channels.Get", err, store.ErrNotFound)
+
+	is(t, "Guilds.Create", s.Guilds.Create(guild("g1")), store.ErrConflict)
+	is(t, "Members.Create", s.Members.Create(models.Member{GuildID: "g1", MemberID: "m1"}), store.ErrConflict)
+	is(t, "Roles.Create", s.Roles.Create(models.Role{GuildID: "g1", RoleID: "r1"}), store.ErrConflict)
+	is(t, "Channels.Create", s.Channels.Create(models.Channel{GuildID: "g1", ChannelID: "c1"}), store.ErrConflict)
+
+	if err := s.Members.Create(models.Member{GuildID: "missing", MemberID: "m1"}); err == nil {
+		t.Error("Members.Create accepted a member of a missing guild")
+	}
+	member, err := s.Members.Get("g1", "m1")
+	must(t, err)
+	if member.GuildID != "g1" || member.MemberID != "m1" {
+		t.Errorf("Members.Get returned member %s of guild %s", member.MemberID, member.GuildID)
+	}
+}
+
+// testGuildCascade checks deleting a guild deletes what belongs to it, as ON DELETE CASCADE.
+func testGuildCascade(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	must(t, s.Guilds.Create(guild("g2")))
+	for _, guildID := range []string{"g1", "g2"} {
+		must(t, s.Members.Create(models.Member{GuildID: guildID, MemberID: "m1"}))
+		must(t, s.Roles.Create(models.Role{GuildID: guildID, RoleID: "r1"}))
+		must(t, s.Channels.Create(models.Channel{GuildID: guildID, ChannelID: "c1"}))
+		must(t, s.Warns.Create(&models.Warn{GuildID: guildID, MemberID: "m1", WarnedAt: now}))
+		must(t, s.Bans.Create(&models.Ban{GuildID: guildID, MemberID: "m1", BannedAt: now}))
+	}
+
+	must(t, s.Guilds.Delete("g1"))
+
+	_, err := s.Guilds.Get("g1")
+	is(t, "Guilds.Get of deleted guild", err, store.ErrNotFound)
+	_, err = s.Members.Get("g1", "m1")
+	is(t, "Members.Get of deleted guild", err, store.ErrNotFound)
+	_, err = s.Roles.Get("g1", "r1")
+	is(t, "Roles.Get of deleted guild", err, store.ErrNotFound)
+	_, err = s.Channels.Get("g1", "c1")
+	is(t, "Channels.Get of deleted guild", err, store.ErrNotFound)
+	warns, err := s.Warns.List("g1", "m1")
+	must(t, err)
+	bans, err := s.Bans.List("g1", "m1")
+	must(t, err)
+	if len(warns) != 0 || len(bans) != 0 {
+		t.Errorf("deleted guild kept %d warns and %d bans", len(warns), len(bans))
+	}
+
+	// The other guild is left untouched.
+	_, err = s.Members.Get("g2", "m1")
+	must(t, err)
+	_, err = s.Roles.Get("g2", "r1")
+	must(t, err)
+	_, err = s.Channels.Get("g2", "c1")
+	must(t, err)
+	warns, err = s.Warns.List("g2", "m1")
+	must(t, err)
+	bans, err = s.Bans.List("g2", "m1")
+	must(t, err)
+	if len(warns) != 1 || len(bans) != 1 {
+		t.Errorf("other guild has %d warns and %d bans, want 1 and 1", len(warns), len(bans))
+	}
+}
@@ -0,0 +1,48 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testGuildAccess checks an access replaces the previous access of the user on the guild,
+// and is deleted with its guild or its user.
+func testGuildAccess(t *testing.T, s *store.Store) {
+	for _, guildID := range []string{"g1", "g2"} {
+		must(t, s.Guilds.Create(guild(guildID)))
+	}
+	for _, username := range []string{"alice", "bob"} {
+		must(t, s.Users.Create(user(username)))
+	}
+
+	must(t, s.Access.Set(models.GuildAccess{GuildID: "g1", Username: "alice", Access: "read", GrantedAt: now}))
+	must(t, s.Access.Set(models.GuildAccess{GuildID: "g1", Username: "alice", Access: "moderate", GrantedAt: now}))
+	access, err := s.Access.Get("g1", "alice")
+	must(t, err)
+	if access.Access != "moderate" {
+		t.Errorf("Access.Get returned access %s, want moderate", access.Access)
+	}
+	_, err = s.Access.Get("g1", "bob")
+	is(t, "Access.Get", err, store.ErrNotFound)
+
+	must(t, s.Access.Set(models.GuildAccess{GuildID: "g2", Username: "alice", Access: "read", GrantedAt: now}))
+	must(t, s.Access.Set(models.GuildAccess{GuildID: "g1", Username: "bob", Access: "read", GrantedAt: now}))
+	must(t, s.Access.Set(models.GuildAccess{GuildID: "g2", Username: "bob", Access: "read", GrantedAt: now}))
+
+	must(t, s.Guilds.Delete("g1"))
+	_, err = s.Access.Get("g1", "alice")
+	is(t, "Access.Get of deleted guild", err, store.ErrNotFound)
+	_, err = s.Access.Get("g1", "bob")
+	is(t, "Access.Get of deleted guild", err, store.ErrNotFound)
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.Access.Get("g2", "alice")
+	is(t, "Access.Get of deleted user", err, store.ErrNotFound)
+	accesses, err := s.Access.ListGuild("g2")
+	must(t, err)
+	if len(accesses) != 1 || accesses[0].Username != "bob" {
+		t.Errorf("Access.ListGuild returned %v, want the access of bob", accesses)
+	}
+}
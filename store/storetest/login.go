@@ -0,0 +1,47 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testLoginHistory checks the ids generated for login attempts, and the filters of the history.
+func testLoginHistory(t *testing.T, s *store.Store) {
+	attempts := []models.LoginAttempt{
+		{Username: "alice", IP: "10.0.0.1", Method: "password", Reason: "invalid password"},
+		{Username: "alice", IP: "10.0.0.2", Method: "password", Success: true},
+		{Username: "missing", IP: "10.0.0.1", Method: "password", Reason: "unknown user"},
+	}
+	var attemptIDs []int
+	for i := range attempts {
+		attempts[i].CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		must(t, s.Logins.Create(&attempts[i]))
+		attemptIDs = append(attemptIDs, attempts[i].AttemptID)
+	}
+	ascending(t, "login attempt", attemptIDs)
+
+	for _, c := range []struct {
+		filter store.LoginFilter
+		want   []int
+	}{
+		{store.LoginFilter{Limit: 10}, []int{attemptIDs[2], attemptIDs[1], attemptIDs[0]}},
+		{store.LoginFilter{Username: "alice", Limit: 10}, []int{attemptIDs[1], attemptIDs[0]}},
+		{store.LoginFilter{IP: "10.0.0.1", Limit: 10}, []int{attemptIDs[2], attemptIDs[0]}},
+		{store.LoginFilter{Failed: true, Limit: 1}, []int{attemptIDs[2]}},
+		{store.LoginFilter{Before: attemptIDs[2], Limit: 10}, []int{attemptIDs[1], attemptIDs[0]}},
+	} {
+		attempts, err := s.Logins.List(c.filter)
+		must(t, err)
+		got := []int{}
+		for _, attempt := range attempts {
+			got = append(got, attempt.AttemptID)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Logins.List(%+v) returned attempts %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package storetest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testLeaderboard checks the ranks of the members are the same whether their page is fetched
+// with an offset or after a cursor, members with the same xp sharing their rank.
+func testLeaderboard(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+	must(t, s.Guilds.Create(guild("g2")))
+	for memberID, xp := range map[string]int{"a": 30, "b": 20, "c": 20, "d": 20, "e": 10} {
+		must(t, s.Members.Create(models.Member{GuildID: "g1", MemberID: memberID, Xp: xp}))
+	}
+	must(t, s.Members.Create(models.Member{GuildID: "g2", MemberID: "z", Xp: 100}))
+
+	for _, c := range []struct {
+		after  models.LeaderboardCursor
+		offset int
+		want   []string
+	}{
+		{models.LeaderboardCursor{}, 0, []string{"a 1", "d 2"}},
+		{models.LeaderboardCursor{}, 2, []string{"c 2", "b 2"}},
+		{models.LeaderboardCursor{}, 4, []string{"e 5"}},
+		{models.LeaderboardCursor{}, 5, []string{}},
+		{models.LeaderboardCursor{Xp: 20, MemberID: "d"}, 0, []string{"c 2", "b 2"}},
+		{models.LeaderboardCursor{Xp: 20, MemberID: "c"}, 0, []string{"b 2", "e 5"}},
+		{models.LeaderboardCursor{Xp: 10, MemberID: "e"}, 0, []string{}},
+	} {
+		members, err := s.Members.Leaderboard("g1", c.after, c.offset, 2)
+		must(t, err)
+		got := []string{}
+		for _, m := range members {
+			got = append(got, fmt.Sprintf("%s %d", m.MemberID, m.Rank))
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Members.Leaderboard after %+v skipping %d returned %v, want %v", c.after, c.offset, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package storetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+// testSessions checks the ids generated for sessions, that a session is only revoked by its user,
+// and that the sessions and their refresh tokens are deleted with their user.
+func testSessions(t *testing.T, s *store.Store) {
+	must(t, s.Users.Create(user("alice")))
+	must(t, s.Users.Create(user("bob")))
+
+	var sessionIDs []int
+	for i, username := range []string{"alice", "alice", "bob"} {
+		session := models.Session{Username: username, CreatedAt: now, LastUsedAt: now.Add(time.Duration(i) * time.Minute), ExpiresAt: now.Add(time.Hour)}
+		must(t, s.Sessions.Create(&session))
+		sessionIDs = append(sessionIDs, session.SessionID)
+		must(t, s.Tokens.Create(models.RefreshToken{TokenHash: fmt.Sprintf("t%d", i), Username: username,
+			SessionID: nulltype.NullInt64Of(int64(session.SessionID)), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	}
+	ascending(t, "session", sessionIDs)
+
+	session, err := s.Sessions.Get(sessionIDs[1])
+	must(t, err)
+	if session.SessionID != sessionIDs[1] || session.Username != "alice" {
+		t.Errorf("Sessions.Get returned session %d of %s, want session %d of alice", session.SessionID, session.Username, sessionIDs[1])
+	}
+	_, err = s.Sessions.Get(0)
+	is(t, "Sessions.Get", err, store.ErrNotFound)
+	sessions, err := s.Sessions.List("alice", now)
+	must(t, err)
+	if len(sessions) != 2 || sessions[0].SessionID != sessionIDs[1] {
+		t.Errorf("Sessions.List returned %v, want sessions %d and %d", sessions, sessionIDs[1], sessionIDs[0])
+	}
+
+	is(t, "Sessions.Revoke of another user", s.Sessions.Revoke("bob", sessionIDs[0]), store.ErrNotFound)
+	must(t, s.Sessions.Revoke("alice", sessionIDs[0]))
+	is(t, "Sessions.Revoke twice", s.Sessions.Revoke("alice", sessionIDs[0]), store.ErrNotFound)
+	sessions, err = s.Sessions.List("alice", now)
+	must(t, err)
+	if len(sessions) != 1 {
+		t.Errorf("alice has %d sessions once one is revoked, want 1", len(sessions))
+	}
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.Sessions.Get(sessionIDs[1])
+	is(t, "Sessions.Get of deleted user", err, store.ErrNotFound)
+	_, err = s.Tokens.Get("t1")
+	is(t, "Tokens.Get of deleted user", err, store.ErrNotFound)
+	_, err = s.Sessions.Get(sessionIDs[2])
+	must(t, err)
+	_, err = s.Tokens.Get("t2")
+	must(t, err)
+}
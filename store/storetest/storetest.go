@@ -0,0 +1,82 @@
+// Package storetest checks that the implementations of the store behave the same,
+// whatever database they are backed by.
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// Run runs the conformance cases against the stores returned by open, which must be empty.
+// Each case opens its own store.
+func Run(t *testing.T, open func(t *testing.T) *store.Store) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, s *store.Store)
+	}{
+		{"Guilds", testGuilds},
+		{"GuildCascade", testGuildCascade},
+		{"WarnsAndBans", testWarnsAndBans},
+		{"Users", testUsers},
+		{"RefreshTokens", testRefreshTokens},
+		{"ApiKeys", testApiKeys},
+		{"GuildAccess", testGuildAccess},
+		{"DiscordLink", testDiscordLink},
+		{"UserTokens", testUserTokens},
+		{"Totp", testTotp},
+		{"LoginHistory", testLoginHistory},
+		{"Sessions", testSessions},
+		{"Leaderboard", testLeaderboard},
+		{"Escalation", testEscalation},
+		{"BanLifting", testBanLifting},
+		{"CaseNumbers", testCaseNumbers},
+		{"CaseReferences", testCaseReferences},
+		{"ModerationCases", testModerationCases},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, open(t))
+		})
+	}
+}
+
+var now = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func guild(guildID string) models.Guild {
+	return models.Guild{GuildID: guildID, GuildName: "Guild " + guildID, Prefix: "!"}
+}
+
+func user(username string) models.User {
+	return models.User{Username: username, Email: username + "@example.com", PasswordHash: "hash", AccessLvl: 3, CreatedAt: now}
+}
+
+// must fails the test on unexpected errors of the fixtures.
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// is fails the test if err is not target.
+func is(t *testing.T, what string, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s: got error %v, want %v", what, err, target)
+	}
+}
+
+// ascending fails the test if the generated ids are not positive and ascending.
+func ascending(t *testing.T, what string, ids []int) {
+	t.Helper()
+	for i, id := range ids {
+		if id <= 0 || (i > 0 && id <= ids[i-1]) {
+			t.Errorf("%s ids are not generated in ascending order: %v", what, ids)
+			return
+		}
+	}
+}
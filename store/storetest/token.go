@@ -0,0 +1,37 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testRefreshTokens checks a refresh token is only revoked once, and is deleted with its user.
+func testRefreshTokens(t *testing.T, s *store.Store) {
+	for _, username := range []string{"alice", "bob"} {
+		must(t, s.Users.Create(user(username)))
+		must(t, s.Tokens.Create(models.RefreshToken{TokenHash: "t-" + username, Username: username, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	}
+
+	is(t, "Tokens.Create", s.Tokens.Create(models.RefreshToken{TokenHash: "t-alice", Username: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}), store.ErrConflict)
+	_, err := s.Tokens.Get("missing")
+	is(t, "Tokens.Get", err, store.ErrNotFound)
+
+	must(t, s.Tokens.Revoke("t-alice"))
+	is(t, "Tokens.Revoke twice", s.Tokens.Revoke("t-alice"), store.ErrNotFound)
+	token, err := s.Tokens.Get("t-alice")
+	must(t, err)
+	if !token.RevokedAt.Valid() {
+		t.Error("revoked token has no revocation date")
+	}
+	must(t, s.Tokens.RevokeAll("bob"))
+	is(t, "Tokens.Revoke of a token revoked by RevokeAll", s.Tokens.Revoke("t-bob"), store.ErrNotFound)
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.Tokens.Get("t-alice")
+	is(t, "Tokens.Get of deleted user", err, store.ErrNotFound)
+	_, err = s.Tokens.Get("t-bob")
+	must(t, err)
+}
@@ -0,0 +1,104 @@
+package storetest
+
+import (
+	"testing"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+	"github.com/mattn/go-nulltype"
+)
+
+// testUsers checks the users are found by their username, and created once.
+func testUsers(t *testing.T, s *store.Store) {
+	must(t, s.Users.Create(user("alice")))
+	must(t, s.Users.Create(user("bob")))
+
+	is(t, "Users.Create", s.Users.Create(user("alice")), store.ErrConflict)
+	_, err := s.Users.Get("missing")
+	is(t, "Users.Get", err, store.ErrNotFound)
+	is(t, "Users.Delete", s.Users.Delete("missing"), store.ErrNotFound)
+
+	must(t, s.Users.SetBanned("alice", true))
+	alice, err := s.Users.Get("alice")
+	must(t, err)
+	if alice.Username != "alice" || alice.Email != "alice@example.com" || !alice.Banned {
+		t.Errorf("Users.Get returned user %s (%s), banned %t", alice.Username, alice.Email, alice.Banned)
+	}
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.Users.Get("alice")
+	is(t, "Users.Get of deleted user", err, store.ErrNotFound)
+	_, err = s.Users.Get("bob")
+	must(t, err)
+}
+
+// testDiscordLink checks a discord id only finds the user who verified it,
+// and is taken from the users who claimed it without verification.
+func testDiscordLink(t *testing.T, s *store.Store) {
+	alice := user("alice")
+	alice.DiscordID = nulltype.NullStringOf("d1")
+	must(t, s.Users.Create(alice))
+	must(t, s.Users.Create(user("bob")))
+
+	_, err := s.Users.GetByDiscordID("d1")
+	is(t, "Users.GetByDiscordID of a claimed id", err, store.ErrNotFound)
+
+	must(t, s.Users.LinkDiscord("bob", "d1"))
+	bob, err := s.Users.GetByDiscordID("d1")
+	must(t, err)
+	if bob.Username != "bob" || !bob.DiscordVerified {
+		t.Errorf("Users.GetByDiscordID returned %s, verified %t, want bob verified", bob.Username, bob.DiscordVerified)
+	}
+	alice, err = s.Users.Get("alice")
+	must(t, err)
+	if alice.DiscordID.Valid() {
+		t.Errorf("alice kept the claim of %s", alice.DiscordID.StringValue())
+	}
+}
+
+// testTotp checks a TOTP step and a recovery code are only used once,
+// and the recovery codes are deleted with their user.
+func testTotp(t *testing.T, s *store.Store) {
+	must(t, s.Users.Create(user("alice")))
+	must(t, s.Users.Create(user("bob")))
+
+	must(t, s.Users.SetTotp("alice", nulltype.NullStringOf("secret"), true))
+	alice, err := s.Users.Get("alice")
+	must(t, err)
+	if alice.TotpSecret.StringValue() != "secret" || !alice.TotpEnabled {
+		t.Errorf("Users.SetTotp saved secret %v, enabled %t", alice.TotpSecret, alice.TotpEnabled)
+	}
+	must(t, s.Users.UseTotpStep("alice", 10))
+	is(t, "Users.UseTotpStep replayed", s.Users.UseTotpStep("alice", 10), store.ErrNotFound)
+	is(t, "Users.UseTotpStep of a previous step", s.Users.UseTotpStep("alice", 9), store.ErrNotFound)
+	must(t, s.Users.UseTotpStep("alice", 11))
+
+	codes := func(username string, hashes ...string) []models.RecoveryCode {
+		var codes []models.RecoveryCode
+		for _, hash := range hashes {
+			codes = append(codes, models.RecoveryCode{Username: username, CodeHash: hash})
+		}
+		return codes
+	}
+	must(t, s.RecoveryCodes.Replace("alice", codes("alice", "c1", "c2")))
+	must(t, s.RecoveryCodes.Replace("bob", codes("bob", "c3")))
+	must(t, s.RecoveryCodes.Use("alice", "c1"))
+	is(t, "RecoveryCodes.Use twice", s.RecoveryCodes.Use("alice", "c1"), store.ErrNotFound)
+	is(t, "RecoveryCodes.Use of another user", s.RecoveryCodes.Use("alice", "c3"), store.ErrNotFound)
+	if remaining, err := s.RecoveryCodes.Remaining("alice"); err != nil || remaining != 1 {
+		t.Errorf("alice has %d recovery codes left, want 1: %v", remaining, err)
+	}
+	must(t, s.RecoveryCodes.Replace("alice", codes("alice", "c4", "c5", "c6")))
+	is(t, "RecoveryCodes.Use of a replaced code", s.RecoveryCodes.Use("alice", "c2"), store.ErrNotFound)
+	if remaining, err := s.RecoveryCodes.Remaining("alice"); err != nil || remaining != 3 {
+		t.Errorf("alice has %d recovery codes left once replaced, want 3: %v", remaining, err)
+	}
+
+	must(t, s.Users.Delete("alice"))
+	if remaining, err := s.RecoveryCodes.Remaining("alice"); err != nil || remaining != 0 {
+		t.Errorf("deleted user kept %d recovery codes: %v", remaining, err)
+	}
+	if remaining, err := s.RecoveryCodes.Remaining("bob"); err != nil || remaining != 1 {
+		t.Errorf("other user has %d recovery codes left, want 1: %v", remaining, err)
+	}
+}
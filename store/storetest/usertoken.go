@@ -0,0 +1,38 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testUserTokens checks a user token is only used once, and is deleted with its user.
+func testUserTokens(t *testing.T, s *store.Store) {
+	token := func(tokenHash string, username string, purpose string) models.UserToken {
+		return models.UserToken{TokenHash: tokenHash, Username: username, Purpose: purpose, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	}
+	must(t, s.Users.Create(user("alice")))
+	must(t, s.Users.Create(user("bob")))
+	must(t, s.UserTokens.Create(token("u1", "alice", models.VerifyEmailPurpose)))
+	must(t, s.UserTokens.Create(token("u2", "bob", models.VerifyEmailPurpose)))
+	must(t, s.UserTokens.Create(token("u3", "bob", models.ResetPasswordPurpose)))
+
+	_, err := s.UserTokens.Get("missing")
+	is(t, "UserTokens.Get", err, store.ErrNotFound)
+	must(t, s.UserTokens.Use("u1"))
+	is(t, "UserTokens.Use twice", s.UserTokens.Use("u1"), store.ErrNotFound)
+
+	must(t, s.UserTokens.UseAll("bob", models.VerifyEmailPurpose))
+	is(t, "UserTokens.Use of a token used by UseAll", s.UserTokens.Use("u2"), store.ErrNotFound)
+	reset, err := s.UserTokens.Get("u3")
+	must(t, err)
+	if reset.UsedAt.Valid() {
+		t.Error("UseAll used a token issued for another purpose")
+	}
+
+	must(t, s.Users.Delete("alice"))
+	_, err = s.UserTokens.Get("u1")
+	is(t, "UserTokens.Get of deleted user", err, store.ErrNotFound)
+}
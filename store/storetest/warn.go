@@ -0,0 +1,135 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gyroskan/cardinal/models"
+	"github.com/gyroskan/cardinal/store"
+)
+
+// testWarnsAndBans checks the ids generated for warns and bans are returned,
+// with RETURNING on PostgreSQL.
+func testWarnsAndBans(t *testing.T, s *store.Store) {
+	must(t, s.Guilds.Create(guild("g1")))
+
+	var warnIDs, banIDs []int
+	for i := 0; i < 3; i++ {
+		warn := models.Warn{GuildID: "g1", MemberID: "m1", WarnedAt: now}
+		must(t, s.Warns.Create(&warn))
+		warnIDs = append(warnIDs, warn.WarnID)
+
+		ban := models.Ban{GuildID: "g1", MemberID: "m1", BannedAt: now}
+		must(t, s.Bans.Create(&ban))
+		banIDs = append(banIDs, ban.BanID)
+	}
+	ascending(t, "warn", warnIDs)
+	ascending(t, "ban", banIDs)
+
+	warn, err := s.Warns.Get("g1", "m1", warnIDs[1])
+	must(t, err)
+	if warn.WarnID != warnIDs[1] {
+		t.Errorf("Warns.Get returned warn %d, want %d", warn.WarnID, warnIDs[1])
+	}
+	ban, err := s.Bans.Get("g1", "m1", banIDs[1])
+	must(t, err)
+	if ban.BanID != banIDs[1] {
+		t.Errorf("Bans.Get returned ban %d, want %d", ban.BanID, banIDs[1])
+	}
+	_, err = s.Warns.Get("g1", "m2", warnIDs[1])
+	is(t, "Warns.Get of another member", err, store.ErrNotFound)
+	_, err = s.Bans.Get("g1", "m2", banIDs[1])
+	is(t, "Bans.Get of another member", err, store.ErrNotFound)
+
+	warns, err := s.Warns.List("g1", "m1")
+	must(t, err)
+	bans, err := s.Bans.List("g1", "m1")
+	must(t, err)
+	if len(warns) != 3 || len(bans) != 3 {
+		t.Errorf("member has %d warns and %d bans, want 3 and 3", len(warns), len(bans))
+	}
+
+	must(t, s.Warns.Delete("g1", "m1", warnIDs[0]))
+	is(t, "Warns.Delete twice", s.Warns.Delete("g1", "m1", warnIDs[0]), store.ErrNotFound)
+	must(t, s.Bans.Delete("g1", "m1", banIDs[0]))
+	is(t, "Bans.Delete twice", s.Bans.Delete("g1", "m1", banIDs[0]), store.ErrNotFound)
+	_, err = s.Warns.Get("g1", "m1", warnIDs[0])
+	is(t, "Warns.Get of deleted warn", err, store.ErrNotFound)
+	_, err = s.Bans.Get("g1", "m1", banIDs[0])
+	is(t, "Bans.Get of deleted ban", err, store.ErrNotFound)
+
+	is(t, "Warns.Create of a missing guild", s.Warns.Create(&models.Warn{GuildID: "missing", MemberID: "m1", WarnedAt: now}), store.ErrNotFound)
+	is(t, "Bans.Create of a missing guild", s.Bans.Create(&models.Ban{GuildID: "missing", MemberID: "m1", BannedAt: now}), store.ErrNotFound)
+}
+
+// testEscalation checks a ban is created with the warn reaching the max warns of the guild,
+// counting the warns since the last ban only.
+func testEscalation(t *testing.T, s *store.Store) {
+	g := guild("g1")
+	g.AllowModeration, g.MaxWarns = true, 2
+	must(t, s.Guilds.Create(g))
+
+	escalate := func(bannedAt time.Time) func(guild models.Guild, warns int) *models.Ban {
+		return func(guild models.Guild, warns int) *models.Ban {
+			if warns < guild.MaxWarns {
+				return nil
+			}
+			return &models.Ban{GuildID: guild.GuildID, MemberID: "m1", BannedAt: bannedAt, AutoBan: true}
+		}
+	}
+	for i, escalated := range []bool{false, true, false, true} {
+		warnedAt := now.Add(time.Duration(i) * time.Minute)
+		warn := models.Warn{GuildID: "g1", MemberID: "m1", WarnedAt: warnedAt}
+		ban, err := s.Warns.CreateEscalating(&warn, escalate(warnedAt.Add(time.Second)))
+		must(t, err)
+		if (ban != nil) != escalated {
+			t.Errorf("warn %d escalated to %v, want escalated %t", i+1, ban, escalated)
+		}
+		if warn.WarnID <= 0 || (ban != nil && ban.BanID <= 0) {
+			t.Errorf("warn %d got id %d and ban %v", i+1, warn.WarnID, ban)
+		}
+	}
+
+	bans, err := s.Bans.List("g1", "m1")
+	must(t, err)
+	if len(bans) != 2 || !bans[0].AutoBan {
+		t.Errorf("member has bans %v, want 2 automatic bans", bans)
+	}
+
+	// Zero dates are set once the previous warns are created.
+	warn := models.Warn{GuildID: "g1", MemberID: "m2"}
+	_, err = s.Warns.CreateEscalating(&warn, escalate(now))
+	must(t, err)
+	if warn.WarnedAt.IsZero() {
+		t.Error("CreateEscalating kept a zero WarnedAt")
+	}
+
+	_, err = s.Warns.CreateEscalating(&models.Warn{GuildID: "missing", MemberID: "m1"}, escalate(now))
+	is(t, "Warns.CreateEscalating of a missing guild", err, store.ErrNotFound)
+
+	// Only the warns dated after the last ban are counted, whatever their creation order.
+	must(t, s.Bans.Create(&models.Ban{GuildID: "g1", MemberID: "m3", BannedAt: now.Add(time.Hour)}))
+	for _, w := range []struct {
+		warnedAt time.Time
+		count    int
+	}{
+		{now, 0},
+		{now.Add(2 * time.Hour), 1},
+		{now.Add(3 * time.Hour), 2}, // Banned at the date of the warn
+		{now.Add(3 * time.Hour), 0},
+		{now.Add(90 * time.Minute), 0},
+		{now.Add(4 * time.Hour), 1},
+	} {
+		warn := models.Warn{GuildID: "g1", MemberID: "m3", WarnedAt: w.warnedAt}
+		_, err := s.Warns.CreateEscalating(&warn, func(guild models.Guild, warns int) *models.Ban {
+			if warns != w.count {
+				t.Errorf("warn at %s counted %d warns since the last ban, want %d", w.warnedAt, warns, w.count)
+			}
+			if warns < guild.MaxWarns {
+				return nil
+			}
+			return &models.Ban{GuildID: guild.GuildID, MemberID: "m3", BannedAt: w.warnedAt, AutoBan: true}
+		})
+		must(t, err)
+	}
+}